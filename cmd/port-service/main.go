@@ -1,16 +1,34 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"log/slog"
+	"os"
+	"time"
 
 	"github.com/axmz/go-port-service/internal/app"
+	"github.com/axmz/go-port-service/internal/config"
+	grpcserver "github.com/axmz/go-port-service/internal/transport/grpc/server"
 	"github.com/axmz/go-port-service/internal/transport/http/server"
 	"github.com/axmz/go-port-service/pkg/graceful"
 )
 
 func start() error {
-	app := app.SetupApp()
+	cfg, flags, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if flags.PrintConfig {
+		return cfg.Print(os.Stdout)
+	}
+
+	app := app.New(cfg)
 
 	server := server.NewServer(app)
 
@@ -20,11 +38,53 @@ func start() error {
 		server.Run()
 	}()
 
-	<-graceful.Shutdown(app.Config.GracefulTimeout, map[string]graceful.Operation{
-		"port-database": app.DB.Port.Shutdown,
-		"user-database": app.DB.User.Shutdown,
-		"http-server":   server.Shutdown,
+	var rpc *grpcserver.Server
+	if app.Config.GRPCServer.Port != "" {
+		rpc = grpcserver.NewServer(app)
+		go func() {
+			rpc.Run()
+		}()
+	}
+
+	stopReload := graceful.Reload(func() {
+		_, _ = app.Reload(func() (*config.Config, error) {
+			cfg, _, err := config.Load(os.Args[1:])
+			return cfg, err
+		})
+	})
+
+	shutdown := graceful.New(app.Config.GracefulTimeout)
+
+	// Fail readiness first and give load balancers DrainDelay to notice.
+	drainDelay := app.Config.Health.DrainDelay
+	shutdown.Register(graceful.PhaseStopAccepting, "readiness", drainDelay+time.Second, func(ctx context.Context) error {
+		app.Health.MarkShuttingDown()
+		select {
+		case <-time.After(drainDelay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	shutdown.Register(graceful.PhaseDrain, "http-server", 0, server.Shutdown)
+	if rpc != nil {
+		shutdown.Register(graceful.PhaseDrain, "grpc-server", 0, rpc.Shutdown)
+	}
+	shutdown.Register(graceful.PhaseBackground, "config-reload", 0, func(context.Context) error {
+		stopReload()
+		return nil
 	})
+	shutdown.Register(graceful.PhaseFlushStores, "port-database", 0, app.DB.Port.Shutdown)
+	shutdown.Register(graceful.PhaseFlushStores, "user-database", 0, app.DB.User.Shutdown)
+	shutdown.Register(graceful.PhaseFlushStores, "security-database", 0, app.DB.Security.Shutdown)
+	shutdown.Register(graceful.PhaseFlushStores, "audit-database", 0, app.DB.Audit.Shutdown)
+	shutdown.Register(graceful.PhaseCloseSessions, "session-store", 0, app.Repos.Session.Shutdown)
+	shutdown.Register(graceful.PhaseCloseSessions, "session-database", 0, app.DB.Session.Shutdown)
+	shutdown.Register(graceful.PhaseTelemetry, "tracer-provider", 0, app.Tracing.Shutdown)
+
+	if err := shutdown.Wait(); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
 
 	slog.Info("Application stopped")
 
@@ -33,6 +93,6 @@ func start() error {
 
 func main() {
 	if err := start(); err != nil {
-		log.Fatal(err)
+		log.Fatalf("port-service: %v", err)
 	}
 }
@@ -1,86 +1,268 @@
 package app
 
 import (
+	"context"
 	"encoding/gob"
+	"log"
 	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"text/template"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-webauthn/webauthn/webauthn"
 
+	"github.com/axmz/go-port-service/internal/assets"
 	"github.com/axmz/go-port-service/internal/config"
+	"github.com/axmz/go-port-service/internal/domain/audit"
+	"github.com/axmz/go-port-service/internal/domain/security"
+	"github.com/axmz/go-port-service/internal/domain/session"
 	"github.com/axmz/go-port-service/internal/domain/user"
 	"github.com/axmz/go-port-service/internal/logger"
+	"github.com/axmz/go-port-service/internal/metrics"
 	"github.com/axmz/go-port-service/internal/renderer"
+	"github.com/axmz/go-port-service/internal/tracing"
+	"github.com/axmz/go-port-service/pkg/health"
 	"github.com/axmz/go-port-service/pkg/inmem"
+	"github.com/axmz/go-port-service/pkg/ratelimit"
 
+	auditRepository "github.com/axmz/go-port-service/internal/repository/audit"
 	portRepository "github.com/axmz/go-port-service/internal/repository/port"
+	securityRepository "github.com/axmz/go-port-service/internal/repository/security"
+	sessionRepository "github.com/axmz/go-port-service/internal/repository/session"
+	"github.com/axmz/go-port-service/internal/repository/unitofwork"
 	userRepository "github.com/axmz/go-port-service/internal/repository/user"
 
+	auditServices "github.com/axmz/go-port-service/internal/services/audit"
 	portServices "github.com/axmz/go-port-service/internal/services/port"
+	sessionServices "github.com/axmz/go-port-service/internal/services/session"
+	userServices "github.com/axmz/go-port-service/internal/services/user"
 	webAuthnServices "github.com/axmz/go-port-service/internal/services/webauthn"
 
 	gqlHandler "github.com/axmz/go-port-service/internal/transport/graphql/handler"
+	auditHandlers "github.com/axmz/go-port-service/internal/transport/http/handlers/audit"
 	portHandlers "github.com/axmz/go-port-service/internal/transport/http/handlers/port"
+	sessionHandlers "github.com/axmz/go-port-service/internal/transport/http/handlers/session"
 	staticHandlers "github.com/axmz/go-port-service/internal/transport/http/handlers/static"
+	userHandlers "github.com/axmz/go-port-service/internal/transport/http/handlers/user"
 	webAuthnHandlers "github.com/axmz/go-port-service/internal/transport/http/handlers/webauthn"
+	"github.com/axmz/go-port-service/internal/transport/http/middleware"
 )
 
 type App struct {
-	Config *config.Config
-	Log    *slog.Logger
-	DB     struct {
-		Port *inmem.InMemoryDB[*portRepository.Port]
-		User *inmem.InMemoryDB[*user.User]
+	// Config is the configuration the app was started with. Reload applies
+	// parts of a newer one to LogLevel, RateLimiters, CORS, Features and the
+	// templates.
+	Config   *config.Config
+	Log      *slog.Logger
+	LogLevel *slog.LevelVar
+	Metrics  *metrics.Metrics
+	Health   *health.Health
+	CORS     atomic.Pointer[CORSPolicies]
+	Features atomic.Pointer[config.Features]
+	// RateLimiters hold a limiter per route group, see config.RateLimit.
+	RateLimiters struct {
+		API    *ratelimit.Limiter
+		Auth   *ratelimit.Limiter
+		Upload *ratelimit.Limiter
+	}
+	// Uploads caps the uploads running at once over HTTP and gRPC together.
+	Uploads *ratelimit.Concurrency
+	Tracing struct {
+		Shutdown func(ctx context.Context) error
+	}
+	DB struct {
+		Port     *inmem.ShardedDB[*portRepository.Port]
+		User     *inmem.ShardedDB[*user.User]
+		Security *inmem.InMemoryDB[*security.Event]
+		Session  *inmem.InMemoryDB[*session.Session]
+		Audit    *inmem.InMemoryDB[*audit.Entry]
 	}
 	Repos struct {
-		Port *portRepository.Repository
-		User *userRepository.Repository
+		Port     *portRepository.Repository
+		User     *userRepository.Repository
+		Security *securityRepository.Repository
+		Session  *sessionRepository.Repository
+		Audit    *auditRepository.Repository
 	}
 	Services struct {
 		Port           *portServices.Service
+		User           *userServices.Service
+		Audit          *auditServices.Service
 		WebAuthn       *webAuthnServices.Service
+		Session        *sessionServices.Service
 		SessionManager *scs.SessionManager
 	}
 	Handlers struct {
 		Page         *staticHandlers.Handlers
 		Ports        *portHandlers.Handlers
+		PortsV2      *portHandlers.V2
 		WebAuthn     *webAuthnHandlers.Handlers
+		Sessions     *sessionHandlers.Handlers
+		Users        *userHandlers.Handlers
+		Audit        *auditHandlers.Handlers
 		GraphQLQuery *gqlHandler.GraphQLHandler
 	}
+	Assets           *assets.Assets
 	TemplateRenderer *renderer.TemplateRenderer
+
+	reloadMu sync.Mutex
+}
+
+// CORSPolicies hold a policy per route group, see config.CORS.
+type CORSPolicies struct {
+	API     *middleware.CORSPolicy
+	GraphQL *middleware.CORSPolicy
 }
 
+// SetupApp wires the application with the configuration from the config file
+// and the environment.
 func SetupApp() *App {
+	return New(config.MustLoad())
+}
+
+// New wires the application with the given configuration.
+func New(cfg *config.Config) *App {
 	app := &App{}
 
 	// Config
-	app.Config = config.MustLoad()
+	app.Config = cfg
 
 	// Logger
-	app.Log = logger.Setup(app.Config.Env) // TODO: use logger in the app instead of slog?
+	app.LogLevel = new(slog.LevelVar)
+	app.LogLevel.Set(logger.Level(app.Config.Env, app.Config.Logging.Level))
+	app.Log = logger.Setup(app.Config.Env, app.LogLevel)
+
+	// Metrics
+	app.Metrics = metrics.New()
+
+	// Tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), app.Config.Tracing)
+	if err != nil {
+		log.Fatal("failed to setup tracing: ", err)
+	}
+	app.Tracing.Shutdown = shutdownTracing
 
 	// DB
-	app.DB.Port = inmem.New[*portRepository.Port]()
-	app.DB.User = inmem.New[*user.User]()
+	app.DB.Port = inmem.NewSharded[*portRepository.Port](inmem.ShardedOptions{Shards: app.Config.Storage.PortShards})
+	app.DB.User = inmem.NewSharded[*user.User](inmem.ShardedOptions{})
+	app.DB.Security = inmem.New[*security.Event]()
+	app.DB.Session = inmem.New[*session.Session]()
+	app.DB.Audit = inmem.New[*audit.Entry]()
 
 	// Repositories
 	app.Repos.Port = portRepository.New(app.DB.Port)
 	app.Repos.User = userRepository.New(app.DB.User)
+	app.Repos.Security = securityRepository.New(app.DB.Security)
+	app.Repos.Audit = auditRepository.New(app.DB.Audit)
+	app.Repos.Session = sessionRepository.New(app.DB.Session, scs.GobCodec{}, app.Config.Session.CleanupInterval)
 
 	// Services
-	app.Services.Port = portServices.New(app.Repos.Port)
-	app.Services.WebAuthn = webAuthnServices.New(app.Config, app.Repos.User)
-	app.Services.SessionManager = scs.New()
+	app.Services.Port = portServices.New(app.Repos.Port, unitofwork.New(app.Repos.Port, app.Repos.User))
+	app.Services.User = userServices.New(app.Repos.User, app.Repos.Session)
+	app.Services.Audit = auditServices.New(app.Repos.Audit)
+	app.Services.WebAuthn = webAuthnServices.New(app.Config, app.Repos.User, app.Repos.Security, app.Metrics)
+	app.Services.Session = sessionServices.New(app.Repos.Session)
+	app.Services.SessionManager = newSessionManager(app.Config, app.Repos.Session)
 	gob.Register(webauthn.SessionData{})
 
+	app.Metrics.RegisterGaugeFunc("port_store_size", "Number of ports in the store.", func() float64 {
+		return float64(app.Repos.Port.Count(context.Background()))
+	})
+
+	// Static assets
+	app.Assets, err = assets.New("./static", "/static/")
+	if err != nil {
+		log.Fatal("failed to load static assets: ", err)
+	}
+
 	// Renderer
-	app.TemplateRenderer = renderer.NewTemplateRenderer()
+	app.TemplateRenderer = renderer.NewTemplateRenderer(middleware.CSRFToken, template.FuncMap{
+		"asset": app.Assets.Path,
+	})
+
+	// Rate limits
+	app.RateLimiters.API = ratelimit.New(newRateLimitPolicy(app.Config.RateLimit.API))
+	app.RateLimiters.Auth = ratelimit.New(newRateLimitPolicy(app.Config.RateLimit.Auth))
+	app.RateLimiters.Upload = ratelimit.New(newRateLimitPolicy(app.Config.RateLimit.Upload))
+	app.Uploads = ratelimit.NewConcurrency(app.Config.Upload.MaxConcurrent)
+
+	// CORS and feature toggles
+	app.CORS.Store(newCORSPolicies(app.Config.CORS))
+	app.Features.Store(&app.Config.Features)
+
+	// Health
+	app.Health = newHealth(app)
 
 	// Handlers
 	app.Handlers.Page = staticHandlers.New(app.TemplateRenderer)
-	app.Handlers.Ports = portHandlers.New(app.Services.Port)
+	app.Handlers.Ports = portHandlers.New(app.Services.Port, app.Metrics, portHandlers.UploadOptions{
+		MaxBytes:  app.Config.Upload.MaxBytes,
+		Workers:   app.Config.Upload.Workers,
+		BatchSize: app.Config.Upload.BatchSize,
+	})
+	app.Handlers.PortsV2 = portHandlers.NewV2(app.Services.Port)
 	app.Handlers.WebAuthn = webAuthnHandlers.New(app.Services.WebAuthn, app.Services.SessionManager)
-	app.Handlers.GraphQLQuery = gqlHandler.InitGql(app.Services.Port)
+	app.Handlers.Sessions = sessionHandlers.New(app.Services.Session, app.Services.SessionManager)
+	app.Handlers.Users = userHandlers.New(app.Services.User, app.Services.SessionManager)
+	app.Handlers.Audit = auditHandlers.New(app.Services.Audit)
+	app.Handlers.GraphQLQuery = gqlHandler.InitGql(app.Services.Port, app.Services.User, app.Services.SessionManager, app.Metrics, gqlHandler.Options{
+		QueryCacheSize:   app.Config.GraphQL.QueryCacheSize,
+		APQCacheSize:     app.Config.GraphQL.APQCacheSize,
+		MetricOperations: app.Config.GraphQL.MetricOperations,
+	})
 
 	return app
 }
+
+func newHealth(app *App) *health.Health {
+	h := health.New(app.Config.Health.CheckTimeout)
+
+	// Liveness only verifies the process itself isn't wedged: a store whose
+	// lock can't be acquired will never recover without a restart.
+	h.AddLivenessCheck("port-database", app.DB.Port.Ping)
+	h.AddLivenessCheck("user-database", app.DB.User.Ping)
+
+	h.AddReadinessCheck("port-database", app.DB.Port.Ping)
+	h.AddReadinessCheck("user-database", app.DB.User.Ping)
+	h.AddReadinessCheck("session-store", app.Repos.Session.Ping)
+	h.AddReadinessCheck("template-cache", app.TemplateRenderer.Check)
+
+	return h
+}
+
+func newRateLimitPolicy(p config.RateLimitPolicy) ratelimit.Policy {
+	return ratelimit.Policy{Limit: p.Requests, Period: p.Period, Burst: p.Burst}
+}
+
+func newCORSPolicies(c config.CORS) *CORSPolicies {
+	return &CORSPolicies{
+		API:     newCORSPolicy(c.API),
+		GraphQL: newCORSPolicy(c.GraphQL),
+	}
+}
+
+func newCORSPolicy(p config.CORSPolicy) *middleware.CORSPolicy {
+	return &middleware.CORSPolicy{
+		AllowedOrigins:   p.AllowedOrigins,
+		AllowedMethods:   p.AllowedMethods,
+		AllowedHeaders:   p.AllowedHeaders,
+		ExposedHeaders:   p.ExposedHeaders,
+		AllowCredentials: p.AllowCredentials,
+		MaxAge:           p.MaxAge,
+	}
+}
+
+func newSessionManager(cfg *config.Config, store scs.Store) *scs.SessionManager {
+	sm := scs.New()
+	sm.Store = store
+	sm.Codec = scs.GobCodec{}
+	sm.Lifetime = cfg.Session.Lifetime
+	sm.IdleTimeout = cfg.Session.IdleTimeout
+	sm.Cookie.Name = cfg.Session.CookieName
+	sm.Cookie.HttpOnly = true
+	sm.Cookie.SameSite = http.SameSiteLaxMode
+	sm.Cookie.Secure = cfg.HTTPServer.Protocol == "https"
+	return sm
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/axmz/go-port-service/internal/config"
+	"github.com/axmz/go-port-service/internal/logger"
+)
+
+// reloadable are the settings Reload applies, as paths in the config file
+// format. Changes to any other setting take effect on restart.
+var reloadable = []string{
+	"logging.level",
+	"rate_limit.api",
+	"rate_limit.auth",
+	"rate_limit.upload",
+	"cors",
+	"features",
+}
+
+// Reload loads the configuration with load and applies its reloadable
+// settings while the server keeps running, and re-parses the templates. It
+// returns the changed settings that need a restart. If loading or applying
+// fails nothing changes and the current configuration stays in use.
+//
+// The WebAuthn relying party origins are derived from the CORS origins at
+// startup and aren't updated.
+func (app *App) Reload(load func() (*config.Config, error)) (restart []string, err error) {
+	app.reloadMu.Lock()
+	defer app.reloadMu.Unlock()
+
+	defer func() {
+		app.Metrics.ConfigReloaded(err != nil)
+		if err != nil {
+			app.Log.Error("config reload failed, keeping the current config", slog.Any("error", err))
+			return
+		}
+		if len(restart) > 0 {
+			app.Log.Warn("config reloaded, some changes need a restart", slog.Any("settings", restart))
+			return
+		}
+		app.Log.Info("config reloaded")
+	}()
+
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parsing the templates is the only step that can fail, so it goes first.
+	if err := app.TemplateRenderer.Reload(); err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	app.LogLevel.Set(logger.Level(cfg.Env, cfg.Logging.Level))
+	app.RateLimiters.API.SetPolicy(newRateLimitPolicy(cfg.RateLimit.API))
+	app.RateLimiters.Auth.SetPolicy(newRateLimitPolicy(cfg.RateLimit.Auth))
+	app.RateLimiters.Upload.SetPolicy(newRateLimitPolicy(cfg.RateLimit.Upload))
+	app.CORS.Store(newCORSPolicies(cfg.CORS))
+	app.Features.Store(&cfg.Features)
+
+	// Compare with the startup config so pending changes are reported until
+	// the restart, not just on the reload that introduced them.
+	for _, setting := range config.Diff(app.Config, cfg) {
+		if !isReloadable(setting) {
+			restart = append(restart, setting)
+		}
+	}
+	return restart, nil
+}
+
+func isReloadable(setting string) bool {
+	for _, r := range reloadable {
+		if setting == r || strings.HasPrefix(setting, r+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ImmutableCacheControl is sent for fingerprinted URLs: their content
+	// can never change, as a new version gets a new URL.
+	ImmutableCacheControl = "public, max-age=31536000, immutable"
+	// RevalidateCacheControl is sent for plain URLs.
+	RevalidateCacheControl = "no-cache"
+
+	hashLen = 10
+)
+
+// Assets serves the files in a directory under prefix and maps them to
+// fingerprinted URLs, e.g. "webauthn.js" to "/static/webauthn.3f2a1b9c0d.js".
+type Assets struct {
+	dir    string
+	prefix string
+
+	fingerprinted map[string]string // name -> fingerprinted name
+	original      map[string]string // fingerprinted name -> name
+}
+
+// New fingerprints every file in dir. A missing dir yields no assets.
+func New(dir, prefix string) (*Assets, error) {
+	a := &Assets{
+		dir:           dir,
+		prefix:        prefix,
+		fingerprinted: make(map[string]string),
+		original:      make(map[string]string),
+	}
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+
+		ext := path.Ext(name)
+		fp := strings.TrimSuffix(name, ext) + "." + sum[:hashLen] + ext
+		a.fingerprinted[name] = fp
+		a.original[fp] = name
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Path returns the fingerprinted URL of the named asset, or its plain URL if
+// the asset is unknown.
+func (a *Assets) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if fp, ok := a.fingerprinted[name]; ok {
+		return a.prefix + fp
+	}
+	return a.prefix + name
+}
+
+// Handler serves assets under the prefix. Fingerprinted URLs are cached
+// forever, plain ones are revalidated with Last-Modified.
+func (a *Assets) Handler() http.Handler {
+	files := http.FileServer(http.Dir(a.dir))
+
+	return http.StripPrefix(a.prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name, ok := a.original[r.URL.Path]; ok {
+			w.Header().Set("Cache-Control", ImmutableCacheControl)
+			r.URL.Path = name
+			r.URL.RawPath = ""
+		} else {
+			w.Header().Set("Cache-Control", RevalidateCacheControl)
+		}
+		files.ServeHTTP(w, r)
+	}))
+}
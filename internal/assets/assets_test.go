@@ -0,0 +1,53 @@
+package assets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssets(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "js"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "js", "app.js"), []byte("console.log(1)"), 0o644))
+
+	a, err := New(dir, "/static/")
+	require.NoError(t, err)
+
+	fp := a.Path("js/app.js")
+	assert.Regexp(t, `^/static/js/app\.[0-9a-f]{10}\.js$`, fp)
+	assert.Equal(t, "/static/missing.js", a.Path("missing.js"))
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		a.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	w := get(fp)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "console.log(1)", w.Body.String())
+	assert.Equal(t, ImmutableCacheControl, w.Header().Get("Cache-Control"))
+
+	w = get("/static/js/app.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, RevalidateCacheControl, w.Header().Get("Cache-Control"))
+	assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+
+	// A changed file gets a new URL.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "js", "app.js"), []byte("console.log(2)"), 0o644))
+	b, err := New(dir, "/static/")
+	require.NoError(t, err)
+	assert.NotEqual(t, fp, b.Path("js/app.js"))
+}
+
+func TestAssets_MissingDir(t *testing.T) {
+	a, err := New(filepath.Join(t.TempDir(), "missing"), "/static/")
+	require.NoError(t, err)
+	assert.Equal(t, "/static/app.js", a.Path("app.js"))
+}
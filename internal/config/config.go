@@ -1,56 +1,297 @@
 package config
 
 import (
+	"fmt"
 	"log"
-	"os"
-	"strconv"
+	"slices"
 	"time"
 )
 
+// Config is loaded from, in increasing order of precedence, the defaults, a
+// YAML or TOML file, environment variables and command line flags. See Load.
 type Config struct {
-	Env             string
-	GracefulTimeout time.Duration
-	HTTPServer      HTTPServer
+	Env             string        `yaml:"env" toml:"env"`
+	GracefulTimeout time.Duration `yaml:"graceful_timeout" toml:"graceful_timeout"`
+	HTTPServer      HTTPServer    `yaml:"http_server" toml:"http_server"`
+	GRPCServer      GRPCServer    `yaml:"grpc_server" toml:"grpc_server"`
+	Auth            Auth          `yaml:"auth" toml:"auth"`
+	Session         Session       `yaml:"session" toml:"session"`
+	Tracing         Tracing       `yaml:"tracing" toml:"tracing"`
+	Health          Health        `yaml:"health" toml:"health"`
+	Logging         Logging       `yaml:"logging" toml:"logging"`
+	RateLimit       RateLimit     `yaml:"rate_limit" toml:"rate_limit"`
+	CORS            CORS          `yaml:"cors" toml:"cors"`
+	Upload          Upload        `yaml:"upload" toml:"upload"`
+	GraphQL         GraphQL       `yaml:"graphql" toml:"graphql"`
+	OpenAPI         OpenAPI       `yaml:"openapi" toml:"openapi"`
+	Storage         Storage       `yaml:"storage" toml:"storage"`
+	Features        Features      `yaml:"features" toml:"features"`
 }
 
 type HTTPServer struct {
-	Protocol     string
-	Host         string
-	Port         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Protocol     string        `yaml:"protocol" toml:"protocol"`
+	Host         string        `yaml:"host" toml:"host"`
+	Port         string        `yaml:"port" toml:"port"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// CompressMinSize is the smallest response body worth compressing.
+	CompressMinSize int `yaml:"compress_min_size" toml:"compress_min_size"`
+	// RedirectPort, when serving https, is the address of a plain HTTP
+	// listener that redirects to https, e.g. :80. Empty disables it.
+	RedirectPort string `yaml:"redirect_port" toml:"redirect_port"`
+	TLS          TLS    `yaml:"tls" toml:"tls"`
 }
 
-func MustLoad() *Config {
+// GRPCServer serves the gRPC API, meant for internal services. It is off
+// unless Port is set, and then only serves clients with one of the
+// Auth.APIKeys.
+type GRPCServer struct {
+	// Port is the address to listen on, e.g. :9090. Empty disables the server.
+	Port string `yaml:"port" toml:"port"`
+	// Reflection lets clients such as grpcurl discover the services. Local
+	// only.
+	Reflection bool `yaml:"reflection" toml:"reflection"`
+}
+
+// TLS configures serving over https, see HTTPServer.Protocol.
+type TLS struct {
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `yaml:"key_file" toml:"key_file"`
+	// SelfSigned generates a development certificate for the host at
+	// CertFile and KeyFile unless they already hold one. Local only.
+	SelfSigned bool `yaml:"self_signed" toml:"self_signed"`
+	// ReloadInterval is how often the files are checked for a new
+	// certificate. Zero disables reloading.
+	ReloadInterval time.Duration `yaml:"reload_interval" toml:"reload_interval"`
+}
+
+type Auth struct {
+	ClonePolicy       string        `yaml:"clone_policy" toml:"clone_policy"`
+	MaxFailedLogins   int           `yaml:"max_failed_logins" toml:"max_failed_logins"`
+	FailedLoginWindow time.Duration `yaml:"failed_login_window" toml:"failed_login_window"`
+	LockoutDuration   time.Duration `yaml:"lockout_duration" toml:"lockout_duration"`
+	Admins            []string      `yaml:"admins" toml:"admins"`
+	// APIKeys map client names to the keys they authenticate with, sent in
+	// X-Api-Key or as a bearer token, over gRPC in the same named metadata.
+	APIKeys map[string]string `yaml:"api_keys" toml:"api_keys" secret:"true"`
+}
+
+type Session struct {
+	CookieName      string        `yaml:"cookie_name" toml:"cookie_name"`
+	Lifetime        time.Duration `yaml:"lifetime" toml:"lifetime"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" toml:"cleanup_interval"`
+}
+
+type Tracing struct {
+	ServiceName  string `yaml:"service_name" toml:"service_name"`
+	Exporter     string `yaml:"exporter" toml:"exporter"`
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+	// OTLPHeaders are sent with every export, typically to authenticate
+	// with a hosted collector.
+	OTLPHeaders map[string]string `yaml:"otlp_headers" toml:"otlp_headers" secret:"true"`
+	SampleRatio float64           `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+type Logging struct {
+	// Level is debug, info, warn or error. Empty picks one by environment.
+	Level string `yaml:"level" toml:"level"`
+	// AccessSampleRate is the fraction of successful requests written to the
+	// access log; errors and slow requests are always logged.
+	AccessSampleRate float64       `yaml:"access_sample_rate" toml:"access_sample_rate"`
+	SlowRequest      time.Duration `yaml:"slow_request" toml:"slow_request"`
+	RedactHeaders    []string      `yaml:"redact_headers" toml:"redact_headers"`
+}
+
+// CORSPolicy describes which cross-origin requests browsers may make.
+// Origins may contain a single "*" wildcard, e.g. "https://*.example.com",
+// or be "*" to allow any origin.
+type CORSPolicy struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods" toml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" toml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" toml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials" toml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age" toml:"max_age"`
+}
+
+// CORS holds a policy per route group. A policy without origins disables CORS
+// for the group. Settings not given for GraphQL are taken from the API policy.
+type CORS struct {
+	API     CORSPolicy `yaml:"api" toml:"api"`
+	GraphQL CORSPolicy `yaml:"graphql" toml:"graphql"`
+}
+
+// RateLimitPolicy allows Requests per Period with bursts of up to Burst.
+// A zero Requests disables the limit.
+type RateLimitPolicy struct {
+	Requests int           `yaml:"requests" toml:"requests"`
+	Period   time.Duration `yaml:"period" toml:"period"`
+	Burst    int           `yaml:"burst" toml:"burst"`
+}
+
+// RateLimit holds a policy per route group. Clients are keyed by
+// authenticated API key, logged in user or IP.
+type RateLimit struct {
+	API    RateLimitPolicy `yaml:"api" toml:"api"`
+	Auth   RateLimitPolicy `yaml:"auth" toml:"auth"`
+	Upload RateLimitPolicy `yaml:"upload" toml:"upload"`
+}
+
+type Upload struct {
+	// MaxBytes caps the size of a bulk upload body.
+	MaxBytes int64 `yaml:"max_bytes" toml:"max_bytes"`
+	// MaxConcurrent caps bulk uploads in flight across all clients, over HTTP
+	// and gRPC together.
+	MaxConcurrent int `yaml:"max_concurrent" toml:"max_concurrent"`
+	// Workers validate the ports of an upload in parallel.
+	Workers int `yaml:"workers" toml:"workers"`
+	// BatchSize is how many ports a gRPC upload stores at once and how many
+	// decoded ports may wait for each HTTP upload worker.
+	BatchSize int `yaml:"batch_size" toml:"batch_size"`
+}
+
+type GraphQL struct {
+	// QueryCacheSize is how many parsed queries are kept.
+	QueryCacheSize int `yaml:"query_cache_size" toml:"query_cache_size"`
+	// APQCacheSize is how many automatic persisted queries are kept.
+	APQCacheSize int `yaml:"apq_cache_size" toml:"apq_cache_size"`
+	// MetricOperations are the operation names that get their own metrics
+	// label. Other operations are counted as "other", so that clients can't
+	// create an unbounded number of series.
+	MetricOperations []string `yaml:"metric_operations" toml:"metric_operations"`
+}
+
+// OpenAPI configures the REST API specification served at /openapi.json.
+type OpenAPI struct {
+	// ValidateRequests rejects API requests that don't match the
+	// specification before they reach a handler.
+	ValidateRequests bool `yaml:"validate_requests" toml:"validate_requests"`
+}
+
+type Storage struct {
+	// PortShards is how many maps hold the ports. A write copies the maps it
+	// changes, so more shards make writes to a large catalog cheaper.
+	PortShards int `yaml:"port_shards" toml:"port_shards"`
+}
+
+// Features switch parts of the API on and off. Disabled endpoints respond
+// with 404.
+type Features struct {
+	GraphQL    bool `yaml:"graphql" toml:"graphql"`
+	Upload     bool `yaml:"upload" toml:"upload"`
+	PortWrites bool `yaml:"port_writes" toml:"port_writes"`
+}
+
+type Health struct {
+	CheckTimeout time.Duration `yaml:"check_timeout" toml:"check_timeout"`
+	// DrainDelay is how long readiness reports failure before the server
+	// stops accepting connections, giving load balancers time to react.
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() *Config {
+	api := CORSPolicy{
+		AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-Api-Key", "X-CSRF-Token", "X-Request-Id", "traceparent", "tracestate"},
+		ExposedHeaders: []string{"X-Request-Id", "X-CSRF-Token", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "traceparent", "Deprecation", "Sunset", "Link"},
+		MaxAge:         10 * time.Minute,
+	}
+
 	return &Config{
-		Env:             getEnv("APP_ENV", "local"),
-		GracefulTimeout: getEnvAsDuration("GRACEFUL_TIMEOUT", 2*time.Second),
+		Env:             "local",
+		GracefulTimeout: 2 * time.Second,
 		HTTPServer: HTTPServer{
-			Protocol:     getEnv("PROTOCOL", "http"),
-			Host:         getEnv("HOST", "localhost"),
-			Port:         getEnv("PORT", ":8080"),
-			ReadTimeout:  getEnvAsDuration("READ_TIMEOUT", 5*time.Second),
-			WriteTimeout: getEnvAsDuration("WRITE_TIMEOUT", 10*time.Second),
-			IdleTimeout:  getEnvAsDuration("IDLE_TIMEOUT", 120*time.Second),
+			Protocol:        "http",
+			Host:            "localhost",
+			Port:            ":8080",
+			ReadTimeout:     5 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			CompressMinSize: 1024,
+			TLS: TLS{
+				ReloadInterval: 30 * time.Second,
+			},
+		},
+		Auth: Auth{
+			ClonePolicy:       "reject",
+			MaxFailedLogins:   5,
+			FailedLoginWindow: 15 * time.Minute,
+			LockoutDuration:   15 * time.Minute,
+		},
+		Session: Session{
+			CookieName:      "session",
+			Lifetime:        24 * time.Hour,
+			IdleTimeout:     30 * time.Minute,
+			CleanupInterval: time.Minute,
+		},
+		Tracing: Tracing{
+			ServiceName: "go-port-service",
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+		Logging: Logging{
+			AccessSampleRate: 1,
+			SlowRequest:      time.Second,
+			RedactHeaders: []string{
+				"Authorization", "Cookie", "Set-Cookie", "X-CSRF-Token", "X-Api-Key", "Proxy-Authorization",
+			},
+		},
+		RateLimit: RateLimit{
+			API:    RateLimitPolicy{Requests: 300, Period: time.Minute},
+			Auth:   RateLimitPolicy{Requests: 20, Period: time.Minute},
+			Upload: RateLimitPolicy{Requests: 10, Period: time.Minute},
+		},
+		CORS: CORS{
+			API:     api,
+			GraphQL: api.clone(),
+		},
+		Upload: Upload{
+			MaxBytes:      50 << 20,
+			MaxConcurrent: 4,
+			Workers:       4,
+			BatchSize:     256,
+		},
+		GraphQL: GraphQL{
+			QueryCacheSize: 1000,
+			APQCacheSize:   100,
+		},
+		Storage: Storage{
+			PortShards: 32,
+		},
+		Features: Features{
+			GraphQL:    true,
+			Upload:     true,
+			PortWrites: true,
+		},
+		Health: Health{
+			CheckTimeout: time.Second,
 		},
 	}
 }
 
-func getEnv(key, defaultVal string) string {
-	if val, exists := os.LookupEnv(key); exists {
-		return val
+// MustLoad loads the configuration from the file named by CONFIG_FILE and
+// the environment, ignoring command line flags.
+func MustLoad() *Config {
+	cfg, _, err := Load(nil)
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
 	}
-	return defaultVal
+	return cfg
 }
 
-func getEnvAsDuration(key string, defaultVal time.Duration) time.Duration {
-	if valStr, ok := os.LookupEnv(key); ok {
-		if valInt, err := strconv.Atoi(valStr); err == nil {
-			return time.Duration(valInt) * time.Second
-		} else {
-			log.Fatalf("Invalid duration for %s: %v. Using default: %v", key, err, defaultVal)
-		}
-	}
-	return defaultVal
+// Origins are the origins the application itself is served from.
+func (c *Config) Origins() []string {
+	origin := fmt.Sprintf("%s://%s", c.HTTPServer.Protocol, c.HTTPServer.Host)
+	return []string{origin, origin + c.HTTPServer.Port}
+}
+
+func (p CORSPolicy) clone() CORSPolicy {
+	p.AllowedOrigins = slices.Clone(p.AllowedOrigins)
+	p.AllowedMethods = slices.Clone(p.AllowedMethods)
+	p.AllowedHeaders = slices.Clone(p.AllowedHeaders)
+	p.ExposedHeaders = slices.Clone(p.ExposedHeaders)
+	return p
 }
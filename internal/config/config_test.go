@@ -0,0 +1,180 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Setenv(FileEnv, "")
+
+	cfg, flags, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+	assert.Equal(t, Flags{}, flags)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+http_server:
+  port: ":9000"
+  read_timeout: 7s
+  write_timeout: 7s
+  idle_timeout: 7s
+upload:
+  max_bytes: 1024
+`)
+	t.Setenv(FileEnv, path)
+	t.Setenv("WRITE_TIMEOUT", "8s")
+	t.Setenv("IDLE_TIMEOUT", "8s")
+
+	cfg, _, err := Load([]string{"-idle-timeout", "9s"})
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9000", cfg.HTTPServer.Port)
+	assert.Equal(t, 7*time.Second, cfg.HTTPServer.ReadTimeout, "file overrides default")
+	assert.Equal(t, 8*time.Second, cfg.HTTPServer.WriteTimeout, "env overrides file")
+	assert.Equal(t, 9*time.Second, cfg.HTTPServer.IdleTimeout, "flag overrides env")
+	assert.Equal(t, int64(1024), cfg.Upload.MaxBytes)
+	assert.Equal(t, "http", cfg.HTTPServer.Protocol, "unset values keep their default")
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+env = "prod"
+
+[rate_limit.api]
+requests = 50
+period = "1s"
+burst = 100
+`)
+
+	cfg, flags, err := Load([]string{"-config", path})
+	require.NoError(t, err)
+	assert.Equal(t, path, flags.File)
+	assert.Equal(t, "prod", cfg.Env)
+	assert.Equal(t, RateLimitPolicy{Requests: 50, Period: time.Second, Burst: 100}, cfg.RateLimit.API)
+}
+
+func TestLoad_Durations(t *testing.T) {
+	t.Setenv(FileEnv, "")
+	t.Setenv("READ_TIMEOUT", "1m30s")
+	t.Setenv("WRITE_TIMEOUT", "30")
+
+	cfg, _, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, cfg.HTTPServer.ReadTimeout)
+	assert.Equal(t, 30*time.Second, cfg.HTTPServer.WriteTimeout, "bare integers are seconds")
+}
+
+func TestLoad_GraphQLCORSFallsBackToAPI(t *testing.T) {
+	t.Setenv(FileEnv, "")
+	t.Setenv("CORS_API_ALLOWED_ORIGINS", "https://app.example.com")
+	t.Setenv("CORS_API_MAX_AGE", "1h")
+	t.Setenv("CORS_GRAPHQL_MAX_AGE", "5m")
+
+	cfg, _, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://app.example.com"}, cfg.CORS.GraphQL.AllowedOrigins)
+	assert.Equal(t, cfg.CORS.API.AllowedMethods, cfg.CORS.GraphQL.AllowedMethods)
+	assert.Equal(t, time.Hour, cfg.CORS.API.MaxAge)
+	assert.Equal(t, 5*time.Minute, cfg.CORS.GraphQL.MaxAge)
+}
+
+func TestLoad_ReportsEveryError(t *testing.T) {
+	path := writeFile(t, "config.yaml", "http_server:\n  prot: https\n")
+	t.Setenv(FileEnv, path)
+	t.Setenv("MAX_FAILED_LOGINS", "five")
+	t.Setenv("TRACING_EXPORTER", "jaeger")
+
+	_, _, err := Load([]string{"-tracing-sample-ratio", "2"})
+	require.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "field prot not found")
+	assert.Contains(t, msg, "MAX_FAILED_LOGINS")
+	assert.Contains(t, msg, `tracing.exporter: must be one of none, stdout, otlp, got "jaeger"`)
+	assert.Contains(t, msg, "tracing.sample_ratio: must be between 0 and 1, got 2")
+}
+
+func TestLoad_RejectsCredentialsForAnyOrigin(t *testing.T) {
+	t.Setenv(FileEnv, "")
+	t.Setenv("CORS_API_ALLOWED_ORIGINS", "*")
+	t.Setenv("CORS_API_ALLOW_CREDENTIALS", "true")
+
+	_, _, err := Load(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cors.api.allow_credentials: can't be combined with the * origin")
+}
+
+func TestLoad_GRPCRequiresAPIKeys(t *testing.T) {
+	t.Setenv(FileEnv, "")
+	t.Setenv("GRPC_PORT", ":9090")
+
+	_, _, err := Load(nil)
+	assert.ErrorContains(t, err, "grpc_server.port: requires auth.api_keys")
+
+	t.Setenv("API_KEYS", "billing=0123456789abcdef")
+	cfg, _, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.GRPCServer.Port)
+}
+
+func TestLoad_GRPCReflectionIsLocalOnly(t *testing.T) {
+	t.Setenv(FileEnv, "")
+	t.Setenv("APP_ENV", "prod")
+	t.Setenv("GRPC_REFLECTION", "true")
+
+	_, _, err := Load(nil)
+	assert.ErrorContains(t, err, "grpc_server.reflection: is only allowed in the local environment")
+}
+
+func TestLoad_InvalidFlag(t *testing.T) {
+	t.Setenv(FileEnv, "")
+
+	_, _, err := Load([]string{"-read-timeout", "soon"})
+	assert.ErrorContains(t, err, "-read-timeout")
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Tracing.OTLPHeaders = map[string]string{"Authorization": "Bearer secret-token"}
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Print(&buf))
+
+	assert.NotContains(t, buf.String(), "secret-token")
+	assert.Contains(t, buf.String(), "Authorization: '[REDACTED]'")
+	assert.Equal(t, "Bearer secret-token", cfg.Tracing.OTLPHeaders["Authorization"], "the config itself is left alone")
+
+	// The printed config loads back.
+	path := writeFile(t, "printed.yaml", buf.String())
+	t.Setenv(FileEnv, path)
+	_, _, err := Load(nil)
+	assert.NoError(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	a, b := Default(), Default()
+	assert.Empty(t, Diff(a, b))
+
+	b.HTTPServer.Port = ":9000"
+	b.RateLimit.Auth.Burst = 5
+	b.Auth.Admins = []string{} // empty and nil are the same
+	b.Tracing.OTLPHeaders = map[string]string{"k": "v"}
+
+	assert.Equal(t, []string{"http_server.port", "tracing.otlp_headers", "rate_limit.auth.burst"}, Diff(a, b))
+}
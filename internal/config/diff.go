@@ -0,0 +1,36 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Diff lists the settings that differ between a and b as dotted paths in the
+// config file format, e.g. "http_server.port".
+func Diff(a, b *Config) []string {
+	return diff("", reflect.ValueOf(*a), reflect.ValueOf(*b))
+}
+
+func diff(prefix string, a, b reflect.Value) []string {
+	var res []string
+	for i := range a.NumField() {
+		name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("yaml"), ",")
+		path := prefix + name
+
+		fa, fb := a.Field(i), b.Field(i)
+		switch fa.Kind() {
+		case reflect.Struct:
+			res = append(res, diff(path+".", fa, fb)...)
+			continue
+		case reflect.Slice, reflect.Map:
+			// nil and empty mean the same.
+			if fa.Len() == 0 && fb.Len() == 0 {
+				continue
+			}
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			res = append(res, path)
+		}
+	}
+	return res
+}
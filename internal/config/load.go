@@ -0,0 +1,167 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable holding the config file path.
+const FileEnv = "CONFIG_FILE"
+
+// Flags are the command line flags that control loading rather than
+// configure the application.
+type Flags struct {
+	// File is the YAML or TOML file read before the environment.
+	File string
+	// PrintConfig asks for the effective configuration to be printed.
+	PrintConfig bool
+}
+
+// Load builds the configuration from, in increasing order of precedence, the
+// defaults, the config file, environment variables and the flags in args.
+// Every setting can be given as an environment variable and as a flag named
+// after it, e.g. READ_TIMEOUT and -read-timeout. Durations are Go duration
+// strings; bare integers are read as seconds.
+//
+// All problems are reported at once, joined into the returned error.
+func Load(args []string) (*Config, Flags, error) {
+	var flags Flags
+
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	fs.StringVar(&flags.File, "config", os.Getenv(FileEnv), "path to a YAML or TOML config file (env "+FileEnv+")")
+	fs.BoolVar(&flags.PrintConfig, "print-config", false, "print the effective configuration with secrets redacted and exit")
+
+	// Flag values are checked while parsing but applied after the file and
+	// the environment, which need the -config flag to be loaded first.
+	var overrides []override
+	for _, s := range settings(Default()) {
+		fs.Var(flagValue{setting: s, overrides: &overrides}, s.flag(), s.usage+" (env "+s.key+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, flags, err
+	}
+	if fs.NArg() > 0 {
+		return nil, flags, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	var file []byte
+	if flags.File != "" {
+		var err error
+		if file, err = os.ReadFile(flags.File); err != nil {
+			return nil, flags, err
+		}
+	}
+
+	// The GraphQL CORS policy falls back to the API one setting by setting,
+	// so resolve the API policy first and use it as the GraphQL default.
+	base := Default()
+	_ = base.apply(flags.File, file, overrides)
+
+	cfg := Default()
+	cfg.CORS.GraphQL = base.CORS.API.clone()
+	if err := errors.Join(cfg.apply(flags.File, file, overrides), cfg.Validate()); err != nil {
+		return nil, flags, err
+	}
+	return cfg, flags, nil
+}
+
+// override is a setting given on the command line.
+type override struct {
+	key   string
+	value string
+}
+
+// apply layers the file, the environment and the overrides onto c.
+func (c *Config) apply(path string, file []byte, overrides []override) error {
+	var errs []error
+
+	if file != nil {
+		if err := decodeFile(path, file, c); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+
+	byKey := make(map[string]setting)
+	for _, s := range settings(c) {
+		byKey[s.key] = s
+		if v, ok := os.LookupEnv(s.key); ok {
+			if err := s.value.Set(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.key, err))
+			}
+		}
+	}
+
+	for _, o := range overrides {
+		if err := byKey[o.key].value.Set(o.value); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", byKey[o.key].flag(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// decodeFile decodes a YAML or TOML file, picked by extension. Unknown keys
+// are errors so that typos don't go unnoticed.
+func decodeFile(path string, data []byte, c *Config) error {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, k := range undecoded {
+				keys[i] = k.String()
+			}
+			return fmt.Errorf("unknown keys: %s", strings.Join(keys, ", "))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported config file extension %q, want .yaml, .yml or .toml", ext)
+	}
+}
+
+// flagValue records a flag for Load to apply after the environment. The
+// value is also set on the defaults it was registered with, which checks it
+// and lets -help show it.
+type flagValue struct {
+	setting
+	overrides *[]override
+}
+
+func (f flagValue) Set(s string) error {
+	if err := f.value.Set(s); err != nil {
+		return err
+	}
+	*f.overrides = append(*f.overrides, override{key: f.key, value: s})
+	return nil
+}
+
+func (f flagValue) String() string {
+	if f.value == nil {
+		return ""
+	}
+	return f.value.String()
+}
+
+func (f flagValue) IsBoolFlag() bool {
+	b, ok := f.value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Print writes the configuration as YAML, in the format Load reads, with the
+// values of fields tagged secret:"true" replaced.
+func (c *Config) Print(w io.Writer) error {
+	r := *c
+	redact(reflect.ValueOf(&r).Elem())
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&r); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// redact replaces secrets in the struct v. Maps and slices are replaced
+// rather than modified, so a shallow copy can be redacted safely.
+func redact(v reflect.Value) {
+	for i := range v.NumField() {
+		f := v.Field(i)
+		switch {
+		case v.Type().Field(i).Tag.Get("secret") == "true":
+			f.Set(redactedValue(f))
+		case f.Kind() == reflect.Struct:
+			redact(f)
+		}
+	}
+}
+
+func redactedValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		if v.Len() == 0 {
+			return v
+		}
+		return reflect.ValueOf(redacted).Convert(v.Type())
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for it := v.MapRange(); it.Next(); {
+			m.SetMapIndex(it.Key(), redactedValue(it.Value()))
+		}
+		return m
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			s.Index(i).Set(redactedValue(v.Index(i)))
+		}
+		return s
+	default:
+		return reflect.Zero(v.Type())
+	}
+}
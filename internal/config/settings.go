@@ -0,0 +1,273 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds an environment variable, and the flag named after it, to a
+// field of the configuration.
+type setting struct {
+	key   string
+	usage string
+	value flag.Value
+}
+
+// flag is the key in lower case with dashes, e.g. -read-timeout.
+func (s setting) flag() string {
+	return strings.ToLower(strings.ReplaceAll(s.key, "_", "-"))
+}
+
+func settings(c *Config) []setting {
+	s := []setting{
+		{"APP_ENV", "environment: local, dev or prod", stringValue{&c.Env}},
+		{"GRACEFUL_TIMEOUT", "time allowed for shutdown", durationValue{&c.GracefulTimeout}},
+
+		{"PROTOCOL", "protocol the application is served over: http or https", stringValue{&c.HTTPServer.Protocol}},
+		{"HOST", "host the application is served from", stringValue{&c.HTTPServer.Host}},
+		{"PORT", "address to listen on, e.g. :8080", stringValue{&c.HTTPServer.Port}},
+		{"READ_TIMEOUT", "HTTP read timeout", durationValue{&c.HTTPServer.ReadTimeout}},
+		{"WRITE_TIMEOUT", "HTTP write timeout", durationValue{&c.HTTPServer.WriteTimeout}},
+		{"IDLE_TIMEOUT", "HTTP keep-alive idle timeout", durationValue{&c.HTTPServer.IdleTimeout}},
+		{"HTTP_REDIRECT_PORT", "address of a listener redirecting plain HTTP to https, e.g. :80", stringValue{&c.HTTPServer.RedirectPort}},
+		{"TLS_CERT_FILE", "PEM certificate file, required for https", stringValue{&c.HTTPServer.TLS.CertFile}},
+		{"TLS_KEY_FILE", "PEM private key file, required for https", stringValue{&c.HTTPServer.TLS.KeyFile}},
+		{"TLS_SELF_SIGNED", "generate a self-signed development certificate, local only", boolValue{&c.HTTPServer.TLS.SelfSigned}},
+		{"TLS_RELOAD_INTERVAL", "how often certificate files are checked for changes, 0 disables it", durationValue{&c.HTTPServer.TLS.ReloadInterval}},
+		{"HTTP_COMPRESS_MIN_SIZE", "smallest response body in bytes worth compressing", intValue{&c.HTTPServer.CompressMinSize}},
+
+		{"GRPC_PORT", "address the gRPC server listens on, e.g. :9090, empty disables it", stringValue{&c.GRPCServer.Port}},
+		{"GRPC_REFLECTION", "enable gRPC server reflection, local only", boolValue{&c.GRPCServer.Reflection}},
+
+		{"CLONE_POLICY", "what to do with cloned authenticators: reject, flag or reregister", stringValue{&c.Auth.ClonePolicy}},
+		{"MAX_FAILED_LOGINS", "failed logins before an account is locked, 0 disables locking", intValue{&c.Auth.MaxFailedLogins}},
+		{"FAILED_LOGIN_WINDOW", "window in which failed logins are counted", durationValue{&c.Auth.FailedLoginWindow}},
+		{"LOCKOUT_DURATION", "how long a locked account stays locked", durationValue{&c.Auth.LockoutDuration}},
+		{"ADMIN_USERS", "comma separated usernames with the admin role", sliceValue{&c.Auth.Admins}},
+		{"API_KEYS", "comma separated client=key pairs of API clients", mapValue{&c.Auth.APIKeys}},
+
+		{"SESSION_COOKIE_NAME", "session cookie name", stringValue{&c.Session.CookieName}},
+		{"SESSION_LIFETIME", "absolute session lifetime", durationValue{&c.Session.Lifetime}},
+		{"SESSION_IDLE_TIMEOUT", "session idle timeout, 0 disables it", durationValue{&c.Session.IdleTimeout}},
+		{"SESSION_CLEANUP_INTERVAL", "how often expired sessions are removed, 0 disables it", durationValue{&c.Session.CleanupInterval}},
+
+		{"TRACING_SERVICE_NAME", "service name reported with traces", stringValue{&c.Tracing.ServiceName}},
+		{"TRACING_EXPORTER", "trace exporter: none, stdout or otlp", stringValue{&c.Tracing.Exporter}},
+		{"TRACING_OTLP_ENDPOINT", "OTLP/HTTP endpoint URL", stringValue{&c.Tracing.OTLPEndpoint}},
+		{"TRACING_OTLP_HEADERS", "comma separated key=value headers sent to the OTLP endpoint", mapValue{&c.Tracing.OTLPHeaders}},
+		{"TRACING_SAMPLE_RATIO", "fraction of traces sampled, between 0 and 1", floatValue{&c.Tracing.SampleRatio}},
+
+		{"LOG_LEVEL", "log level: debug, info, warn or error, empty picks one by environment", stringValue{&c.Logging.Level}},
+		{"LOG_ACCESS_SAMPLE_RATE", "fraction of successful requests written to the access log", floatValue{&c.Logging.AccessSampleRate}},
+		{"LOG_SLOW_REQUEST", "requests slower than this are always logged", durationValue{&c.Logging.SlowRequest}},
+		{"LOG_REDACT_HEADERS", "comma separated headers redacted in logs", sliceValue{&c.Logging.RedactHeaders}},
+
+		{"RATE_LIMIT_API", "API rate limit as requests/period[/burst], 0 disables it", rateLimitValue{&c.RateLimit.API}},
+		{"RATE_LIMIT_AUTH", "login and registration rate limit as requests/period[/burst], 0 disables it", rateLimitValue{&c.RateLimit.Auth}},
+		{"RATE_LIMIT_UPLOAD", "upload rate limit as requests/period[/burst], 0 disables it", rateLimitValue{&c.RateLimit.Upload}},
+
+		{"UPLOAD_MAX_BYTES", "largest upload body in bytes", int64Value{&c.Upload.MaxBytes}},
+		{"UPLOAD_MAX_CONCURRENT", "uploads in flight across all clients, 0 disables the cap", intValue{&c.Upload.MaxConcurrent}},
+		{"UPLOAD_WORKERS", "workers validating the ports of an upload in parallel", intValue{&c.Upload.Workers}},
+		{"UPLOAD_BATCH_SIZE", "ports stored at once by a gRPC upload, queued per HTTP upload worker", intValue{&c.Upload.BatchSize}},
+
+		{"GRAPHQL_QUERY_CACHE_SIZE", "parsed GraphQL queries kept", intValue{&c.GraphQL.QueryCacheSize}},
+		{"GRAPHQL_APQ_CACHE_SIZE", "automatic persisted queries kept", intValue{&c.GraphQL.APQCacheSize}},
+		{"GRAPHQL_METRIC_OPERATIONS", "comma separated GraphQL operation names with their own metrics label", sliceValue{&c.GraphQL.MetricOperations}},
+
+		{"OPENAPI_VALIDATE_REQUESTS", "reject API requests that don't match the OpenAPI specification", boolValue{&c.OpenAPI.ValidateRequests}},
+
+		{"STORAGE_PORT_SHARDS", "maps holding the ports; writes copy the ones they change", intValue{&c.Storage.PortShards}},
+
+		{"FEATURE_GRAPHQL", "serve the GraphQL API and playground", boolValue{&c.Features.GraphQL}},
+		{"FEATURE_UPLOAD", "accept bulk port uploads", boolValue{&c.Features.Upload}},
+		{"FEATURE_PORT_WRITES", "allow updating and deleting single ports", boolValue{&c.Features.PortWrites}},
+
+		{"HEALTH_CHECK_TIMEOUT", "timeout of a single health check", durationValue{&c.Health.CheckTimeout}},
+		{"HEALTH_DRAIN_DELAY", "how long readiness fails before shutdown starts", durationValue{&c.Health.DrainDelay}},
+	}
+	s = append(s, corsSettings("CORS_API", "API", &c.CORS.API)...)
+	s = append(s, corsSettings("CORS_GRAPHQL", "GraphQL", &c.CORS.GraphQL)...)
+	return s
+}
+
+func corsSettings(prefix, group string, p *CORSPolicy) []setting {
+	return []setting{
+		{prefix + "_ALLOWED_ORIGINS", "comma separated origins allowed to call the " + group, sliceValue{&p.AllowedOrigins}},
+		{prefix + "_ALLOWED_METHODS", "comma separated methods allowed on the " + group, sliceValue{&p.AllowedMethods}},
+		{prefix + "_ALLOWED_HEADERS", "comma separated request headers allowed on the " + group, sliceValue{&p.AllowedHeaders}},
+		{prefix + "_EXPOSED_HEADERS", "comma separated response headers exposed by the " + group, sliceValue{&p.ExposedHeaders}},
+		{prefix + "_ALLOW_CREDENTIALS", "allow cookies on cross-origin " + group + " requests", boolValue{&p.AllowCredentials}},
+		{prefix + "_MAX_AGE", "how long browsers may cache " + group + " preflight results", durationValue{&p.MaxAge}},
+	}
+}
+
+type stringValue struct{ p *string }
+
+func (v stringValue) Set(s string) error {
+	*v.p = s
+	return nil
+}
+
+func (v stringValue) String() string { return *v.p }
+
+type durationValue struct{ p *time.Duration }
+
+func (v durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		// Durations used to be whole seconds, keep accepting those.
+		n, nerr := strconv.Atoi(s)
+		if nerr != nil {
+			return err
+		}
+		d = time.Duration(n) * time.Second
+	}
+	*v.p = d
+	return nil
+}
+
+func (v durationValue) String() string { return v.p.String() }
+
+type intValue struct{ p *int }
+
+func (v intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v.p = n
+	return nil
+}
+
+func (v intValue) String() string { return strconv.Itoa(*v.p) }
+
+type int64Value struct{ p *int64 }
+
+func (v int64Value) Set(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*v.p = n
+	return nil
+}
+
+func (v int64Value) String() string { return strconv.FormatInt(*v.p, 10) }
+
+type floatValue struct{ p *float64 }
+
+func (v floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*v.p = f
+	return nil
+}
+
+func (v floatValue) String() string { return strconv.FormatFloat(*v.p, 'g', -1, 64) }
+
+type boolValue struct{ p *bool }
+
+func (v boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v.p = b
+	return nil
+}
+
+func (v boolValue) String() string { return strconv.FormatBool(*v.p) }
+
+func (v boolValue) IsBoolFlag() bool { return true }
+
+// sliceValue is a comma separated list. An empty string sets an empty list.
+type sliceValue struct{ p *[]string }
+
+func (v sliceValue) Set(s string) error {
+	res := []string{}
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			res = append(res, e)
+		}
+	}
+	*v.p = res
+	return nil
+}
+
+func (v sliceValue) String() string { return strings.Join(*v.p, ",") }
+
+// mapValue is a comma separated list of key=value pairs. Its values are
+// never printed.
+type mapValue struct{ p *map[string]string }
+
+func (v mapValue) Set(s string) error {
+	res := map[string]string{}
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+		k, val, ok := strings.Cut(e, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return errors.New("want comma separated key=value pairs")
+		}
+		res[strings.TrimSpace(k)] = strings.TrimSpace(val)
+	}
+	*v.p = res
+	return nil
+}
+
+func (v mapValue) String() string {
+	return strings.Join(slices.Sorted(maps.Keys(*v.p)), ",")
+}
+
+// rateLimitValue is "requests/period[/burst]", e.g. "10/1m" or "10/1m/20".
+// "0" disables the limit.
+type rateLimitValue struct{ p *RateLimitPolicy }
+
+func (v rateLimitValue) Set(s string) error {
+	if s == "0" {
+		*v.p = RateLimitPolicy{}
+		return nil
+	}
+
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf("invalid rate limit %q, want requests/period[/burst]", s)
+	}
+
+	var p RateLimitPolicy
+	var err error
+	if p.Requests, err = strconv.Atoi(parts[0]); err != nil {
+		return fmt.Errorf("invalid rate limit requests: %w", err)
+	}
+	if p.Period, err = time.ParseDuration(parts[1]); err != nil {
+		return fmt.Errorf("invalid rate limit period: %w", err)
+	}
+	if len(parts) == 3 {
+		if p.Burst, err = strconv.Atoi(parts[2]); err != nil {
+			return fmt.Errorf("invalid rate limit burst: %w", err)
+		}
+	}
+	*v.p = p
+	return nil
+}
+
+func (v rateLimitValue) String() string {
+	if v.p.Requests == 0 {
+		return "0"
+	}
+	s := fmt.Sprintf("%d/%s", v.p.Requests, v.p.Period)
+	if v.p.Burst > 0 {
+		s += "/" + strconv.Itoa(v.p.Burst)
+	}
+	return s
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate checks the whole configuration and reports every problem found.
+func (c *Config) Validate() error {
+	var v validator
+
+	v.oneOf("env", c.Env, "local", "dev", "prod")
+	v.positive("graceful_timeout", c.GracefulTimeout)
+
+	v.oneOf("http_server.protocol", c.HTTPServer.Protocol, "http", "https")
+	v.check(c.HTTPServer.Host != "", "http_server.host", "must not be empty")
+	v.check(validPort(c.HTTPServer.Port), "http_server.port", "must be :<1-65535>, got %q", c.HTTPServer.Port)
+	v.nonNegative("http_server.read_timeout", c.HTTPServer.ReadTimeout)
+	v.nonNegative("http_server.write_timeout", c.HTTPServer.WriteTimeout)
+	v.nonNegative("http_server.idle_timeout", c.HTTPServer.IdleTimeout)
+	if c.HTTPServer.Protocol == "https" {
+		tls := c.HTTPServer.TLS
+		v.check(tls.CertFile != "" && tls.KeyFile != "", "http_server.tls", "cert_file and key_file are required for https")
+		v.check(!tls.SelfSigned || c.Env == "local", "http_server.tls.self_signed", "is only allowed in the local environment")
+		v.nonNegative("http_server.tls.reload_interval", tls.ReloadInterval)
+		if c.HTTPServer.RedirectPort != "" {
+			v.check(validPort(c.HTTPServer.RedirectPort) && c.HTTPServer.RedirectPort != c.HTTPServer.Port,
+				"http_server.redirect_port", "must be :<1-65535> and differ from the port, got %q", c.HTTPServer.RedirectPort)
+		}
+	}
+	v.check(c.HTTPServer.CompressMinSize >= 0, "http_server.compress_min_size", "must not be negative")
+
+	if c.GRPCServer.Port != "" {
+		v.check(validPort(c.GRPCServer.Port) && c.GRPCServer.Port != c.HTTPServer.Port && c.GRPCServer.Port != c.HTTPServer.RedirectPort,
+			"grpc_server.port", "must be :<1-65535> and differ from the HTTP ports, got %q", c.GRPCServer.Port)
+		v.check(len(c.Auth.APIKeys) > 0, "grpc_server.port", "requires auth.api_keys, which gRPC clients authenticate with")
+	}
+	v.check(!c.GRPCServer.Reflection || c.Env == "local", "grpc_server.reflection", "is only allowed in the local environment")
+
+	v.oneOf("auth.clone_policy", c.Auth.ClonePolicy, "reject", "flag", "reregister")
+	v.check(c.Auth.MaxFailedLogins >= 0, "auth.max_failed_logins", "must not be negative")
+	if c.Auth.MaxFailedLogins > 0 {
+		v.positive("auth.failed_login_window", c.Auth.FailedLoginWindow)
+		v.positive("auth.lockout_duration", c.Auth.LockoutDuration)
+	}
+
+	for client, key := range c.Auth.APIKeys {
+		v.check(len(key) >= 16, "auth.api_keys", "key of %q must be at least 16 characters", client)
+	}
+
+	v.check(c.Session.CookieName != "", "session.cookie_name", "must not be empty")
+	v.positive("session.lifetime", c.Session.Lifetime)
+	v.nonNegative("session.idle_timeout", c.Session.IdleTimeout)
+	v.nonNegative("session.cleanup_interval", c.Session.CleanupInterval)
+
+	v.oneOf("tracing.exporter", c.Tracing.Exporter, "none", "stdout", "otlp")
+	if c.Tracing.OTLPEndpoint != "" {
+		u, err := url.Parse(c.Tracing.OTLPEndpoint)
+		v.check(err == nil && u.Scheme != "" && u.Host != "", "tracing.otlp_endpoint", "must be an absolute URL")
+	}
+	v.fraction("tracing.sample_ratio", c.Tracing.SampleRatio)
+
+	if c.Logging.Level != "" {
+		var l slog.Level
+		v.check(l.UnmarshalText([]byte(c.Logging.Level)) == nil, "logging.level", "must be debug, info, warn or error, got %q", c.Logging.Level)
+	}
+	v.fraction("logging.access_sample_rate", c.Logging.AccessSampleRate)
+	v.nonNegative("logging.slow_request", c.Logging.SlowRequest)
+
+	v.rateLimit("rate_limit.api", c.RateLimit.API)
+	v.rateLimit("rate_limit.auth", c.RateLimit.Auth)
+	v.rateLimit("rate_limit.upload", c.RateLimit.Upload)
+
+	v.cors("cors.api", c.CORS.API)
+	v.cors("cors.graphql", c.CORS.GraphQL)
+
+	v.check(c.Upload.MaxBytes > 0, "upload.max_bytes", "must be positive")
+	v.check(c.Upload.MaxConcurrent >= 0, "upload.max_concurrent", "must not be negative")
+	v.check(c.Upload.Workers > 0, "upload.workers", "must be positive")
+	v.check(c.Upload.BatchSize > 0, "upload.batch_size", "must be positive")
+
+	v.check(c.GraphQL.QueryCacheSize > 0, "graphql.query_cache_size", "must be positive")
+	v.check(c.GraphQL.APQCacheSize > 0, "graphql.apq_cache_size", "must be positive")
+
+	v.check(c.Storage.PortShards > 0, "storage.port_shards", "must be positive")
+
+	v.positive("health.check_timeout", c.Health.CheckTimeout)
+	v.nonNegative("health.drain_delay", c.Health.DrainDelay)
+
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) check(ok bool, field, format string, args ...any) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s: "+format, append([]any{field}, args...)...))
+	}
+}
+
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	v.check(slices.Contains(allowed, value), field, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+func (v *validator) positive(field string, d time.Duration) {
+	v.check(d > 0, field, "must be positive, got %s", d)
+}
+
+func (v *validator) nonNegative(field string, d time.Duration) {
+	v.check(d >= 0, field, "must not be negative, got %s", d)
+}
+
+func (v *validator) fraction(field string, f float64) {
+	v.check(f >= 0 && f <= 1, field, "must be between 0 and 1, got %g", f)
+}
+
+func (v *validator) rateLimit(field string, p RateLimitPolicy) {
+	v.check(p.Requests >= 0, field+".requests", "must not be negative")
+	v.check(p.Burst >= 0, field+".burst", "must not be negative")
+	if p.Requests > 0 {
+		v.positive(field+".period", p.Period)
+	}
+}
+
+func (v *validator) cors(field string, p CORSPolicy) {
+	for _, o := range p.AllowedOrigins {
+		v.check(validOrigin(o), field+".allowed_origins", "invalid origin %q, want scheme://host[:port] or *", o)
+	}
+	// Allowing credentials from any origin would let every site act as the
+	// logged in user and read the responses, CSRF token included.
+	v.check(!p.AllowCredentials || !slices.Contains(p.AllowedOrigins, "*"),
+		field+".allow_credentials", "can't be combined with the * origin")
+	v.nonNegative(field+".max_age", p.MaxAge)
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(strings.TrimPrefix(port, ":"))
+	return strings.HasPrefix(port, ":") && err == nil && n > 0 && n <= 65535
+}
+
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	scheme, host, ok := strings.Cut(origin, "://")
+	return ok && scheme != "" && host != "" && !strings.Contains(host, "/") && strings.Count(origin, "*") <= 1
+}
@@ -0,0 +1,74 @@
+package audit
+
+import "time"
+
+type Action string
+
+const (
+	ActionRegisterBegin  Action = "webauthn.register.begin"
+	ActionRegisterFinish Action = "webauthn.register.finish"
+	ActionLoginBegin     Action = "webauthn.login.begin"
+	ActionLoginFinish    Action = "webauthn.login.finish"
+	ActionLogout         Action = "webauthn.logout"
+
+	ActionPortUpload Action = "port.upload"
+	ActionPortUpdate Action = "port.update"
+	ActionPortDelete Action = "port.delete"
+	ActionPortRename Action = "port.rename"
+
+	ActionUserUpdate  Action = "user.update"
+	ActionUserRole    Action = "user.role"
+	ActionUserDisable Action = "user.disable"
+	ActionUserEnable  Action = "user.enable"
+	ActionUserDelete  Action = "user.delete"
+	ActionUserUnlock  Action = "user.unlock"
+)
+
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
+// Anonymous is the actor of requests made without a logged in user.
+const Anonymous = "anonymous"
+
+// Entry is a single record of the append-only audit log.
+type Entry struct {
+	ID         string
+	Time       time.Time
+	Actor      string
+	Action     Action
+	Target     string
+	RequestID  string
+	RemoteAddr string
+	UserAgent  string
+	Outcome    Outcome
+	Status     int
+	Detail     string
+}
+
+// Filter selects audit entries. Zero fields match everything.
+type Filter struct {
+	From   time.Time
+	To     time.Time
+	Actor  string
+	Action Action
+}
+
+func (f Filter) Match(e *Entry) bool {
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.Time.Before(f.To) {
+		return false
+	}
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	return true
+}
@@ -8,8 +8,12 @@ import (
 
 var (
 	ErrNotFound   = errors.New("port not found")
+	ErrExists     = errors.New("port already exists")
 	ErrValidation = errors.New("validation error")
 	ErrRequired   = fmt.Errorf("%w: value cannot be empty", ErrValidation)
+	// ErrSnapshotExpired is returned for a listing snapshot that is no longer
+	// held. Listing has to start over.
+	ErrSnapshotExpired = errors.New("snapshot expired")
 )
 
 type Port struct {
@@ -62,7 +66,7 @@ func (p *Port) Name() string {
 
 func (p *Port) SetName(name string) error {
 	if name == "" {
-		return fmt.Errorf("%w: port name is required", ErrRequired)
+		return &ValidationError{Fields: []*FieldError{{Field: "name", Err: ErrRequired}}}
 	}
 	p.name = name
 	return nil
@@ -105,8 +109,13 @@ func (p *Port) Unlocs() []string {
 }
 
 func (p *Port) Copy() (*Port, error) {
+	return p.WithID(p.ID())
+}
+
+// WithID returns a copy of the port under another ID.
+func (p *Port) WithID(id string) (*Port, error) {
 	return New(
-		p.ID(),
+		id,
 		p.Name(),
 		p.Code(),
 		p.City(),
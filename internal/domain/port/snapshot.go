@@ -0,0 +1,42 @@
+package port
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SnapshotID identifies a listing snapshot: the store revision it holds and
+// when, in Unix nanoseconds, that revision was made. Revisions start over
+// when the process restarts; the time tells them apart.
+type SnapshotID struct {
+	Revision uint64
+	Modified int64
+}
+
+func (id SnapshotID) IsZero() bool {
+	return id == SnapshotID{}
+}
+
+// String formats id like the v1 validators, as hexadecimal modified time
+// and revision.
+func (id SnapshotID) String() string {
+	return fmt.Sprintf("%x-%x", id.Modified, id.Revision)
+}
+
+// ParseSnapshotID parses a SnapshotID formatted by String.
+func ParseSnapshotID(s string) (SnapshotID, error) {
+	modified, revision, ok := strings.Cut(s, "-")
+	if !ok {
+		return SnapshotID{}, fmt.Errorf("invalid snapshot: %q", s)
+	}
+	m, err := strconv.ParseInt(modified, 16, 64)
+	if err != nil {
+		return SnapshotID{}, fmt.Errorf("invalid snapshot: %q", s)
+	}
+	r, err := strconv.ParseUint(revision, 16, 64)
+	if err != nil {
+		return SnapshotID{}, fmt.Errorf("invalid snapshot: %q", s)
+	}
+	return SnapshotID{Revision: r, Modified: m}, nil
+}
@@ -1,21 +1,71 @@
 package port
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is a problem with a single field.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason())
+}
+
+// Reason describes the problem without naming the field.
+func (e *FieldError) Reason() string {
+	return strings.TrimPrefix(e.Err.Error(), ErrValidation.Error()+": ")
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every invalid field. It matches ErrValidation and the
+// errors of the individual fields, e.g. ErrRequired.
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return fmt.Sprintf("%s: %s", ErrValidation, strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := []error{ErrValidation}
+	for _, f := range e.Fields {
+		errs = append(errs, f)
+	}
+	return errs
+}
 
 func validate(id, name, city, country string) error {
 	// TODO: add validation library?
-	fields := map[string]string{
-		"port id":      id,
-		"port name":    name,
-		"port city":    city,
-		"port country": country,
+	fields := []struct {
+		name, value string
+	}{
+		{"id", id},
+		{"name", name},
+		{"city", city},
+		{"country", country},
 	}
 
-	for field, value := range fields {
-		if value == "" {
-			return fmt.Errorf("%w: %s", ErrRequired, field)
+	var verr ValidationError
+	for _, f := range fields {
+		if f.value == "" {
+			verr.Fields = append(verr.Fields, &FieldError{Field: f.name, Err: ErrRequired})
 		}
 	}
 
+	if len(verr.Fields) > 0 {
+		return &verr
+	}
 	return nil
 }
@@ -1,6 +1,9 @@
 package port
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestValidate(t *testing.T) {
 	tests := []struct {
@@ -27,3 +30,21 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidate_ReportsEveryField(t *testing.T) {
+	err := validate("id", "", "city", "")
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("validate() error = %v, want *ValidationError", err)
+	}
+	if !errors.Is(err, ErrValidation) || !errors.Is(err, ErrRequired) {
+		t.Errorf("validate() error = %v, want ErrValidation and ErrRequired", err)
+	}
+	if len(verr.Fields) != 2 || verr.Fields[0].Field != "name" || verr.Fields[1].Field != "country" {
+		t.Errorf("validate() fields = %v, want name and country", verr.Fields)
+	}
+	if want := "validation error: name: value cannot be empty; country: value cannot be empty"; err.Error() != want {
+		t.Errorf("validate() error = %q, want %q", err.Error(), want)
+	}
+}
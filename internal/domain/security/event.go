@@ -0,0 +1,24 @@
+package security
+
+import "time"
+
+type EventType string
+
+const (
+	EventCloneWarning    EventType = "clone_warning"
+	EventLoginFailed     EventType = "login_failed"
+	EventAccountLocked   EventType = "account_locked"
+	EventAccountUnlocked EventType = "account_unlocked"
+)
+
+// Event is a single entry of the security event log.
+type Event struct {
+	ID                string
+	Type              EventType
+	UserID            string
+	CredentialID      string
+	StoredSignCount   uint32
+	ReceivedSignCount uint32
+	Detail            string
+	Time              time.Time
+}
@@ -0,0 +1,43 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("session not found")
+
+// Keys the transport layer stores in the session so that the store can index
+// sessions by user and show where they came from.
+const (
+	UserIDKey     = "user_id"
+	UserAgentKey  = "user_agent"
+	RemoteAddrKey = "remote_addr"
+)
+
+type Session struct {
+	Token      string
+	UserID     string
+	UserAgent  string
+	RemoteAddr string
+	Data       []byte
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Expiry     time.Time
+}
+
+// ID is a stable public identifier of the session that does not reveal the token.
+func (s *Session) ID() string {
+	return IDFromToken(s.Token)
+}
+
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.Expiry)
+}
+
+func IDFromToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
@@ -1,8 +1,12 @@
 package user
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/go-webauthn/webauthn/webauthn"
 )
@@ -10,16 +14,56 @@ import (
 // TODO: extract to a separate package
 var (
 	ErrNotFound   = errors.New("user not found")
+	ErrExists     = errors.New("user already exists")
 	ErrValidation = errors.New("validation error")
 	ErrRequired   = fmt.Errorf("%w: value cannot be empty", ErrValidation)
+	ErrLocked     = errors.New("user account is locked")
+	ErrDisabled   = errors.New("user account is disabled")
+	ErrRole       = fmt.Errorf("%w: unknown role", ErrValidation)
+
+	ErrCloneDetected          = errors.New("authenticator clone detected")
+	ErrReregistrationRequired = fmt.Errorf("%w: credential must be registered again", ErrCloneDetected)
+)
+
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+func ParseRole(s string) (Role, error) {
+	switch r := Role(s); r {
+	case RoleUser, RoleAdmin:
+		return r, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrRole, s)
+}
+
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusLocked   Status = "locked"
+	StatusDisabled Status = "disabled"
 )
 
 type User struct {
 	ID          []byte
 	DisplayName string
 	Name        string
+	Email       string
+	Role        Role
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	disabled bool
+
+	creds      []webauthn.Credential
+	suspicious map[string]bool
 
-	creds []webauthn.Credential
+	failedLogins []time.Time
+	lockedUntil  time.Time
 }
 
 func New(id string, name, displayName string) (*User, error) {
@@ -33,13 +77,74 @@ func New(id string, name, displayName string) (*User, error) {
 		return nil, ErrRequired
 	}
 
+	now := time.Now()
 	return &User{
 		ID:          []byte(id),
 		Name:        name,
 		DisplayName: displayName,
+		Role:        RoleUser,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}, nil
 }
 
+// Clone returns a copy that can be changed without affecting o. Users held
+// by a store are shared with concurrent readers, so change a clone and put it
+// back instead.
+func (o *User) Clone() *User {
+	c := *o
+	c.ID = bytes.Clone(o.ID)
+	c.creds = slices.Clone(o.creds)
+	c.suspicious = maps.Clone(o.suspicious)
+	c.failedLogins = slices.Clone(o.failedLogins)
+	return &c
+}
+
+func (o *User) SetProfile(displayName, email string) error {
+	if displayName == "" {
+		return fmt.Errorf("%w: display name", ErrRequired)
+	}
+	o.DisplayName = displayName
+	o.Email = email
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+func (o *User) SetRole(role Role) {
+	o.Role = role
+	o.UpdatedAt = time.Now()
+}
+
+func (o *User) IsAdmin() bool {
+	return o.Role == RoleAdmin
+}
+
+func (o *User) Disable() {
+	o.disabled = true
+	o.UpdatedAt = time.Now()
+}
+
+func (o *User) Enable() {
+	o.disabled = false
+	o.UpdatedAt = time.Now()
+}
+
+func (o *User) IsDisabled() bool {
+	return o.disabled
+}
+
+// Status reports the account status at the given time. Disabling takes
+// precedence over a temporary lockout.
+func (o *User) Status(now time.Time) Status {
+	switch {
+	case o.disabled:
+		return StatusDisabled
+	case o.IsLocked(now):
+		return StatusLocked
+	}
+	return StatusActive
+}
+
 func (o *User) WebAuthnID() []byte                            { return o.ID }
 func (o *User) WebAuthnName() string                          { return o.Name }
 func (o *User) WebAuthnDisplayName() string                   { return o.DisplayName }
@@ -52,3 +157,76 @@ func (o *User) UpdateCredential(credential *webauthn.Credential) {
 		}
 	}
 }
+
+// RemoveCredential drops the credential so the authenticator has to be registered again.
+func (o *User) RemoveCredential(id []byte) {
+	for i, c := range o.creds {
+		if string(c.ID) == string(id) {
+			o.creds = append(o.creds[:i], o.creds[i+1:]...)
+			delete(o.suspicious, string(id))
+			return
+		}
+	}
+}
+
+// FlagCredential marks the credential as possibly cloned.
+func (o *User) FlagCredential(id []byte) {
+	if o.suspicious == nil {
+		o.suspicious = make(map[string]bool)
+	}
+	o.suspicious[string(id)] = true
+}
+
+func (o *User) IsCredentialSuspicious(id []byte) bool {
+	return o.suspicious[string(id)]
+}
+
+// SuspiciousCredentials returns the IDs of the registered credentials that
+// are flagged as possibly cloned, in registration order.
+func (o *User) SuspiciousCredentials() [][]byte {
+	var ids [][]byte
+	for _, c := range o.creds {
+		if o.suspicious[string(c.ID)] {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+func (o *User) IsLocked(now time.Time) bool {
+	return now.Before(o.lockedUntil)
+}
+
+func (o *User) LockedUntil() time.Time {
+	return o.lockedUntil
+}
+
+// RegisterFailedLogin records a failed login at now and locks the account for
+// lockFor once max failures happened within window. It reports whether the
+// account got locked by this call.
+func (o *User) RegisterFailedLogin(now time.Time, window time.Duration, max int, lockFor time.Duration) bool {
+	recent := o.failedLogins[:0]
+	for _, t := range o.failedLogins {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	o.failedLogins = append(recent, now)
+
+	if max <= 0 || len(o.failedLogins) < max {
+		return false
+	}
+
+	o.lockedUntil = now.Add(lockFor)
+	o.failedLogins = nil
+	return true
+}
+
+func (o *User) ResetFailedLogins() {
+	o.failedLogins = nil
+}
+
+func (o *User) Unlock() {
+	o.lockedUntil = time.Time{}
+	o.failedLogins = nil
+}
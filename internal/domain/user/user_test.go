@@ -0,0 +1,102 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterFailedLogin_LocksAfterThreshold(t *testing.T) {
+	u, err := New("id", "name", "display")
+	require.NoError(t, err)
+
+	now := time.Now()
+	assert.False(t, u.RegisterFailedLogin(now, time.Minute, 3, time.Hour))
+	assert.False(t, u.RegisterFailedLogin(now.Add(time.Second), time.Minute, 3, time.Hour))
+	assert.True(t, u.RegisterFailedLogin(now.Add(2*time.Second), time.Minute, 3, time.Hour))
+
+	assert.True(t, u.IsLocked(now.Add(time.Minute)))
+	assert.False(t, u.IsLocked(now.Add(2*time.Hour)))
+}
+
+func TestRegisterFailedLogin_IgnoresFailuresOutsideWindow(t *testing.T) {
+	u, _ := New("id", "name", "display")
+
+	now := time.Now()
+	u.RegisterFailedLogin(now, time.Minute, 2, time.Hour)
+	locked := u.RegisterFailedLogin(now.Add(2*time.Minute), time.Minute, 2, time.Hour)
+
+	assert.False(t, locked)
+	assert.False(t, u.IsLocked(now.Add(2*time.Minute)))
+}
+
+func TestUnlock(t *testing.T) {
+	u, _ := New("id", "name", "display")
+
+	now := time.Now()
+	require.True(t, u.RegisterFailedLogin(now, time.Minute, 1, time.Hour))
+	u.Unlock()
+
+	assert.False(t, u.IsLocked(now))
+}
+
+func TestFlagAndRemoveCredential(t *testing.T) {
+	u, _ := New("id", "name", "display")
+	u.AddCredential(&webauthn.Credential{ID: []byte("cred1")})
+	u.AddCredential(&webauthn.Credential{ID: []byte("cred2")})
+
+	u.FlagCredential([]byte("cred1"))
+	assert.True(t, u.IsCredentialSuspicious([]byte("cred1")))
+	assert.False(t, u.IsCredentialSuspicious([]byte("cred2")))
+
+	u.RemoveCredential([]byte("cred1"))
+	assert.Len(t, u.WebAuthnCredentials(), 1)
+	assert.False(t, u.IsCredentialSuspicious([]byte("cred1")))
+}
+
+func TestClone(t *testing.T) {
+	u, _ := New("id", "name", "display")
+	u.AddCredential(&webauthn.Credential{ID: []byte("cred1")})
+	now := time.Now()
+	u.RegisterFailedLogin(now, time.Minute, 3, time.Hour)
+
+	c := u.Clone()
+	c.SetRole(RoleAdmin)
+	c.AddCredential(&webauthn.Credential{ID: []byte("cred2")})
+	c.FlagCredential([]byte("cred1"))
+	c.RegisterFailedLogin(now, time.Minute, 2, time.Hour)
+
+	assert.False(t, u.IsAdmin())
+	assert.Len(t, u.WebAuthnCredentials(), 1)
+	assert.False(t, u.IsCredentialSuspicious([]byte("cred1")))
+	assert.False(t, u.IsLocked(now))
+	assert.True(t, c.IsLocked(now))
+}
+
+func TestStatus(t *testing.T) {
+	u, _ := New("id", "name", "display")
+	now := time.Now()
+	assert.Equal(t, StatusActive, u.Status(now))
+
+	u.RegisterFailedLogin(now, time.Minute, 1, time.Hour)
+	assert.Equal(t, StatusLocked, u.Status(now))
+
+	u.Disable()
+	assert.Equal(t, StatusDisabled, u.Status(now))
+
+	u.Enable()
+	u.Unlock()
+	assert.Equal(t, StatusActive, u.Status(now))
+}
+
+func TestParseRole(t *testing.T) {
+	r, err := ParseRole("admin")
+	require.NoError(t, err)
+	assert.Equal(t, RoleAdmin, r)
+
+	_, err = ParseRole("root")
+	assert.ErrorIs(t, err, ErrValidation)
+}
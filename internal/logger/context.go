@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey int
+
+const loggerKey ctxKey = 0
+
+// WithContext returns a copy of ctx carrying log.
+func WithContext(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, log)
+}
+
+// FromContext returns the request-scoped logger stored in ctx, or the default
+// logger when there is none (background jobs, startup, tests).
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if log, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+			return log
+		}
+	}
+	return slog.Default()
+}
+
+// With adds attributes to the logger in ctx, e.g. the user ID once a login
+// completes, and returns the updated context.
+func With(ctx context.Context, args ...any) context.Context {
+	return WithContext(ctx, FromContext(ctx).With(args...))
+}
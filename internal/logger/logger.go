@@ -11,18 +11,33 @@ const (
 	Production  = "prod"
 )
 
-func Setup(env string) *slog.Logger {
+// Setup installs the default logger for env. level can be a *slog.LevelVar
+// to change the level while running.
+func Setup(env string, level slog.Leveler) *slog.Logger {
 	var log *slog.Logger
 
 	switch env {
 	case Local:
-		log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: true}))
-	case Development:
-		log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: true}))
-	case Production:
-		log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo, AddSource: true}))
+		log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level, AddSource: true}))
+	default: // Development, production and any unknown environment
+		log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level, AddSource: true}))
 	}
 
 	slog.SetDefault(log)
 	return log
 }
+
+// Level parses level, e.g. "debug" or "warn". An empty or invalid level
+// gives the default for env: debug outside production.
+func Level(env, level string) slog.Level {
+	var l slog.Level
+	if level != "" && l.UnmarshalText([]byte(level)) == nil {
+		return l
+	}
+	switch env {
+	case Local, Development:
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "port_service"
+
+// UnmatchedRoute labels requests that did not match any registered pattern,
+// so that arbitrary paths can't blow up label cardinality.
+const UnmatchedRoute = "unmatched"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	httpInFlight *prometheus.GaugeVec
+	apiRequests  *prometheus.CounterVec
+
+	uploadPorts      prometheus.Counter
+	uploadBytes      prometheus.Counter
+	uploadRejections *prometheus.CounterVec
+
+	graphqlDuration *prometheus.HistogramVec
+
+	webauthnCeremonies *prometheus.CounterVec
+
+	configReloads          *prometheus.CounterVec
+	configLastReload       prometheus.Gauge
+	configLastReloadStatus prometheus.Gauge
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "code"}),
+		httpInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Number of HTTP requests being served by route pattern.",
+		}, []string{"route"}),
+
+		apiRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_requests_total",
+			Help:      "Number of REST API requests by API version; legacy counts the deprecated unversioned paths.",
+		}, []string{"version"}),
+
+		uploadPorts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upload_ports_total",
+			Help:      "Number of ports stored by bulk uploads.",
+		}),
+		uploadBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upload_bytes_total",
+			Help:      "Number of request body bytes read by bulk uploads.",
+		}),
+		uploadRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upload_rejections_total",
+			Help:      "Number of rejected bulk uploads by reason.",
+		}, []string{"reason"}),
+
+		graphqlDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "graphql_operation_duration_seconds",
+			Help:      "GraphQL operation latency by operation type and name.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"type", "name", "outcome"}),
+
+		webauthnCeremonies: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webauthn_ceremonies_total",
+			Help:      "Number of WebAuthn ceremonies by ceremony and outcome.",
+		}, []string{"ceremony", "outcome"}),
+
+		configReloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "config_reloads_total",
+			Help:      "Number of configuration reloads by outcome.",
+		}, []string{"outcome"}),
+		configLastReload: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "config_last_reload_timestamp_seconds",
+			Help:      "Unix time of the last configuration reload attempt.",
+		}),
+		configLastReloadStatus: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "config_last_reload_successful",
+			Help:      "Whether the last configuration reload succeeded.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.httpInFlight,
+		m.apiRequests,
+		m.uploadPorts,
+		m.uploadBytes,
+		m.uploadRejections,
+		m.graphqlDuration,
+		m.webauthnCeremonies,
+		m.configReloads,
+		m.configLastReload,
+		m.configLastReloadStatus,
+	)
+	// The configuration the process started with loaded fine.
+	m.configLastReloadStatus.Set(1)
+
+	return m
+}
+
+// Handler serves the registry in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterGaugeFunc exposes a value computed at scrape time, e.g. a store size.
+func (m *Metrics) RegisterGaugeFunc(name, help string, fn func() float64) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// InFlight marks a request to route as started and returns a func that marks it done.
+func (m *Metrics) InFlight(route string) func() {
+	g := m.httpInFlight.WithLabelValues(routeLabel(route))
+	g.Inc()
+	return g.Dec
+}
+
+func (m *Metrics) ObserveHTTP(route, method string, status int, d time.Duration) {
+	labels := []string{routeLabel(route), method, strconv.Itoa(status)}
+	m.httpRequests.WithLabelValues(labels...).Inc()
+	m.httpDuration.WithLabelValues(labels...).Observe(d.Seconds())
+}
+
+func (m *Metrics) APIRequest(version string) {
+	m.apiRequests.WithLabelValues(version).Inc()
+}
+
+func (m *Metrics) UploadedPorts(n int) {
+	m.uploadPorts.Add(float64(n))
+}
+
+func (m *Metrics) UploadedBytes(n int64) {
+	m.uploadBytes.Add(float64(n))
+}
+
+func (m *Metrics) UploadRejected(reason string) {
+	m.uploadRejections.WithLabelValues(reason).Inc()
+}
+
+func (m *Metrics) ObserveGraphQL(opType, name string, failed bool, d time.Duration) {
+	m.graphqlDuration.WithLabelValues(opType, name, outcome(failed)).Observe(d.Seconds())
+}
+
+func (m *Metrics) WebAuthnCeremony(ceremony string, failed bool) {
+	m.webauthnCeremonies.WithLabelValues(ceremony, outcome(failed)).Inc()
+}
+
+func (m *Metrics) ConfigReloaded(failed bool) {
+	m.configReloads.WithLabelValues(outcome(failed)).Inc()
+	m.configLastReload.SetToCurrentTime()
+	if failed {
+		m.configLastReloadStatus.Set(0)
+	} else {
+		m.configLastReloadStatus.Set(1)
+	}
+}
+
+func routeLabel(route string) string {
+	if route == "" {
+		return UnmatchedRoute
+	}
+	return route
+}
+
+func outcome(failed bool) string {
+	if failed {
+		return "failure"
+	}
+	return "success"
+}
@@ -1,10 +1,12 @@
 package renderer
 
 import (
+	"context"
 	"errors"
 	"log"
 	"net/http"
 	"path/filepath"
+	"sync/atomic"
 	"text/template"
 )
 
@@ -12,7 +14,9 @@ const templateBasePath = "templates"
 
 type TemplateCache map[string]*template.Template
 
-func NewTemplateCache() (TemplateCache, error) {
+// NewTemplateCache parses every page together with the layouts. funcs are
+// available to all templates.
+func NewTemplateCache(funcs template.FuncMap) (TemplateCache, error) {
 	cache := TemplateCache{}
 
 	layouts, err := filepath.Glob(templateBasePath + "/layouts/*.html")
@@ -28,7 +32,7 @@ func NewTemplateCache() (TemplateCache, error) {
 	for _, page := range pages {
 		files := append(layouts, page)
 		name := filepath.Base(page)
-		tmpl, err := template.ParseFiles(files...)
+		tmpl, err := template.New(name).Funcs(funcs).ParseFiles(files...)
 		if err != nil {
 			return nil, err
 		}
@@ -38,26 +42,57 @@ func NewTemplateCache() (TemplateCache, error) {
 	return cache, nil
 }
 
+// TemplateData is passed to every template. Page specific data is in Data.
+type TemplateData struct {
+	CSRFToken string
+	Data      any
+}
+
 type TemplateRenderer struct {
-	templates TemplateCache
+	templates atomic.Pointer[TemplateCache]
+	funcs     template.FuncMap
+	csrfToken func(r *http.Request) string
 }
 
-func NewTemplateRenderer() *TemplateRenderer {
-	templates, err := NewTemplateCache()
-	if err != nil {
+func NewTemplateRenderer(csrfToken func(r *http.Request) string, funcs template.FuncMap) *TemplateRenderer {
+	tr := &TemplateRenderer{funcs: funcs, csrfToken: csrfToken}
+	if err := tr.Reload(); err != nil {
 		log.Fatal("failed to create TemplateRenderer: ", err)
 	}
-	return &TemplateRenderer{templates: templates}
+	return tr
+}
+
+// Reload parses the templates again. Pages being rendered finish with the
+// old templates. On error the old templates stay in use.
+func (tr *TemplateRenderer) Reload() error {
+	templates, err := NewTemplateCache(tr.funcs)
+	if err != nil {
+		return err
+	}
+	tr.templates.Store(&templates)
+	return nil
 }
 
-func (r *TemplateRenderer) Render(w http.ResponseWriter, name string, data any) error {
-	tmpl, ok := r.templates[name]
+func (tr *TemplateRenderer) Render(w http.ResponseWriter, r *http.Request, name string, data any) error {
+	tmpl, ok := (*tr.templates.Load())[name]
 	if !ok {
 		return errors.New("template not found: " + name)
 	}
-	err := tmpl.ExecuteTemplate(w, "layout", data)
+	td := TemplateData{Data: data}
+	if tr.csrfToken != nil {
+		td.CSRFToken = tr.csrfToken(r)
+	}
+	err := tmpl.ExecuteTemplate(w, "layout", td)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// Check reports whether the template cache is usable.
+func (tr *TemplateRenderer) Check(_ context.Context) error {
+	if len(*tr.templates.Load()) == 0 {
+		return errors.New("template cache is empty")
+	}
+	return nil
+}
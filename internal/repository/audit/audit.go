@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/axmz/go-port-service/internal/domain/audit"
+)
+
+type InMem[T any] interface {
+	GetAll(ctx context.Context) []T
+	Put(ctx context.Context, key string, value T)
+}
+
+// Repository is append-only: entries can be added and queried but never
+// changed or removed.
+type Repository struct {
+	db  InMem[*audit.Entry]
+	seq *atomic.Uint64
+}
+
+func New(db InMem[*audit.Entry]) *Repository {
+	return &Repository{
+		db:  db,
+		seq: &atomic.Uint64{},
+	}
+}
+
+func (r Repository) Append(ctx context.Context, e *audit.Entry) error {
+	e.ID = fmt.Sprintf("%012d", r.seq.Add(1))
+	r.db.Put(ctx, e.ID, e)
+	return nil
+}
+
+// Query returns matching entries in chronological order.
+func (r Repository) Query(ctx context.Context, f audit.Filter) ([]*audit.Entry, error) {
+	var res []*audit.Entry
+	for _, e := range r.db.GetAll(ctx) {
+		if f.Match(e) {
+			res = append(res, e)
+		}
+	}
+
+	slices.SortFunc(res, func(a, b *audit.Entry) int {
+		if c := a.Time.Compare(b.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return res, nil
+}
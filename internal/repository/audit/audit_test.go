@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axmz/go-port-service/internal/domain/audit"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+func TestAuditRepository_Query(t *testing.T) {
+	repo := New(inmem.New[*audit.Entry]())
+	ctx := context.Background()
+	now := time.Now()
+
+	entries := []*audit.Entry{
+		{Time: now.Add(-2 * time.Hour), Actor: "alice", Action: audit.ActionLoginFinish},
+		{Time: now.Add(-time.Hour), Actor: "alice", Action: audit.ActionPortUpload},
+		{Time: now, Actor: "bob", Action: audit.ActionPortDelete},
+	}
+	for _, e := range entries {
+		require.NoError(t, repo.Append(ctx, e))
+	}
+
+	tests := []struct {
+		name   string
+		filter audit.Filter
+		want   int
+	}{
+		{"no filter", audit.Filter{}, 3},
+		{"by actor", audit.Filter{Actor: "alice"}, 2},
+		{"by action", audit.Filter{Action: audit.ActionPortDelete}, 1},
+		{"from", audit.Filter{From: now.Add(-90 * time.Minute)}, 2},
+		{"to is exclusive", audit.Filter{To: now}, 2},
+		{"time range and actor", audit.Filter{From: now.Add(-90 * time.Minute), To: now, Actor: "alice"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.Query(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Len(t, got, tt.want)
+		})
+	}
+
+	all, _ := repo.Query(ctx, audit.Filter{})
+	assert.Equal(t, audit.ActionLoginFinish, all[0].Action, "expected chronological order")
+}
@@ -2,29 +2,46 @@ package port
 
 import (
 	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/pkg/inmem"
 )
 
+var tracer = otel.Tracer("github.com/axmz/go-port-service/internal/repository/port")
+
 type InMem[T any] interface {
 	Get(ctx context.Context, key string) (T, bool)
 	GetAll(ctx context.Context) []T
 	Put(ctx context.Context, key string, value T)
+	PutMany(ctx context.Context, entries map[string]T)
 	Delete(ctx context.Context, key string) (T, bool)
 	Len(ctx context.Context) int
+	Revision(ctx context.Context) (uint64, time.Time)
+	Snapshot(ctx context.Context) *inmem.Snapshot[T]
+	Begin(ctx context.Context) *inmem.Tx[T]
 }
 
 type Repository struct {
-	db InMem[*Port]
+	db        InMem[*Port]
+	snapshots *snapshots
 }
 
 func New(db InMem[*Port]) *Repository {
 	return &Repository{
-		db: db,
+		db:        db,
+		snapshots: newSnapshots(),
 	}
 }
 
 func (r Repository) Get(ctx context.Context, id string) (*port.Port, error) {
+	ctx, span := tracer.Start(ctx, "PortRepository.Get", trace.WithAttributes(attribute.String("port.id", id)))
+	defer span.End()
+
 	portDb, exists := r.db.Get(ctx, id)
 	if !exists {
 		return nil, port.ErrNotFound
@@ -39,6 +56,9 @@ func (r Repository) Get(ctx context.Context, id string) (*port.Port, error) {
 }
 
 func (r Repository) GetAll(ctx context.Context) ([]*port.Port, error) {
+	ctx, span := tracer.Start(ctx, "PortRepository.GetAll")
+	defer span.End()
+
 	arr := r.db.GetAll(ctx)
 	res := make([]*port.Port, 0, len(arr))
 
@@ -53,11 +73,34 @@ func (r Repository) GetAll(ctx context.Context) ([]*port.Port, error) {
 	return res, nil
 }
 
+// List returns a page of ports ordered by ID along with the total number of
+// ports.
+func (r Repository) List(ctx context.Context, offset, limit int) ([]*port.Port, int, error) {
+	ctx, span := tracer.Start(ctx, "PortRepository.List", trace.WithAttributes(
+		attribute.Int("page.offset", offset),
+		attribute.Int("page.limit", limit),
+	))
+	defer span.End()
+
+	return page(sortByID(r.db.GetAll(ctx)), offset, limit)
+}
+
 func (r Repository) Count(ctx context.Context) int {
+	ctx, span := tracer.Start(ctx, "PortRepository.Count")
+	defer span.End()
+
 	return r.db.Len(ctx)
 }
 
+// Revision returns the store revision and the time of the last change.
+func (r Repository) Revision(ctx context.Context) (uint64, time.Time) {
+	return r.db.Revision(ctx)
+}
+
 func (r Repository) Upload(ctx context.Context, p *port.Port) error {
+	ctx, span := tracer.Start(ctx, "PortRepository.Upload", trace.WithAttributes(attribute.String("port.id", p.ID())))
+	defer span.End()
+
 	portRepo, err := fromDomainToRepository(p)
 	if err != nil {
 		return err
@@ -66,7 +109,28 @@ func (r Repository) Upload(ctx context.Context, p *port.Port) error {
 	return nil
 }
 
+// UploadBatch stores ports in one write. A later port replaces an earlier one
+// with the same ID.
+func (r Repository) UploadBatch(ctx context.Context, ports []*port.Port) error {
+	ctx, span := tracer.Start(ctx, "PortRepository.UploadBatch", trace.WithAttributes(attribute.Int("port.count", len(ports))))
+	defer span.End()
+
+	entries := make(map[string]*Port, len(ports))
+	for _, p := range ports {
+		portRepo, err := fromDomainToRepository(p)
+		if err != nil {
+			return err
+		}
+		entries[portRepo.ID] = portRepo
+	}
+	r.db.PutMany(ctx, entries)
+	return nil
+}
+
 func (r Repository) Delete(ctx context.Context, id string) (*port.Port, error) {
+	ctx, span := tracer.Start(ctx, "PortRepository.Delete", trace.WithAttributes(attribute.String("port.id", id)))
+	defer span.End()
+
 	portDb, exists := r.db.Delete(ctx, id)
 	if !exists {
 		return nil, port.ErrNotFound
@@ -3,6 +3,7 @@ package port
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -12,7 +13,7 @@ import (
 )
 
 func setupIntegrationRepo() *Repository {
-	db := inmem.New[*Port]()
+	db := inmem.NewSharded[*Port](inmem.ShardedOptions{})
 	return New(db)
 }
 
@@ -58,3 +59,68 @@ func TestIntegration_PortRepository_Flow(t *testing.T) {
 		assert.Error(t, err, "expected error for missing port after delete")
 	})
 }
+
+func TestIntegration_PortRepository_Snapshot(t *testing.T) {
+	repo := New(inmem.NewSharded[*Port](inmem.ShardedOptions{}))
+	ctx := context.Background()
+
+	upload := func(ids ...string) {
+		t.Helper()
+		ports := make([]*domain.Port, 0, len(ids))
+		for _, id := range ids {
+			p, err := domain.New(id, "Port "+id, "", "City", "Country", nil, nil, nil, "", "", nil)
+			require.NoError(t, err)
+			ports = append(ports, p)
+		}
+		require.NoError(t, repo.UploadBatch(ctx, ports))
+	}
+	ids := func(ports []*domain.Port) []string {
+		res := make([]string, 0, len(ports))
+		for _, p := range ports {
+			res = append(res, p.ID())
+		}
+		return res
+	}
+
+	upload("b", "d", "f")
+	snap, err := repo.Snapshot(ctx)
+	require.NoError(t, err)
+
+	first, total, err := repo.ListSnapshot(ctx, snap, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "d"}, ids(first))
+	assert.Equal(t, 3, total)
+
+	// Ports added and removed between pages don't shift the snapshot.
+	upload("a", "c")
+	_, err = repo.Delete(ctx, "f")
+	require.NoError(t, err)
+
+	second, total, err := repo.ListSnapshot(ctx, snap, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"f"}, ids(second))
+	assert.Equal(t, 3, total)
+
+	current, total, err := repo.List(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, ids(current))
+	assert.Equal(t, 4, total)
+
+	again, err := repo.Snapshot(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, snap, again)
+
+	_, _, err = repo.ListSnapshot(ctx, domain.SnapshotID{Revision: 12345}, 0, 10)
+	assert.ErrorIs(t, err, domain.ErrSnapshotExpired)
+
+	// The same revision of an earlier process was made at another time.
+	restarted := snap
+	restarted.Modified--
+	_, _, err = repo.ListSnapshot(ctx, restarted, 0, 10)
+	assert.ErrorIs(t, err, domain.ErrSnapshotExpired)
+
+	now := time.Now().Add(SnapshotTTL + time.Second)
+	repo.snapshots.now = func() time.Time { return now }
+	_, _, err = repo.ListSnapshot(ctx, snap, 0, 10)
+	assert.ErrorIs(t, err, domain.ErrSnapshotExpired)
+}
@@ -5,41 +5,19 @@ import (
 	"testing"
 
 	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/pkg/inmem"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// mockInMem is backed by a real store, so that the snapshots and
+// transactions it hands out see what the other methods wrote.
 type mockInMem struct {
-	store map[string]*Port
+	*inmem.ShardedDB[*Port]
 }
 
 func newMockInMem() *mockInMem {
-	return &mockInMem{store: make(map[string]*Port)}
-}
-
-func (m *mockInMem) Get(ctx context.Context, key string) (*Port, bool) {
-	val, ok := m.store[key]
-	return val, ok
-}
-func (m *mockInMem) GetAll(ctx context.Context) []*Port {
-	res := make([]*Port, 0, len(m.store))
-	for _, v := range m.store {
-		res = append(res, v)
-	}
-	return res
-}
-func (m *mockInMem) Put(ctx context.Context, key string, value *Port) {
-	m.store[key] = value
-}
-func (m *mockInMem) Delete(ctx context.Context, key string) (*Port, bool) {
-	val, ok := m.store[key]
-	if ok {
-		delete(m.store, key)
-	}
-	return val, ok
-}
-func (m *mockInMem) Len(ctx context.Context) int {
-	return len(m.store)
+	return &mockInMem{ShardedDB: inmem.NewSharded[*Port](inmem.ShardedOptions{})}
 }
 
 // helpers for conversion
@@ -110,6 +88,32 @@ func TestPortRepository(t *testing.T) {
 		assert.ErrorIs(t, err, port.ErrNotFound)
 	})
 
+	t.Run("Tx", func(t *testing.T) {
+		t.Parallel()
+		mem := newMockInMem()
+		repo := New(mem)
+		ctx := context.Background()
+		require.NoError(t, repo.Upload(ctx, testDomainPort()))
+
+		tx := repo.Begin(ctx)
+		got, err := tx.Get(ctx, "id1")
+		require.NoError(t, err, "the transaction sees the stored ports")
+		assert.Equal(t, "id1", got.ID())
+
+		renamed, err := got.WithID("id2")
+		require.NoError(t, err)
+		require.NoError(t, tx.Upload(ctx, renamed))
+		_, err = tx.Delete(ctx, "id1")
+		require.NoError(t, err)
+		require.NoError(t, inmem.CommitAll(ctx, tx.Committer()))
+
+		_, err = repo.Get(ctx, "id1")
+		assert.ErrorIs(t, err, port.ErrNotFound)
+		got, err = repo.Get(ctx, "id2")
+		require.NoError(t, err, "the commit is stored")
+		assert.Equal(t, "name", got.Name())
+	})
+
 	t.Run("Delete_NotFound", func(t *testing.T) {
 		mem := newMockInMem()
 		repo := New(mem)
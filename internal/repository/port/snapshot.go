@@ -0,0 +1,145 @@
+package port
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+const (
+	// SnapshotTTL is how long a snapshot is held after it was last listed.
+	SnapshotTTL = 5 * time.Minute
+	// maxSnapshots bounds the snapshots held at once; the least recently
+	// listed one is dropped first.
+	maxSnapshots = 16
+)
+
+type heldSnapshot struct {
+	snap   *inmem.Snapshot[*Port]
+	listed time.Time
+}
+
+// snapshots holds the store versions handed out for pagination, keyed by
+// revision. A held snapshot shares its data with the store until the store
+// changes, and then only keeps the shards written since, so holding one
+// doesn't copy the catalog.
+type snapshots struct {
+	mu   sync.Mutex
+	held map[uint64]*heldSnapshot
+	now  func() time.Time
+}
+
+func newSnapshots() *snapshots {
+	return &snapshots{
+		held: make(map[uint64]*heldSnapshot),
+		now:  time.Now,
+	}
+}
+
+func (s *snapshots) hold(rev uint64, snap *inmem.Snapshot[*Port]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for r, h := range s.held {
+		if now.Sub(h.listed) > SnapshotTTL {
+			delete(s.held, r)
+		}
+	}
+	if _, ok := s.held[rev]; !ok && len(s.held) >= maxSnapshots {
+		var oldest uint64
+		for r, h := range s.held {
+			if o, ok := s.held[oldest]; !ok || h.listed.Before(o.listed) {
+				oldest = r
+			}
+		}
+		delete(s.held, oldest)
+	}
+	if h, ok := s.held[rev]; ok {
+		h.listed = now
+		return
+	}
+	s.held[rev] = &heldSnapshot{snap: snap, listed: now}
+}
+
+func (s *snapshots) get(rev uint64) (*inmem.Snapshot[*Port], bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.held[rev]
+	if !ok {
+		return nil, false
+	}
+	now := s.now()
+	if now.Sub(h.listed) > SnapshotTTL {
+		delete(s.held, rev)
+		return nil, false
+	}
+	h.listed = now
+	return h.snap, true
+}
+
+// Snapshot holds the current version of the ports for SnapshotTTL and returns
+// its handle for ListSnapshot. Pages listed from it stay consistent with each
+// other however the ports change in between.
+func (r Repository) Snapshot(ctx context.Context) (port.SnapshotID, error) {
+	ctx, span := tracer.Start(ctx, "PortRepository.Snapshot")
+	defer span.End()
+
+	snap := r.db.Snapshot(ctx)
+	rev, modified := snap.Revision()
+	r.snapshots.hold(rev, snap)
+	id := port.SnapshotID{Revision: rev, Modified: modified.UnixNano()}
+	span.SetAttributes(attribute.String("snapshot", id.String()))
+	return id, nil
+}
+
+// ListSnapshot is List on a snapshot taken by Snapshot. It fails with
+// port.ErrSnapshotExpired once the snapshot isn't held anymore, including when
+// it was taken by an earlier process. Like List, it orders the ports for
+// every page rather than keeping them ordered.
+func (r Repository) ListSnapshot(ctx context.Context, snapshot port.SnapshotID, offset, limit int) ([]*port.Port, int, error) {
+	_, span := tracer.Start(ctx, "PortRepository.ListSnapshot", trace.WithAttributes(
+		attribute.String("snapshot", snapshot.String()),
+		attribute.Int("page.offset", offset),
+		attribute.Int("page.limit", limit),
+	))
+	defer span.End()
+
+	snap, ok := r.snapshots.get(snapshot.Revision)
+	if !ok {
+		return nil, 0, port.ErrSnapshotExpired
+	}
+	if _, modified := snap.Revision(); modified.UnixNano() != snapshot.Modified {
+		return nil, 0, port.ErrSnapshotExpired
+	}
+	return page(sortByID(snap.GetAll()), offset, limit)
+}
+
+func sortByID(ports []*Port) []*Port {
+	slices.SortFunc(ports, func(a, b *Port) int { return strings.Compare(a.ID, b.ID) })
+	return ports
+}
+
+// page converts the ports from offset to offset+limit and counts them all.
+func page(all []*Port, offset, limit int) ([]*port.Port, int, error) {
+	total := len(all)
+	if offset >= total {
+		return []*port.Port{}, total, nil
+	}
+	res := make([]*port.Port, 0, min(limit, total-offset))
+	for _, v := range all[offset:min(offset+limit, total)] {
+		p, err := fromRepositoryToDomain(v)
+		if err != nil {
+			return nil, 0, err
+		}
+		res = append(res, p)
+	}
+	return res, total, nil
+}
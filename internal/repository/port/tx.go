@@ -0,0 +1,49 @@
+package port
+
+import (
+	"context"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+// Tx changes ports in a transaction. Its changes are visible to it right
+// away and to everyone else once it is committed, see unitofwork.
+type Tx struct {
+	tx *inmem.Tx[*Port]
+}
+
+// Begin starts a transaction on the current ports.
+func (r Repository) Begin(ctx context.Context) *Tx {
+	return &Tx{tx: r.db.Begin(ctx)}
+}
+
+func (t *Tx) Get(_ context.Context, id string) (*port.Port, error) {
+	portDb, exists := t.tx.Get(id)
+	if !exists {
+		return nil, port.ErrNotFound
+	}
+	return fromRepositoryToDomain(portDb)
+}
+
+func (t *Tx) Upload(_ context.Context, p *port.Port) error {
+	portRepo, err := fromDomainToRepository(p)
+	if err != nil {
+		return err
+	}
+	t.tx.Put(portRepo.ID, portRepo)
+	return nil
+}
+
+func (t *Tx) Delete(_ context.Context, id string) (*port.Port, error) {
+	portDb, exists := t.tx.Delete(id)
+	if !exists {
+		return nil, port.ErrNotFound
+	}
+	return fromRepositoryToDomain(portDb)
+}
+
+// Committer commits or rolls back the transaction.
+func (t *Tx) Committer() inmem.Committer {
+	return t.tx
+}
@@ -0,0 +1,55 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/axmz/go-port-service/internal/domain/security"
+)
+
+type InMem[T any] interface {
+	GetAll(ctx context.Context) []T
+	Put(ctx context.Context, key string, value T)
+}
+
+type Repository struct {
+	db  InMem[*security.Event]
+	seq *atomic.Uint64
+}
+
+func New(db InMem[*security.Event]) *Repository {
+	return &Repository{
+		db:  db,
+		seq: &atomic.Uint64{},
+	}
+}
+
+func (r Repository) Append(ctx context.Context, e *security.Event) error {
+	e.ID = fmt.Sprintf("%012d", r.seq.Add(1))
+	r.db.Put(ctx, e.ID, e)
+	return nil
+}
+
+// List returns events in chronological order. An empty userID returns events
+// for all users.
+func (r Repository) List(ctx context.Context, userID string) ([]*security.Event, error) {
+	all := r.db.GetAll(ctx)
+	res := make([]*security.Event, 0, len(all))
+	for _, e := range all {
+		if userID == "" || e.UserID == userID {
+			res = append(res, e)
+		}
+	}
+
+	slices.SortFunc(res, func(a, b *security.Event) int {
+		if c := a.Time.Compare(b.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return res, nil
+}
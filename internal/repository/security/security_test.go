@@ -0,0 +1,34 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axmz/go-port-service/internal/domain/security"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+func TestSecurityRepository_AppendAndList(t *testing.T) {
+	repo := New(inmem.New[*security.Event]())
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, repo.Append(ctx, &security.Event{Type: security.EventLoginFailed, UserID: "bob", Time: now.Add(time.Second)}))
+	require.NoError(t, repo.Append(ctx, &security.Event{Type: security.EventCloneWarning, UserID: "alice", Time: now}))
+	require.NoError(t, repo.Append(ctx, &security.Event{Type: security.EventAccountLocked, UserID: "bob", Time: now.Add(time.Second)}))
+
+	all, err := repo.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, "alice", all[0].UserID)
+	assert.Equal(t, security.EventLoginFailed, all[1].Type)
+	assert.Equal(t, security.EventAccountLocked, all[2].Type)
+
+	bob, err := repo.List(ctx, "bob")
+	require.NoError(t, err)
+	assert.Len(t, bob, 2)
+}
@@ -0,0 +1,192 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+
+	"github.com/axmz/go-port-service/internal/domain/session"
+	"github.com/axmz/go-port-service/internal/logger"
+)
+
+type InMem[T any] interface {
+	Get(ctx context.Context, key string) (T, bool)
+	GetAll(ctx context.Context) []T
+	Put(ctx context.Context, key string, value T)
+	Delete(ctx context.Context, key string) (T, bool)
+}
+
+// Repository is an scs.Store that keeps sessions in the application database
+// and indexes them by user so they can be listed and revoked.
+type Repository struct {
+	db    InMem[*session.Session]
+	codec scs.Codec
+	now   func() time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+var (
+	_ scs.CtxStore         = (*Repository)(nil)
+	_ scs.IterableCtxStore = (*Repository)(nil)
+)
+
+// New creates the store and starts a sweeper that removes expired sessions
+// every cleanupInterval. A zero interval disables the sweeper.
+func New(db InMem[*session.Session], codec scs.Codec, cleanupInterval time.Duration) *Repository {
+	r := &Repository{
+		db:    db,
+		codec: codec,
+		now:   time.Now,
+		stop:  make(chan struct{}),
+	}
+	if cleanupInterval > 0 {
+		go r.cleanup(cleanupInterval)
+	}
+	return r
+}
+
+func (r *Repository) Find(token string) ([]byte, bool, error) {
+	return r.FindCtx(context.Background(), token)
+}
+
+func (r *Repository) Commit(token string, b []byte, expiry time.Time) error {
+	return r.CommitCtx(context.Background(), token, b, expiry)
+}
+
+func (r *Repository) Delete(token string) error {
+	return r.DeleteCtx(context.Background(), token)
+}
+
+func (r *Repository) All() (map[string][]byte, error) {
+	return r.AllCtx(context.Background())
+}
+
+func (r *Repository) FindCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	s, ok := r.db.Get(ctx, token)
+	if !ok {
+		return nil, false, nil
+	}
+	if s.Expired(r.now()) {
+		r.db.Delete(ctx, token)
+		return nil, false, nil
+	}
+	return s.Data, true, nil
+}
+
+func (r *Repository) CommitCtx(ctx context.Context, token string, b []byte, expiry time.Time) error {
+	now := r.now()
+	s := &session.Session{
+		Token:     token,
+		Data:      b,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Expiry:    expiry,
+	}
+	if prev, ok := r.db.Get(ctx, token); ok {
+		s.CreatedAt = prev.CreatedAt
+	}
+
+	// Index the session by the values the transport layer put into it.
+	if _, values, err := r.codec.Decode(b); err == nil {
+		s.UserID, _ = values[session.UserIDKey].(string)
+		s.UserAgent, _ = values[session.UserAgentKey].(string)
+		s.RemoteAddr, _ = values[session.RemoteAddrKey].(string)
+	} else {
+		logger.FromContext(ctx).Warn("failed to decode session data", slog.Any("error", err))
+	}
+
+	r.db.Put(ctx, token, s)
+	return nil
+}
+
+func (r *Repository) DeleteCtx(ctx context.Context, token string) error {
+	r.db.Delete(ctx, token)
+	return nil
+}
+
+func (r *Repository) AllCtx(ctx context.Context) (map[string][]byte, error) {
+	now := r.now()
+	res := make(map[string][]byte)
+	for _, s := range r.db.GetAll(ctx) {
+		if !s.Expired(now) {
+			res[s.Token] = s.Data
+		}
+	}
+	return res, nil
+}
+
+// ListByUser returns the user's sessions that have not expired yet.
+func (r *Repository) ListByUser(ctx context.Context, userID string) ([]*session.Session, error) {
+	now := r.now()
+	var res []*session.Session
+	for _, s := range r.db.GetAll(ctx) {
+		if s.UserID == userID && !s.Expired(now) {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+// DeleteByUser removes every session of the user and returns how many were removed.
+func (r *Repository) DeleteByUser(ctx context.Context, userID string) (int, error) {
+	n := 0
+	for _, s := range r.db.GetAll(ctx) {
+		if s.UserID == userID {
+			r.db.Delete(ctx, s.Token)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *Repository) DeleteExpired(ctx context.Context) {
+	now := r.now()
+	for _, s := range r.db.GetAll(ctx) {
+		if s.Expired(now) {
+			r.db.Delete(ctx, s.Token)
+		}
+	}
+}
+
+func (r *Repository) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.DeleteExpired(context.Background())
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+var ErrStoreClosed = errors.New("session store is shut down")
+
+// Ping reports whether the store is still accepting sessions.
+func (r *Repository) Ping(ctx context.Context) error {
+	select {
+	case <-r.stop:
+		return ErrStoreClosed
+	default:
+	}
+	_, _, err := r.FindCtx(ctx, "health-check")
+	return err
+}
+
+// Shutdown stops the expired session sweeper.
+func (r *Repository) Shutdown(ctx context.Context) error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axmz/go-port-service/internal/domain/session"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+func encode(t *testing.T, values map[string]any) []byte {
+	b, err := scs.GobCodec{}.Encode(time.Now().Add(time.Hour), values)
+	require.NoError(t, err)
+	return b
+}
+
+func TestSessionRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := New(inmem.New[*session.Session](), scs.GobCodec{}, 0)
+	expiry := time.Now().Add(time.Hour)
+
+	require.NoError(t, repo.Commit("t1", encode(t, map[string]any{session.UserIDKey: "alice"}), expiry))
+	require.NoError(t, repo.Commit("t2", encode(t, map[string]any{session.UserIDKey: "alice"}), expiry))
+	require.NoError(t, repo.Commit("t3", encode(t, map[string]any{session.UserIDKey: "bob"}), expiry))
+	require.NoError(t, repo.Commit("t4", encode(t, nil), expiry))
+
+	t.Run("find", func(t *testing.T) {
+		b, found, err := repo.Find("t1")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.NotEmpty(t, b)
+	})
+
+	t.Run("list by user", func(t *testing.T) {
+		sessions, err := repo.ListByUser(ctx, "alice")
+		require.NoError(t, err)
+		assert.Len(t, sessions, 2)
+	})
+
+	t.Run("delete by user", func(t *testing.T) {
+		n, err := repo.DeleteByUser(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		_, found, _ := repo.Find("t1")
+		assert.False(t, found)
+		_, found, _ = repo.Find("t3")
+		assert.True(t, found)
+	})
+
+	t.Run("expired sessions are not found", func(t *testing.T) {
+		require.NoError(t, repo.Commit("old", encode(t, nil), time.Now().Add(-time.Second)))
+		_, found, err := repo.Find("old")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	require.NoError(t, repo.Shutdown(ctx))
+}
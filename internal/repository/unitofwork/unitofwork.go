@@ -0,0 +1,87 @@
+// Package unitofwork groups changes to several repositories into one
+// transaction.
+package unitofwork
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/internal/domain/user"
+	portRepository "github.com/axmz/go-port-service/internal/repository/port"
+	userRepository "github.com/axmz/go-port-service/internal/repository/user"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+var tracer = otel.Tracer("github.com/axmz/go-port-service/internal/repository/unitofwork")
+
+// MaxAttempts is how many times Do runs a unit of work that keeps conflicting
+// with concurrent changes before it gives up.
+const MaxAttempts = 3
+
+type Ports interface {
+	Get(ctx context.Context, id string) (*port.Port, error)
+	Upload(ctx context.Context, p *port.Port) error
+	Delete(ctx context.Context, id string) (*port.Port, error)
+}
+
+type Users interface {
+	Get(ctx context.Context, id string) (*user.User, error)
+	Put(ctx context.Context, u *user.User) (*user.User, error)
+	Delete(ctx context.Context, id string) (*user.User, error)
+}
+
+// UnitOfWork is what a function passed to Do reads and changes. Its reads see
+// its own changes; nobody else sees them before Do commits.
+type UnitOfWork struct {
+	Ports Ports
+	Users Users
+}
+
+type Runner struct {
+	ports *portRepository.Repository
+	users *userRepository.Repository
+}
+
+func New(ports *portRepository.Repository, users *userRepository.Repository) *Runner {
+	return &Runner{
+		ports: ports,
+		users: users,
+	}
+}
+
+// Do runs fn and commits its changes to all repositories together if it
+// returns nil. If another change to what fn read or wrote was committed in
+// the meantime, fn runs again on fresh data, up to MaxAttempts times, so it
+// must not have side effects outside the unit of work.
+func (r *Runner) Do(ctx context.Context, fn func(ctx context.Context, uow UnitOfWork) error) error {
+	ctx, span := tracer.Start(ctx, "UnitOfWork.Do")
+	defer span.End()
+
+	var err error
+	for attempt := 1; attempt <= MaxAttempts; attempt++ {
+		span.SetAttributes(attribute.Int("attempts", attempt))
+		if err = r.attempt(ctx, fn); !errors.Is(err, inmem.ErrConflict) {
+			break
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (r *Runner) attempt(ctx context.Context, fn func(ctx context.Context, uow UnitOfWork) error) error {
+	ports, users := r.ports.Begin(ctx), r.users.Begin(ctx)
+	if err := fn(ctx, UnitOfWork{Ports: ports, Users: users}); err != nil {
+		ports.Committer().Rollback()
+		users.Committer().Rollback()
+		return err
+	}
+	return inmem.CommitAll(ctx, ports.Committer(), users.Committer())
+}
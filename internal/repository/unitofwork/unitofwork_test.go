@@ -0,0 +1,186 @@
+package unitofwork
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/internal/domain/user"
+	portRepository "github.com/axmz/go-port-service/internal/repository/port"
+	userRepository "github.com/axmz/go-port-service/internal/repository/user"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+func setup(t *testing.T) (*Runner, *portRepository.Repository, *userRepository.Repository) {
+	ports := portRepository.New(inmem.NewSharded[*portRepository.Port](inmem.ShardedOptions{}))
+	users := userRepository.New(inmem.NewSharded[*user.User](inmem.ShardedOptions{}))
+	return New(ports, users), ports, users
+}
+
+func newPort(t *testing.T, id, name string) *port.Port {
+	p, err := port.New(id, name, "", "City", "Country", nil, nil, nil, "", "", nil)
+	require.NoError(t, err)
+	return p
+}
+
+func TestDo_CommitsBothStores(t *testing.T) {
+	ctx := context.Background()
+	uow, ports, users := setup(t)
+
+	err := uow.Do(ctx, func(ctx context.Context, uow UnitOfWork) error {
+		if err := uow.Ports.Upload(ctx, newPort(t, "A", "Alpha")); err != nil {
+			return err
+		}
+		u, err := user.New("alice", "alice", "Alice")
+		require.NoError(t, err)
+		if _, err := uow.Users.Put(ctx, u); err != nil {
+			return err
+		}
+
+		// Reads see the unit's own writes, nobody else does yet.
+		got, err := uow.Ports.Get(ctx, "A")
+		require.NoError(t, err)
+		assert.Equal(t, "Alpha", got.Name())
+		_, err = ports.Get(ctx, "A")
+		assert.ErrorIs(t, err, port.ErrNotFound)
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = ports.Get(ctx, "A")
+	assert.NoError(t, err)
+	_, err = users.Get(ctx, "alice")
+	assert.NoError(t, err)
+}
+
+func TestDo_ErrorRollsBack(t *testing.T) {
+	ctx := context.Background()
+	uow, ports, users := setup(t)
+	errStop := errors.New("stop")
+
+	err := uow.Do(ctx, func(ctx context.Context, uow UnitOfWork) error {
+		_ = uow.Ports.Upload(ctx, newPort(t, "A", "Alpha"))
+		u, _ := user.New("alice", "alice", "Alice")
+		_, _ = uow.Users.Put(ctx, u)
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+
+	assert.Zero(t, ports.Count(ctx))
+	_, err = users.Get(ctx, "alice")
+	assert.ErrorIs(t, err, user.ErrNotFound)
+}
+
+func TestDo_RetriesConflicts(t *testing.T) {
+	ctx := context.Background()
+	uow, ports, _ := setup(t)
+	require.NoError(t, ports.Upload(ctx, newPort(t, "A", "v0")))
+
+	// The first attempt loses to a concurrent upload and runs again on the
+	// port it uploaded.
+	var attempts int
+	err := uow.Do(ctx, func(ctx context.Context, uow UnitOfWork) error {
+		attempts++
+		p, err := uow.Ports.Get(ctx, "A")
+		if err != nil {
+			return err
+		}
+		if attempts == 1 {
+			require.NoError(t, ports.Upload(ctx, newPort(t, "A", "v1")))
+		}
+		return uow.Ports.Upload(ctx, newPort(t, "A", p.Name()+"+"))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+
+	p, err := ports.Get(ctx, "A")
+	require.NoError(t, err)
+	assert.Equal(t, "v1+", p.Name())
+}
+
+func TestDo_GivesUpAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	uow, ports, _ := setup(t)
+	require.NoError(t, ports.Upload(ctx, newPort(t, "A", "v0")))
+
+	var attempts int
+	err := uow.Do(ctx, func(ctx context.Context, uow UnitOfWork) error {
+		attempts++
+		if _, err := uow.Ports.Get(ctx, "A"); err != nil {
+			return err
+		}
+		require.NoError(t, ports.Upload(ctx, newPort(t, "A", "other")))
+		return nil
+	})
+	assert.ErrorIs(t, err, inmem.ErrConflict)
+	assert.Equal(t, MaxAttempts, attempts)
+}
+
+// TestDo_ConcurrentConflicting runs units of work that all move the same
+// port on to the next of ten IDs. Without conflict detection moves would be
+// lost, or the port duplicated.
+func TestDo_ConcurrentConflicting(t *testing.T) {
+	ctx := context.Background()
+	uow, ports, _ := setup(t)
+	require.NoError(t, ports.Upload(ctx, newPort(t, "0", "Moving")))
+
+	move := func() error {
+		return uow.Do(ctx, func(ctx context.Context, uow UnitOfWork) error {
+			for i := range 10 {
+				from, to := strconv.Itoa(i), strconv.Itoa((i+1)%10)
+				p, err := uow.Ports.Get(ctx, from)
+				if errors.Is(err, port.ErrNotFound) {
+					continue
+				} else if err != nil {
+					return err
+				}
+				runtime.Gosched() // let other moves commit in between
+				moved, err := p.WithID(to)
+				if err != nil {
+					return err
+				}
+				if err := uow.Ports.Upload(ctx, moved); err != nil {
+					return err
+				}
+				_, err = uow.Ports.Delete(ctx, from)
+				return err
+			}
+			return port.ErrNotFound
+		})
+	}
+
+	var wg sync.WaitGroup
+	var moved, conflicts atomic.Int64
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				err := move()
+				switch {
+				case err == nil:
+					moved.Add(1)
+				case errors.Is(err, inmem.ErrConflict):
+					conflicts.Add(1)
+				default:
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	all, err := ports.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 1, "the port is neither lost nor duplicated")
+	assert.Equal(t, strconv.Itoa(int(moved.Load()%10)), all[0].ID(), "every committed move took effect once")
+	assert.Equal(t, int64(8*20), moved.Load()+conflicts.Load())
+}
@@ -0,0 +1,45 @@
+package user
+
+import (
+	"context"
+
+	"github.com/axmz/go-port-service/internal/domain/user"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+// Tx changes users in a transaction. Its changes are visible to it right
+// away and to everyone else once it is committed, see unitofwork.
+type Tx struct {
+	tx *inmem.Tx[*user.User]
+}
+
+// Begin starts a transaction on the current users.
+func (r Repository) Begin(ctx context.Context) *Tx {
+	return &Tx{tx: r.db.Begin(ctx)}
+}
+
+func (t *Tx) Get(_ context.Context, id string) (*user.User, error) {
+	u, exists := t.tx.Get(id)
+	if !exists {
+		return nil, user.ErrNotFound
+	}
+	return u, nil
+}
+
+func (t *Tx) Put(_ context.Context, u *user.User) (*user.User, error) {
+	t.tx.Put(string(u.ID), u)
+	return u, nil
+}
+
+func (t *Tx) Delete(_ context.Context, id string) (*user.User, error) {
+	u, exists := t.tx.Delete(id)
+	if !exists {
+		return nil, user.ErrNotFound
+	}
+	return u, nil
+}
+
+// Committer commits or rolls back the transaction.
+func (t *Tx) Committer() inmem.Committer {
+	return t.tx
+}
@@ -2,14 +2,20 @@ package user
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strings"
 
 	"github.com/axmz/go-port-service/internal/domain/user"
+	"github.com/axmz/go-port-service/pkg/inmem"
 )
 
 type InMem[T any] interface {
 	Get(ctx context.Context, key string) (T, bool)
+	GetAll(ctx context.Context) []T
 	Put(ctx context.Context, key string, value T)
 	Delete(ctx context.Context, key string) (T, bool)
+	Begin(ctx context.Context) *inmem.Tx[T]
 }
 
 type Repository struct {
@@ -31,6 +37,43 @@ func (r Repository) Get(ctx context.Context, id string) (*user.User, error) {
 	return u, nil
 }
 
+// List returns a page of users ordered by creation time along with the total
+// number of users.
+func (r Repository) List(ctx context.Context, offset, limit int) ([]*user.User, int, error) {
+	all := r.db.GetAll(ctx)
+	slices.SortFunc(all, func(a, b *user.User) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.ID), string(b.ID))
+	})
+
+	total := len(all)
+	if offset >= total {
+		return []*user.User{}, total, nil
+	}
+	end := min(offset+limit, total)
+
+	return all[offset:end], total, nil
+}
+
+// Create stores u unless a user with its id exists, in which case it returns
+// user.ErrExists.
+func (r Repository) Create(ctx context.Context, u *user.User) (*user.User, error) {
+	tx := r.db.Begin(ctx)
+	if _, exists := tx.Get(string(u.ID)); exists {
+		tx.Rollback()
+		return nil, user.ErrExists
+	}
+	tx.Put(string(u.ID), u)
+	if err := tx.Commit(ctx); errors.Is(err, inmem.ErrConflict) {
+		return nil, user.ErrExists
+	} else if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
 func (r Repository) Put(ctx context.Context, u *user.User) (*user.User, error) {
 	r.db.Put(ctx, string(u.ID), u)
 	return u, nil
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/axmz/go-port-service/internal/domain/audit"
+	"github.com/axmz/go-port-service/internal/logger"
+)
+
+type AuditRepository interface {
+	Append(ctx context.Context, e *audit.Entry) error
+	Query(ctx context.Context, f audit.Filter) ([]*audit.Entry, error)
+}
+
+type Service struct {
+	repo AuditRepository
+}
+
+func New(r AuditRepository) *Service {
+	return &Service{
+		repo: r,
+	}
+}
+
+// Record appends the entry to the audit log. Failures are logged and not
+// returned so that auditing never fails the audited operation.
+func (s *Service) Record(ctx context.Context, e *audit.Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if err := s.repo.Append(ctx, e); err != nil {
+		logger.FromContext(ctx).Error("failed to record audit entry",
+			slog.String("action", string(e.Action)),
+			slog.Any("error", err),
+		)
+	}
+}
+
+func (s *Service) Query(ctx context.Context, f audit.Filter) ([]*audit.Entry, error) {
+	return s.repo.Query(ctx, f)
+}
+
+type entryCtxType int
+
+const entryKey entryCtxType = 0
+
+// WithEntry attaches an entry under construction to the context so that
+// handlers can annotate it.
+func WithEntry(ctx context.Context, e *audit.Entry) context.Context {
+	return context.WithValue(ctx, entryKey, e)
+}
+
+// Annotate sets the target and detail of the entry attached to ctx, if any.
+// Empty values leave the current ones in place.
+func Annotate(ctx context.Context, target, detail string) {
+	e, ok := ctx.Value(entryKey).(*audit.Entry)
+	if !ok {
+		return
+	}
+	if target != "" {
+		e.Target = target
+	}
+	if detail != "" {
+		e.Detail = detail
+	}
+}
@@ -2,44 +2,223 @@ package port
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/internal/repository/unitofwork"
+)
+
+var tracer = otel.Tracer("github.com/axmz/go-port-service/internal/services/port")
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
 )
 
 type PortRepository interface {
 	Get(ctx context.Context, id string) (*port.Port, error)
 	GetAll(ctx context.Context) ([]*port.Port, error)
+	Snapshot(ctx context.Context) (port.SnapshotID, error)
+	ListSnapshot(ctx context.Context, snapshot port.SnapshotID, offset, limit int) ([]*port.Port, int, error)
 	Count(ctx context.Context) int
 	Upload(ctx context.Context, p *port.Port) error
+	UploadBatch(ctx context.Context, ports []*port.Port) error
 	Delete(ctx context.Context, id string) (*port.Port, error)
+	Revision(ctx context.Context) (uint64, time.Time)
+}
+
+// UnitOfWork runs fn as one transaction across the repositories.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, uow unitofwork.UnitOfWork) error) error
+}
+
+type Page struct {
+	Ports    []*port.Port
+	Page     int
+	PageSize int
+	Total    int
+	// Snapshot is the handle to list the other pages with.
+	Snapshot port.SnapshotID
 }
 
 type Service struct {
-	port PortRepository
+	port   PortRepository
+	uow    UnitOfWork
+	broker broker
 }
 
-func New(r PortRepository) *Service {
+func New(r PortRepository, uow UnitOfWork) *Service {
 	return &Service{
 		port: r,
+		uow:  uow,
 	}
 }
 
 func (p *Service) Get(ctx context.Context, id string) (*port.Port, error) {
-	return p.port.Get(ctx, id)
+	ctx, span := tracer.Start(ctx, "PortService.Get", trace.WithAttributes(attribute.String("port.id", id)))
+	defer span.End()
+
+	res, err := p.port.Get(ctx, id)
+	recordError(span, err)
+	return res, err
 }
 
 func (p *Service) GetAll(ctx context.Context) ([]*port.Port, error) {
-	return p.port.GetAll(ctx)
+	ctx, span := tracer.Start(ctx, "PortService.GetAll")
+	defer span.End()
+
+	res, err := p.port.GetAll(ctx)
+	recordError(span, err)
+	span.SetAttributes(attribute.Int("port.count", len(res)))
+	return res, err
+}
+
+// List returns the 1-based page of ports ordered by ID. Out of range page
+// sizes are clamped. Pages are listed from a snapshot of the ports, so that
+// changes in between don't shift them: a zero snapshot takes a new one, and
+// the returned page carries the handle for the next pages.
+func (p *Service) List(ctx context.Context, snapshot port.SnapshotID, page, pageSize int) (*Page, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	pageSize = min(pageSize, MaxPageSize)
+
+	ctx, span := tracer.Start(ctx, "PortService.List", trace.WithAttributes(
+		attribute.Int("page", page),
+		attribute.Int("page.size", pageSize),
+	))
+	defer span.End()
+
+	if snapshot.IsZero() {
+		var err error
+		if snapshot, err = p.port.Snapshot(ctx); err != nil {
+			recordError(span, err)
+			return nil, err
+		}
+	}
+
+	ports, total, err := p.port.ListSnapshot(ctx, snapshot, (page-1)*pageSize, pageSize)
+	recordError(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{
+		Ports:    ports,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+		Snapshot: snapshot,
+	}, nil
 }
 
 func (p *Service) Count(ctx context.Context) int {
+	ctx, span := tracer.Start(ctx, "PortService.Count")
+	defer span.End()
+
 	return p.port.Count(ctx)
 }
 
-func (p *Service) Upload(ctx context.Context, port *port.Port) error {
-	return p.port.Upload(ctx, port)
+// Revision changes whenever any port changes; read endpoints derive their
+// validators from it. It is too cheap to be worth a span.
+func (p *Service) Revision(ctx context.Context) (uint64, time.Time) {
+	return p.port.Revision(ctx)
+}
+
+func (p *Service) Upload(ctx context.Context, newPort *port.Port) error {
+	ctx, span := tracer.Start(ctx, "PortService.Upload", trace.WithAttributes(attribute.String("port.id", newPort.ID())))
+	defer span.End()
+
+	err := p.port.Upload(ctx, newPort)
+	recordError(span, err)
+	if err == nil {
+		p.broker.publish(Event{Type: EventUpserted, Ports: []*port.Port{newPort}})
+	}
+	return err
+}
+
+// UploadBatch stores ports in one repository write, published to watchers as
+// one event.
+func (p *Service) UploadBatch(ctx context.Context, ports []*port.Port) error {
+	ctx, span := tracer.Start(ctx, "PortService.UploadBatch", trace.WithAttributes(attribute.Int("port.count", len(ports))))
+	defer span.End()
+
+	err := p.port.UploadBatch(ctx, ports)
+	recordError(span, err)
+	if err == nil && len(ports) > 0 {
+		p.broker.publish(Event{Type: EventUpserted, Ports: ports})
+	}
+	return err
 }
 
 func (p *Service) Delete(ctx context.Context, id string) (*port.Port, error) {
-	return p.port.Delete(ctx, id)
+	ctx, span := tracer.Start(ctx, "PortService.Delete", trace.WithAttributes(attribute.String("port.id", id)))
+	defer span.End()
+
+	res, err := p.port.Delete(ctx, id)
+	recordError(span, err)
+	if err == nil {
+		p.broker.publish(Event{Type: EventDeleted, Ports: []*port.Port{res}})
+	}
+	return res, err
+}
+
+// Rename moves a port to a new ID. Readers see the port under either ID,
+// never both or neither.
+func (p *Service) Rename(ctx context.Context, id, newID string) (*port.Port, error) {
+	ctx, span := tracer.Start(ctx, "PortService.Rename", trace.WithAttributes(
+		attribute.String("port.id", id),
+		attribute.String("port.new_id", newID),
+	))
+	defer span.End()
+
+	var old, renamed *port.Port
+	err := p.uow.Do(ctx, func(ctx context.Context, uow unitofwork.UnitOfWork) error {
+		var err error
+		if old, err = uow.Ports.Get(ctx, id); err != nil {
+			return err
+		}
+		if newID == id {
+			renamed = old
+			return nil
+		}
+		if _, err := uow.Ports.Get(ctx, newID); err == nil {
+			return fmt.Errorf("%w: %s", port.ErrExists, newID)
+		} else if !errors.Is(err, port.ErrNotFound) {
+			return err
+		}
+		if renamed, err = old.WithID(newID); err != nil {
+			return err
+		}
+		if err := uow.Ports.Upload(ctx, renamed); err != nil {
+			return err
+		}
+		_, err = uow.Ports.Delete(ctx, id)
+		return err
+	})
+	recordError(span, err)
+	if err != nil {
+		return nil, err
+	}
+	if newID != id {
+		p.broker.publish(Event{Type: EventDeleted, Ports: []*port.Port{old}})
+		p.broker.publish(Event{Type: EventUpserted, Ports: []*port.Port{renamed}})
+	}
+	return renamed, nil
+}
+
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
 }
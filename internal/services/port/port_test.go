@@ -0,0 +1,154 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/internal/domain/user"
+	portRepository "github.com/axmz/go-port-service/internal/repository/port"
+	"github.com/axmz/go-port-service/internal/repository/unitofwork"
+	userRepository "github.com/axmz/go-port-service/internal/repository/user"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+func TestService_Tracing(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+
+	svc := New(portRepository.New(inmem.NewSharded[*portRepository.Port](inmem.ShardedOptions{})), nil)
+	ctx := context.Background()
+
+	p, err := port.New("id1", "name", "code", "city", "country", nil, nil, nil, "", "", nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.Upload(ctx, p))
+
+	_, err = svc.Get(ctx, "missing")
+	assert.ErrorIs(t, err, port.ErrNotFound)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 4)
+
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range spans {
+		byName[s.Name()] = s
+	}
+
+	svcSpan, repoSpan := byName["PortService.Upload"], byName["PortRepository.Upload"]
+	require.NotNil(t, svcSpan)
+	require.NotNil(t, repoSpan)
+	assert.Equal(t, svcSpan.SpanContext().SpanID(), repoSpan.Parent().SpanID(), "expected repository span to be a child of the service span")
+
+	assert.Len(t, byName["PortService.Get"].Events(), 1, "expected the not found error to be recorded")
+}
+
+func TestService_List(t *testing.T) {
+	svc := New(portRepository.New(inmem.NewSharded[*portRepository.Port](inmem.ShardedOptions{})), nil)
+	ctx := context.Background()
+
+	for _, id := range []string{"C", "A", "B"} {
+		p, err := port.New(id, "name", "", "city", "country", nil, nil, nil, "", "", nil)
+		require.NoError(t, err)
+		require.NoError(t, svc.Upload(ctx, p))
+	}
+
+	page, err := svc.List(ctx, port.SnapshotID{}, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+	require.Len(t, page.Ports, 2)
+	assert.Equal(t, "A", page.Ports[0].ID())
+	assert.Equal(t, "B", page.Ports[1].ID())
+	assert.NotZero(t, page.Snapshot)
+
+	// The next page comes from the same snapshot, without the new port.
+	p, err := port.New("0", "name", "", "city", "country", nil, nil, nil, "", "", nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.Upload(ctx, p))
+
+	next, err := svc.List(ctx, page.Snapshot, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, next.Total)
+	require.Len(t, next.Ports, 1)
+	assert.Equal(t, "C", next.Ports[0].ID())
+
+	page, err = svc.List(ctx, port.SnapshotID{}, 3, 2)
+	require.NoError(t, err)
+	assert.Empty(t, page.Ports)
+	assert.Equal(t, 4, page.Total)
+
+	_, err = svc.List(ctx, port.SnapshotID{Revision: 12345}, 1, 2)
+	assert.ErrorIs(t, err, port.ErrSnapshotExpired)
+
+	page, err = svc.List(ctx, port.SnapshotID{}, 0, MaxPageSize+1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.Page)
+	assert.Equal(t, MaxPageSize, page.PageSize)
+}
+
+func TestService_Rename(t *testing.T) {
+	repo := portRepository.New(inmem.NewSharded[*portRepository.Port](inmem.ShardedOptions{}))
+	users := userRepository.New(inmem.NewSharded[*user.User](inmem.ShardedOptions{}))
+	svc := New(repo, unitofwork.New(repo, users))
+	ctx := context.Background()
+
+	for _, id := range []string{"OLD", "TAKEN"} {
+		p, err := port.New(id, "name "+id, "", "city", "country", nil, nil, nil, "", "", nil)
+		require.NoError(t, err)
+		require.NoError(t, svc.Upload(ctx, p))
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := svc.Watch(watchCtx)
+
+	renamed, err := svc.Rename(ctx, "OLD", "NEW")
+	require.NoError(t, err)
+	assert.Equal(t, "NEW", renamed.ID())
+	assert.Equal(t, "name OLD", renamed.Name())
+
+	_, err = svc.Get(ctx, "OLD")
+	assert.ErrorIs(t, err, port.ErrNotFound)
+	_, err = svc.Get(ctx, "NEW")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, svc.Count(ctx))
+
+	assert.Equal(t, EventDeleted, (<-w.Events()).Type)
+	assert.Equal(t, EventUpserted, (<-w.Events()).Type)
+
+	_, err = svc.Rename(ctx, "NEW", "TAKEN")
+	assert.ErrorIs(t, err, port.ErrExists)
+	_, err = svc.Rename(ctx, "MISSING", "OTHER")
+	assert.ErrorIs(t, err, port.ErrNotFound)
+	_, err = svc.Rename(ctx, "NEW", "")
+	assert.ErrorIs(t, err, port.ErrValidation)
+	assert.Equal(t, 2, svc.Count(ctx), "failed renames change nothing")
+}
+
+func TestService_WatchLargeUpload(t *testing.T) {
+	svc := New(portRepository.New(inmem.NewSharded[*portRepository.Port](inmem.ShardedOptions{})), nil)
+	ctx := context.Background()
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := svc.Watch(watchCtx)
+
+	ports := make([]*port.Port, 4*watchBuffer)
+	for i := range ports {
+		var err error
+		ports[i], err = port.New(fmt.Sprintf("P%04d", i), "name", "", "city", "country", nil, nil, nil, "", "", nil)
+		require.NoError(t, err)
+	}
+	require.NoError(t, svc.UploadBatch(ctx, ports))
+
+	e, ok := <-w.Events()
+	require.True(t, ok, "the watch ended: %v", w.Err())
+	assert.Equal(t, EventUpserted, e.Type)
+	assert.Len(t, e.Ports, len(ports))
+}
@@ -0,0 +1,98 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+)
+
+// watchBuffer is how many events a watcher may lag behind before it is
+// dropped, so that a slow consumer can't hold up writers. A batch upload is
+// one event however many ports it stores.
+const watchBuffer = 256
+
+// ErrWatcherBehind ends a watch whose consumer didn't keep up.
+var ErrWatcherBehind = errors.New("watcher fell behind")
+
+type EventType int
+
+const (
+	EventUpserted EventType = iota + 1
+	EventDeleted
+)
+
+// Event is a change to the ports of one write. Ports are the ports after an
+// upsert, or as they were before a delete.
+type Event struct {
+	Type  EventType
+	Ports []*port.Port
+}
+
+// Watcher receives the changes made through the service after it was
+// created.
+type Watcher struct {
+	events chan Event
+	err    error
+}
+
+// Events is closed when the watch ends; Err then tells why.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Err is the context error or ErrWatcherBehind once Events is closed.
+func (w *Watcher) Err() error {
+	return w.err
+}
+
+type broker struct {
+	mu       sync.Mutex
+	watchers map[*Watcher]struct{}
+}
+
+// Watch streams port changes until ctx is done.
+func (p *Service) Watch(ctx context.Context) *Watcher {
+	w := &Watcher{events: make(chan Event, watchBuffer)}
+
+	p.broker.mu.Lock()
+	if p.broker.watchers == nil {
+		p.broker.watchers = make(map[*Watcher]struct{})
+	}
+	p.broker.watchers[w] = struct{}{}
+	p.broker.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		p.broker.remove(w, ctx.Err())
+	}()
+	return w
+}
+
+func (b *broker) publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for w := range b.watchers {
+		select {
+		case w.events <- e:
+		default:
+			b.removeLocked(w, ErrWatcherBehind)
+		}
+	}
+}
+
+func (b *broker) remove(w *Watcher, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeLocked(w, err)
+}
+
+func (b *broker) removeLocked(w *Watcher, err error) {
+	if _, ok := b.watchers[w]; !ok {
+		return
+	}
+	delete(b.watchers, w)
+	w.err = err
+	close(w.events)
+}
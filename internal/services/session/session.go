@@ -0,0 +1,55 @@
+package session
+
+import (
+	"context"
+	"slices"
+
+	"github.com/axmz/go-port-service/internal/domain/session"
+)
+
+type SessionRepository interface {
+	ListByUser(ctx context.Context, userID string) ([]*session.Session, error)
+	DeleteByUser(ctx context.Context, userID string) (int, error)
+	DeleteCtx(ctx context.Context, token string) error
+}
+
+type Service struct {
+	repo SessionRepository
+}
+
+func New(r SessionRepository) *Service {
+	return &Service{
+		repo: r,
+	}
+}
+
+// List returns the user's active sessions, most recently used first.
+func (s *Service) List(ctx context.Context, userID string) ([]*session.Session, error) {
+	sessions, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(sessions, func(a, b *session.Session) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return sessions, nil
+}
+
+// Revoke ends a single session of the user identified by its public ID.
+func (s *Service) Revoke(ctx context.Context, userID, id string) error {
+	sessions, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, ss := range sessions {
+		if ss.ID() == id {
+			return s.repo.DeleteCtx(ctx, ss.Token)
+		}
+	}
+	return session.ErrNotFound
+}
+
+// RevokeAll ends every session of the user.
+func (s *Service) RevokeAll(ctx context.Context, userID string) (int, error) {
+	return s.repo.DeleteByUser(ctx, userID)
+}
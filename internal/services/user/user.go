@@ -2,24 +2,147 @@ package user
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 
-	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/internal/domain/user"
+	"github.com/axmz/go-port-service/internal/logger"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
 )
 
 type UserRepository interface {
-	Get(ctx context.Context, id string) (*port.Port, error)
+	Get(ctx context.Context, id string) (*user.User, error)
+	List(ctx context.Context, offset, limit int) ([]*user.User, int, error)
+	Put(ctx context.Context, u *user.User) (*user.User, error)
+	Delete(ctx context.Context, id string) (*user.User, error)
+}
+
+type SessionRepository interface {
+	DeleteByUser(ctx context.Context, userID string) (int, error)
+}
+
+type Page struct {
+	Users    []*user.User
+	Page     int
+	PageSize int
+	Total    int
 }
 
 type Service struct {
-	repo UserRepository
+	repo     UserRepository
+	sessions SessionRepository
 }
 
-func New(r UserRepository) *Service {
+func New(r UserRepository, s SessionRepository) *Service {
 	return &Service{
-		repo: r,
+		repo:     r,
+		sessions: s,
+	}
+}
+
+func (s *Service) Get(ctx context.Context, id string) (*user.User, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List returns the 1-based page of users. Out of range page sizes are clamped.
+func (s *Service) List(ctx context.Context, page, pageSize int) (*Page, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	pageSize = min(pageSize, MaxPageSize)
+
+	users, total, err := s.repo.List(ctx, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page{
+		Users:    users,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}, nil
+}
+
+func (s *Service) IsAdmin(ctx context.Context, id string) bool {
+	u, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return false
 	}
+	return u.IsAdmin() && !u.IsDisabled()
 }
 
-func (p *Service) Get(ctx context.Context, id string) (*port.Port, error) {
-	return p.repo.Get(ctx, id)
+func (s *Service) UpdateProfile(ctx context.Context, id, displayName, email string) (*user.User, error) {
+	return s.update(ctx, id, func(u *user.User) error {
+		return u.SetProfile(displayName, email)
+	})
+}
+
+func (s *Service) SetRole(ctx context.Context, id string, role user.Role) (*user.User, error) {
+	return s.update(ctx, id, func(u *user.User) error {
+		u.SetRole(role)
+		return nil
+	})
+}
+
+// Disable blocks the user from logging in and ends their sessions.
+func (s *Service) Disable(ctx context.Context, id string) (*user.User, error) {
+	u, err := s.update(ctx, id, func(u *user.User) error {
+		u.Disable()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.revokeSessions(ctx, id)
+	return u, nil
+}
+
+func (s *Service) Enable(ctx context.Context, id string) (*user.User, error) {
+	return s.update(ctx, id, func(u *user.User) error {
+		u.Enable()
+		return nil
+	})
+}
+
+// Delete removes the user together with their credentials, which are stored
+// on the user, and their sessions.
+func (s *Service) Delete(ctx context.Context, id string) (*user.User, error) {
+	u, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.revokeSessions(ctx, id)
+	return u, nil
+}
+
+func (s *Service) update(ctx context.Context, id string, fn func(u *user.User) error) (*user.User, error) {
+	stored, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	u := stored.Clone()
+	if err := fn(u); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.Put(ctx, u); err != nil {
+		return nil, fmt.Errorf("failed to save user: %w", err)
+	}
+	return u, nil
+}
+
+func (s *Service) revokeSessions(ctx context.Context, id string) {
+	n, err := s.sessions.DeleteByUser(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to revoke user sessions", slog.String("user_id", id), slog.Any("error", err))
+		return
+	}
+	logger.FromContext(ctx).Info("revoked user sessions", slog.String("user_id", id), slog.Int("count", n))
 }
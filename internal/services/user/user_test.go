@@ -0,0 +1,111 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axmz/go-port-service/internal/domain/user"
+	userRepository "github.com/axmz/go-port-service/internal/repository/user"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+type mockSessions struct {
+	revoked []string
+}
+
+func (m *mockSessions) DeleteByUser(ctx context.Context, userID string) (int, error) {
+	m.revoked = append(m.revoked, userID)
+	return 1, nil
+}
+
+func setup(t *testing.T, n int) (*Service, *mockSessions) {
+	ctx := context.Background()
+	repo := userRepository.New(inmem.NewSharded[*user.User](inmem.ShardedOptions{}))
+	for i := range n {
+		u, err := user.New(fmt.Sprintf("user%02d", i), "name", "display")
+		require.NoError(t, err)
+		_, err = repo.Put(ctx, u)
+		require.NoError(t, err)
+	}
+	sessions := &mockSessions{}
+	return New(repo, sessions), sessions
+}
+
+func TestList_Pagination(t *testing.T) {
+	svc, _ := setup(t, 5)
+	ctx := context.Background()
+
+	p, err := svc.List(ctx, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 5, p.Total)
+	assert.Len(t, p.Users, 2)
+
+	p, err = svc.List(ctx, 3, 2)
+	require.NoError(t, err)
+	assert.Len(t, p.Users, 1)
+
+	p, err = svc.List(ctx, 10, 2)
+	require.NoError(t, err)
+	assert.Empty(t, p.Users)
+
+	p, err = svc.List(ctx, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.Page)
+	assert.Equal(t, DefaultPageSize, p.PageSize)
+}
+
+func TestDelete_RevokesSessions(t *testing.T) {
+	svc, sessions := setup(t, 1)
+	ctx := context.Background()
+
+	_, err := svc.Delete(ctx, "user00")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user00"}, sessions.revoked)
+
+	_, err = svc.Get(ctx, "user00")
+	assert.ErrorIs(t, err, user.ErrNotFound)
+}
+
+func TestDisable_RevokesSessions(t *testing.T) {
+	svc, sessions := setup(t, 1)
+	ctx := context.Background()
+
+	u, err := svc.Disable(ctx, "user00")
+	require.NoError(t, err)
+	assert.True(t, u.IsDisabled())
+	assert.Equal(t, []string{"user00"}, sessions.revoked)
+}
+
+func TestUpdate_LeavesStoredUserAlone(t *testing.T) {
+	svc, _ := setup(t, 1)
+	ctx := context.Background()
+
+	before, err := svc.Get(ctx, "user00")
+	require.NoError(t, err)
+	_, err = svc.UpdateProfile(ctx, "user00", "Ada", "ada@example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "display", before.DisplayName, "readers keep the version they got")
+	after, err := svc.Get(ctx, "user00")
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", after.DisplayName)
+}
+
+func TestIsAdmin(t *testing.T) {
+	svc, _ := setup(t, 1)
+	ctx := context.Background()
+
+	assert.False(t, svc.IsAdmin(ctx, "user00"))
+
+	_, err := svc.SetRole(ctx, "user00", user.RoleAdmin)
+	require.NoError(t, err)
+	assert.True(t, svc.IsAdmin(ctx, "user00"))
+
+	_, err = svc.Disable(ctx, "user00")
+	require.NoError(t, err)
+	assert.False(t, svc.IsAdmin(ctx, "user00"))
+}
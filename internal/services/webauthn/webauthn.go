@@ -2,62 +2,121 @@ package webauthn
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/axmz/go-port-service/internal/config"
+	"github.com/axmz/go-port-service/internal/domain/security"
 	"github.com/axmz/go-port-service/internal/domain/user"
+	"github.com/axmz/go-port-service/internal/logger"
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
 )
 
+// ClonePolicy decides what happens to a login whose authenticator reported a
+// sign count that did not increase.
+type ClonePolicy string
+
+const (
+	// ClonePolicyReject fails the login.
+	ClonePolicyReject ClonePolicy = "reject"
+	// ClonePolicyFlag lets the login through and marks the credential as suspicious.
+	ClonePolicyFlag ClonePolicy = "flag"
+	// ClonePolicyReregister fails the login and removes the credential.
+	ClonePolicyReregister ClonePolicy = "reregister"
+)
+
 type UserRepository interface {
 	Get(ctx context.Context, id string) (*user.User, error)
+	Create(ctx context.Context, u *user.User) (*user.User, error)
 	Put(ctx context.Context, u *user.User) (*user.User, error)
 }
 
+type SecurityEventRepository interface {
+	Append(ctx context.Context, e *security.Event) error
+	List(ctx context.Context, userID string) ([]*security.Event, error)
+}
+
+type Metrics interface {
+	WebAuthnCeremony(ceremony string, failed bool)
+}
+
 type Service struct {
 	wa       *webauthn.WebAuthn
 	userRepo UserRepository
-}
+	events   SecurityEventRepository
+	metrics  Metrics
 
-func New(cfg *config.Config, userRepo UserRepository) *Service {
-	proto := cfg.HTTPServer.Protocol
-	host := cfg.HTTPServer.Host
-	port := cfg.HTTPServer.Port
-	origin := fmt.Sprintf("%s://%s", proto, host)
-	originWithPort := fmt.Sprintf("%s://%s%s", proto, host, port)
+	clonePolicy       ClonePolicy
+	maxFailedLogins   int
+	failedLoginWindow time.Duration
+	lockoutDuration   time.Duration
+	admins            []string
+	now               func() time.Time
+}
 
+func New(cfg *config.Config, userRepo UserRepository, events SecurityEventRepository, metrics Metrics) *Service {
 	wa, err := webauthn.New(&webauthn.Config{
 		RPDisplayName: "Port Service",
-		RPID:          host,
-		RPOrigins:     []string{origin, originWithPort},
+		RPID:          cfg.HTTPServer.Host,
+		RPOrigins:     rpOrigins(cfg),
 	})
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	policy := ClonePolicy(cfg.Auth.ClonePolicy)
+	switch policy {
+	case ClonePolicyReject, ClonePolicyFlag, ClonePolicyReregister:
+	default:
+		log.Fatalf("unknown clone policy: %q", cfg.Auth.ClonePolicy)
+	}
+
 	return &Service{
-		wa:       wa,
-		userRepo: userRepo,
+		wa:                wa,
+		userRepo:          userRepo,
+		events:            events,
+		metrics:           metrics,
+		clonePolicy:       policy,
+		maxFailedLogins:   cfg.Auth.MaxFailedLogins,
+		failedLoginWindow: cfg.Auth.FailedLoginWindow,
+		lockoutDuration:   cfg.Auth.LockoutDuration,
+		admins:            cfg.Auth.Admins,
+		now:               time.Now,
 	}
 }
 
-func (s *Service) BeginRegistration(ctx context.Context, id string) (
+// rpOrigins are the origins ceremonies may run on: the application's own and
+// every exact origin a cross-origin frontend is allowed to call the API from.
+func rpOrigins(cfg *config.Config) []string {
+	origins := cfg.Origins()
+	for _, o := range cfg.CORS.API.AllowedOrigins {
+		if !strings.Contains(o, "*") && !slices.Contains(origins, o) {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
+
+// BeginRegistration starts registering an authenticator for id. A new id
+// gets an account when the registration finishes. An existing account only
+// takes authenticators from its own logged-in user, currentID, and only while
+// that user may log in.
+func (s *Service) BeginRegistration(ctx context.Context, id, currentID string) (
 	creation *protocol.CredentialCreation, session *webauthn.SessionData, err error) {
-	var u *user.User
-	u, err = s.userRepo.Get(ctx, id) // Find or create the new user
+	defer func() { s.metrics.WebAuthnCeremony("register_begin", err != nil) }()
+
+	u, _, err := s.registrant(ctx, id, currentID)
 	if err != nil {
-		u, err = user.New(id, id, id)
-		if err != nil {
-			return nil, nil, err
-		}
-		_, err = s.userRepo.Put(ctx, u) // Save the new user
-		if err != nil {
-			return nil, nil, err
-		}
+		return nil, nil, err
 	}
 
 	creation, session, err = s.wa.BeginRegistration(u)
@@ -68,41 +127,86 @@ func (s *Service) BeginRegistration(ctx context.Context, id string) (
 	return
 }
 
+// FinishRegistration adds the authenticator to the user of the session,
+// creating the account if the registration began for a new id. created
+// tells whether it did; the checks of BeginRegistration are made again since
+// the account may have been created or locked meanwhile.
 func (s *Service) FinishRegistration(
 	ctx context.Context,
 	session webauthn.SessionData,
+	currentID string,
 	r *http.Request,
-) error {
-	id := string(session.UserID)
-	user, err := s.userRepo.Get(ctx, id)
+) (created bool, err error) {
+	defer func() { s.metrics.WebAuthnCeremony("register_finish", err != nil) }()
+
+	u, created, err := s.registrant(ctx, string(session.UserID), currentID)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	credential, err := s.wa.FinishRegistration(user, session, r)
+	credential, err := s.wa.FinishRegistration(u, session, r)
 	if err != nil {
-		return fmt.Errorf("failed to finish registration: %w", err)
+		return false, fmt.Errorf("failed to finish registration: %w", err)
 	}
 
-	user.AddCredential(credential)
-	_, err = s.userRepo.Put(ctx, user)
+	u.AddCredential(credential)
+	if created {
+		_, err = s.userRepo.Create(ctx, u)
+	} else {
+		_, err = s.userRepo.Put(ctx, u)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to save user credentials: %w", err)
+		return false, fmt.Errorf("failed to save user credentials: %w", err)
 	}
 
-	return nil
+	return created, nil
+}
+
+// registrant returns the user an authenticator is being registered for: a
+// new, unsaved one if id is free, or a copy of the stored one if it belongs
+// to currentID and may log in.
+func (s *Service) registrant(ctx context.Context, id, currentID string) (u *user.User, created bool, err error) {
+	stored, err := s.userRepo.Get(ctx, id)
+	switch {
+	case errors.Is(err, user.ErrNotFound):
+		u, err = user.New(id, id, id)
+		if err != nil {
+			return nil, false, err
+		}
+		u.Email = id
+		if slices.Contains(s.admins, id) {
+			u.SetRole(user.RoleAdmin)
+		}
+		return u, true, nil
+	case err != nil:
+		return nil, false, err
+	case id != currentID:
+		return nil, false, user.ErrExists
+	}
+
+	if err := checkCanLogin(stored, s.now()); err != nil {
+		return nil, false, err
+	}
+	// Stored users are shared with concurrent readers; change a copy.
+	return stored.Clone(), false, nil
 }
 
 func (s *Service) BeginLogin(
 	ctx context.Context,
 	id string,
 ) (creation *protocol.CredentialAssertion, session *webauthn.SessionData, err error) {
+	defer func() { s.metrics.WebAuthnCeremony("login_begin", err != nil) }()
+
 	var u *user.User
 	u, err = s.userRepo.Get(ctx, id)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if err := checkCanLogin(u, s.now()); err != nil {
+		return nil, nil, err
+	}
+
 	creation, session, err = s.wa.BeginLogin(u)
 	if err != nil {
 		return nil, nil, err
@@ -115,27 +219,151 @@ func (s *Service) FinishLogin(
 	ctx context.Context,
 	session webauthn.SessionData,
 	r *http.Request,
-) error {
-	user, err := s.userRepo.Get(ctx, string(session.UserID))
+) (err error) {
+	defer func() { s.metrics.WebAuthnCeremony("login_finish", err != nil) }()
+
+	stored, err := s.userRepo.Get(ctx, string(session.UserID))
 	if err != nil {
 		return fmt.Errorf("user not found: %w", err)
 	}
+	// Stored users are shared with concurrent readers; the failure counts,
+	// flags and sign counts below go to a copy that is put back.
+	u := stored.Clone()
+
+	if err := checkCanLogin(u, s.now()); err != nil {
+		return err
+	}
+
+	// Parse the assertion ourselves so the received sign count is available
+	// when the authenticator reports a clone warning.
+	parsed, err := protocol.ParseCredentialRequestResponse(r)
+	if err != nil {
+		s.failLogin(ctx, u, "", err.Error())
+		return fmt.Errorf("failed to finish login: %w", err)
+	}
 
-	credential, err := s.wa.FinishLogin(user, session, r)
+	credential, err := s.wa.ValidateLogin(u, session, parsed)
 	if err != nil {
-		return fmt.Errorf("failed to finish registration: %w", err)
+		s.failLogin(ctx, u, "", err.Error())
+		return fmt.Errorf("failed to finish login: %w", err)
 	}
 
 	if credential.Authenticator.CloneWarning {
-		// TODO: Handle clone warning
-		log.Println("Authenticator clone warning detected")
+		received := parsed.Response.AuthenticatorData.Counter
+		if err := s.handleCloneWarning(ctx, u, credential, received); err != nil {
+			return err
+		}
 	}
 
-	user.AddCredential(credential)
-	_, err = s.userRepo.Put(ctx, user)
+	u.ResetFailedLogins()
+	u.UpdateCredential(credential)
+	_, err = s.userRepo.Put(ctx, u)
 	if err != nil {
 		return fmt.Errorf("failed to save user credentials: %w", err)
 	}
 
 	return nil
 }
+
+func checkCanLogin(u *user.User, now time.Time) error {
+	switch u.Status(now) {
+	case user.StatusDisabled:
+		return user.ErrDisabled
+	case user.StatusLocked:
+		return user.ErrLocked
+	}
+	return nil
+}
+
+// handleCloneWarning records the warning and applies the configured policy.
+// A nil error means the login may proceed.
+func (s *Service) handleCloneWarning(ctx context.Context, u *user.User, credential *webauthn.Credential, received uint32) error {
+	credID := base64.RawURLEncoding.EncodeToString(credential.ID)
+
+	logger.FromContext(ctx).Warn("authenticator clone warning detected",
+		slog.String("user_id", string(u.ID)),
+		slog.String("credential_id", credID),
+		slog.String("policy", string(s.clonePolicy)),
+	)
+
+	s.recordEvent(ctx, &security.Event{
+		Type:              security.EventCloneWarning,
+		UserID:            string(u.ID),
+		CredentialID:      credID,
+		StoredSignCount:   credential.Authenticator.SignCount,
+		ReceivedSignCount: received,
+		Detail:            string(s.clonePolicy),
+	})
+
+	switch s.clonePolicy {
+	case ClonePolicyFlag:
+		u.FlagCredential(credential.ID)
+		return nil
+	case ClonePolicyReregister:
+		u.RemoveCredential(credential.ID)
+		s.failLogin(ctx, u, credID, user.ErrReregistrationRequired.Error())
+		return user.ErrReregistrationRequired
+	default:
+		s.failLogin(ctx, u, credID, user.ErrCloneDetected.Error())
+		return user.ErrCloneDetected
+	}
+}
+
+// failLogin counts a failed login towards the lockout threshold and persists
+// the user, which must be a copy of the stored one.
+func (s *Service) failLogin(ctx context.Context, u *user.User, credID, detail string) {
+	now := s.now()
+
+	s.recordEvent(ctx, &security.Event{
+		Type:         security.EventLoginFailed,
+		UserID:       string(u.ID),
+		CredentialID: credID,
+		Detail:       detail,
+	})
+
+	if u.RegisterFailedLogin(now, s.failedLoginWindow, s.maxFailedLogins, s.lockoutDuration) {
+		s.recordEvent(ctx, &security.Event{
+			Type:   security.EventAccountLocked,
+			UserID: string(u.ID),
+			Detail: fmt.Sprintf("locked until %s", u.LockedUntil().Format(time.RFC3339)),
+		})
+	}
+
+	if _, err := s.userRepo.Put(ctx, u); err != nil {
+		logger.FromContext(ctx).Error("failed to save user", slog.String("user_id", string(u.ID)), slog.Any("error", err))
+	}
+}
+
+// Unlock lifts a temporary lockout ahead of time.
+func (s *Service) Unlock(ctx context.Context, id string) error {
+	stored, err := s.userRepo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	u := stored.Clone()
+	u.Unlock()
+	if _, err := s.userRepo.Put(ctx, u); err != nil {
+		return err
+	}
+
+	s.recordEvent(ctx, &security.Event{
+		Type:   security.EventAccountUnlocked,
+		UserID: id,
+	})
+
+	return nil
+}
+
+func (s *Service) SecurityEvents(ctx context.Context, userID string) ([]*security.Event, error) {
+	return s.events.List(ctx, userID)
+}
+
+func (s *Service) recordEvent(ctx context.Context, e *security.Event) {
+	if e.Time.IsZero() {
+		e.Time = s.now()
+	}
+	if err := s.events.Append(ctx, e); err != nil {
+		logger.FromContext(ctx).Error("failed to record security event", slog.String("type", string(e.Type)), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,353 @@
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axmz/go-port-service/internal/config"
+	"github.com/axmz/go-port-service/internal/domain/security"
+	"github.com/axmz/go-port-service/internal/domain/user"
+	securityRepository "github.com/axmz/go-port-service/internal/repository/security"
+	userRepository "github.com/axmz/go-port-service/internal/repository/user"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+const userID = "ada@example.com"
+
+type nopMetrics struct{}
+
+func (nopMetrics) WebAuthnCeremony(string, bool) {}
+
+// authenticator is a software passkey holding one ES256 credential.
+type authenticator struct {
+	key  *ecdsa.PrivateKey
+	cred webauthn.Credential
+}
+
+func newAuthenticator(t *testing.T, signCount uint32) *authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pub, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: key.X.FillBytes(make([]byte, 32)),
+		YCoord: key.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return &authenticator{key: key, cred: webauthn.Credential{
+		ID:            id,
+		PublicKey:     pub,
+		Authenticator: webauthn.Authenticator{SignCount: signCount},
+	}}
+}
+
+// assert answers the login challenge of session, reporting counter as the
+// sign count.
+func (a *authenticator) assert(t *testing.T, session *webauthn.SessionData, counter uint32) *http.Request {
+	rpIDHash := sha256.Sum256([]byte("localhost"))
+	authData := append(rpIDHash[:], byte(protocol.FlagUserPresent))
+	authData = binary.BigEndian.AppendUint32(authData, counter)
+
+	clientData, err := json.Marshal(map[string]string{
+		"type":      string(protocol.AssertCeremony),
+		"challenge": session.Challenge,
+		"origin":    "http://localhost",
+	})
+	require.NoError(t, err)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(bytes.Clone(authData), clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	body, err := json.Marshal(map[string]any{
+		"id":    b64(a.cred.ID),
+		"rawId": b64(a.cred.ID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"authenticatorData": b64(authData),
+			"clientDataJSON":    b64(clientData),
+			"signature":         b64(sig),
+			"userHandle":        b64(session.UserID),
+		},
+	})
+	require.NoError(t, err)
+	return httptest.NewRequest(http.MethodPost, "/api/webauth/login/finish", bytes.NewReader(body))
+}
+
+// attest answers the registration challenge of session with a "none"
+// attestation of the authenticator's credential.
+func (a *authenticator) attest(t *testing.T, session *webauthn.SessionData) *http.Request {
+	rpIDHash := sha256.Sum256([]byte("localhost"))
+	authData := append(rpIDHash[:], byte(protocol.FlagUserPresent|protocol.FlagAttestedCredentialData))
+	authData = binary.BigEndian.AppendUint32(authData, a.cred.Authenticator.SignCount)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.cred.ID)))
+	authData = append(authData, a.cred.ID...)
+	authData = append(authData, a.cred.PublicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	require.NoError(t, err)
+	clientData, err := json.Marshal(map[string]string{
+		"type":      string(protocol.CreateCeremony),
+		"challenge": session.Challenge,
+		"origin":    "http://localhost",
+	})
+	require.NoError(t, err)
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	body, err := json.Marshal(map[string]any{
+		"id":    b64(a.cred.ID),
+		"rawId": b64(a.cred.ID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"attestationObject": b64(attestation),
+			"clientDataJSON":    b64(clientData),
+		},
+	})
+	require.NoError(t, err)
+	return httptest.NewRequest(http.MethodPost, "/api/webauth/register/finish", bytes.NewReader(body))
+}
+
+type fixture struct {
+	svc    *Service
+	users  *userRepository.Repository
+	events *securityRepository.Repository
+	auth   *authenticator
+}
+
+func setup(t *testing.T, configure func(cfg *config.Config)) *fixture {
+	cfg := config.Default()
+	configure(cfg)
+
+	users := userRepository.New(inmem.NewSharded[*user.User](inmem.ShardedOptions{}))
+	events := securityRepository.New(inmem.New[*security.Event]())
+	svc := New(cfg, users, events, nopMetrics{})
+
+	u, err := user.New(userID, userID, userID)
+	require.NoError(t, err)
+	auth := newAuthenticator(t, 5)
+	u.AddCredential(&auth.cred)
+	_, err = users.Put(context.Background(), u)
+	require.NoError(t, err)
+
+	return &fixture{svc: svc, users: users, events: events, auth: auth}
+}
+
+// login runs a login ceremony in which the authenticator reports counter.
+func (f *fixture) login(t *testing.T, a *authenticator, counter uint32) error {
+	ctx := context.Background()
+	_, session, err := f.svc.BeginLogin(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return f.svc.FinishLogin(ctx, *session, a.assert(t, session, counter))
+}
+
+// register runs a registration ceremony for id by the logged-in user
+// currentID, which is empty when nobody is logged in.
+func (f *fixture) register(t *testing.T, a *authenticator, id, currentID string) (bool, error) {
+	ctx := context.Background()
+	_, session, err := f.svc.BeginRegistration(ctx, id, currentID)
+	if err != nil {
+		return false, err
+	}
+	return f.svc.FinishRegistration(ctx, *session, currentID, a.attest(t, session))
+}
+
+func (f *fixture) user(t *testing.T) *user.User {
+	u, err := f.users.Get(context.Background(), userID)
+	require.NoError(t, err)
+	return u
+}
+
+func (f *fixture) eventTypes(t *testing.T) []security.EventType {
+	events, err := f.events.List(context.Background(), userID)
+	require.NoError(t, err)
+	var types []security.EventType
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestFinishLogin(t *testing.T) {
+	f := setup(t, func(cfg *config.Config) {})
+
+	require.NoError(t, f.login(t, f.auth, 6))
+	creds := f.user(t).WebAuthnCredentials()
+	require.Len(t, creds, 1)
+	assert.Equal(t, uint32(6), creds[0].Authenticator.SignCount)
+	assert.Empty(t, f.eventTypes(t))
+}
+
+func TestFinishLogin_ClonePolicies(t *testing.T) {
+	for _, tt := range []struct {
+		policy      ClonePolicy
+		err         error
+		credentials int
+		suspicious  bool
+		events      []security.EventType
+	}{
+		{
+			policy:      ClonePolicyReject,
+			err:         user.ErrCloneDetected,
+			credentials: 1,
+			events:      []security.EventType{security.EventCloneWarning, security.EventLoginFailed},
+		},
+		{
+			policy:      ClonePolicyFlag,
+			credentials: 1,
+			suspicious:  true,
+			events:      []security.EventType{security.EventCloneWarning},
+		},
+		{
+			policy:      ClonePolicyReregister,
+			err:         user.ErrReregistrationRequired,
+			credentials: 0,
+			events:      []security.EventType{security.EventCloneWarning, security.EventLoginFailed},
+		},
+	} {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			f := setup(t, func(cfg *config.Config) { cfg.Auth.ClonePolicy = string(tt.policy) })
+
+			// The stored sign count is 5, so 5 again means a clone.
+			err := f.login(t, f.auth, 5)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			u := f.user(t)
+			assert.Len(t, u.WebAuthnCredentials(), tt.credentials)
+			assert.Equal(t, tt.suspicious, u.IsCredentialSuspicious(f.auth.cred.ID))
+			if tt.suspicious {
+				assert.Equal(t, [][]byte{f.auth.cred.ID}, u.SuspiciousCredentials())
+			}
+			assert.Equal(t, tt.events, f.eventTypes(t))
+		})
+	}
+}
+
+func TestFinishLogin_Lockout(t *testing.T) {
+	f := setup(t, func(cfg *config.Config) {
+		cfg.Auth.MaxFailedLogins = 2
+		cfg.Auth.FailedLoginWindow = time.Minute
+		cfg.Auth.LockoutDuration = time.Hour
+	})
+	now := time.Now()
+	f.svc.now = func() time.Time { return now }
+
+	// A different key signs, so the signature doesn't verify.
+	impostor := newAuthenticator(t, 0)
+	impostor.cred.ID = f.auth.cred.ID
+
+	assert.Error(t, f.login(t, impostor, 6))
+	assert.False(t, f.user(t).IsLocked(now))
+	assert.Error(t, f.login(t, impostor, 7))
+	assert.True(t, f.user(t).IsLocked(now))
+
+	assert.ErrorIs(t, f.login(t, f.auth, 8), user.ErrLocked, "even the right key is locked out")
+	assert.Equal(t, []security.EventType{
+		security.EventLoginFailed, security.EventLoginFailed, security.EventAccountLocked,
+	}, f.eventTypes(t))
+
+	require.NoError(t, f.svc.Unlock(context.Background(), userID))
+	assert.NoError(t, f.login(t, f.auth, 8))
+
+	now = now.Add(2 * time.Hour)
+	assert.Error(t, f.login(t, impostor, 9))
+	assert.False(t, f.user(t).IsLocked(now), "a successful login resets the count")
+}
+
+func TestRegistration(t *testing.T) {
+	t.Run("new account", func(t *testing.T) {
+		f := setup(t, func(cfg *config.Config) { cfg.Auth.Admins = []string{"root@example.com"} })
+		created, err := f.register(t, newAuthenticator(t, 0), "root@example.com", "")
+		require.NoError(t, err)
+		assert.True(t, created)
+
+		u, err := f.users.Get(context.Background(), "root@example.com")
+		require.NoError(t, err)
+		assert.Len(t, u.WebAuthnCredentials(), 1)
+		assert.Equal(t, user.RoleAdmin, u.Role)
+	})
+
+	t.Run("existing account of someone else", func(t *testing.T) {
+		f := setup(t, func(cfg *config.Config) {})
+		_, err := f.register(t, newAuthenticator(t, 0), userID, "")
+		assert.ErrorIs(t, err, user.ErrExists)
+		_, err = f.register(t, newAuthenticator(t, 0), userID, "eve@example.com")
+		assert.ErrorIs(t, err, user.ErrExists)
+		assert.Len(t, f.user(t).WebAuthnCredentials(), 1)
+	})
+
+	t.Run("account created during the ceremony", func(t *testing.T) {
+		f := setup(t, func(cfg *config.Config) {})
+		ctx := context.Background()
+		a := newAuthenticator(t, 0)
+		_, session, err := f.svc.BeginRegistration(ctx, "bob@example.com", "")
+		require.NoError(t, err)
+
+		first, err := user.New("bob@example.com", "bob", "bob")
+		require.NoError(t, err)
+		_, err = f.users.Create(ctx, first)
+		require.NoError(t, err)
+
+		_, err = f.svc.FinishRegistration(ctx, *session, "", a.attest(t, session))
+		assert.ErrorIs(t, err, user.ErrExists)
+		u, err := f.users.Get(ctx, "bob@example.com")
+		require.NoError(t, err)
+		assert.Empty(t, u.WebAuthnCredentials())
+	})
+
+	t.Run("own account", func(t *testing.T) {
+		f := setup(t, func(cfg *config.Config) {})
+		created, err := f.register(t, newAuthenticator(t, 0), userID, userID)
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Len(t, f.user(t).WebAuthnCredentials(), 2)
+	})
+
+	t.Run("own locked account", func(t *testing.T) {
+		f := setup(t, func(cfg *config.Config) {})
+		ctx := context.Background()
+		u := f.user(t).Clone()
+		u.RegisterFailedLogin(time.Now(), time.Minute, 1, time.Hour)
+		_, err := f.users.Put(ctx, u)
+		require.NoError(t, err)
+
+		_, err = f.register(t, newAuthenticator(t, 0), userID, userID)
+		assert.ErrorIs(t, err, user.ErrLocked)
+		assert.Len(t, f.user(t).WebAuthnCredentials(), 1)
+	})
+}
@@ -1,7 +1,11 @@
 package graph
 
 import (
+	"strings"
+	"time"
+
 	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/internal/domain/user"
 	"github.com/axmz/go-port-service/internal/transport/graphql/model"
 )
 
@@ -19,3 +23,17 @@ func convertToGraphQLPort(p *port.Port) *model.Port {
 		Unlocs:      p.Unlocs(),
 	}
 }
+
+func convertToGraphQLUser(u *user.User) *model.User {
+	return &model.User{
+		ID:          string(u.ID),
+		Name:        u.Name,
+		DisplayName: u.DisplayName,
+		Email:       u.Email,
+		Role:        model.Role(strings.ToUpper(string(u.Role))),
+		Status:      model.UserStatus(strings.ToUpper(string(u.Status(time.Now())))),
+		Credentials: int32(len(u.WebAuthnCredentials())),
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+	}
+}
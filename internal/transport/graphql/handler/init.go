@@ -6,21 +6,38 @@ import (
 	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/axmz/go-port-service/internal/services/port"
+	"github.com/axmz/go-port-service/internal/services/user"
 	graphql "github.com/axmz/go-port-service/internal/transport/graphql"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
 type GraphQLHandler = handler.Server
 
-func InitGql(portSvc *port.Service) *GraphQLHandler {
+// Options sizes the caches of the GraphQL server and names the operations
+// that are measured on their own.
+type Options struct {
+	// QueryCacheSize is how many parsed queries are kept.
+	QueryCacheSize int
+	// APQCacheSize is how many automatic persisted queries are kept.
+	APQCacheSize int
+	// MetricOperations are the operation names used as metrics labels. The
+	// other named operations are measured as "other".
+	MetricOperations []string
+}
+
+func InitGql(portSvc *port.Service, userSvc *user.Service, session graphql.SessionManager, metrics Metrics, opts Options) *GraphQLHandler {
 	gqlsrv := handler.New(graphql.NewExecutableSchema(graphql.Config{Resolvers: &graphql.Resolver{
 		PortService: portSvc,
+		UserService: userSvc,
+		Session:     session,
 	}}))
 	gqlsrv.AddTransport(transport.Options{})
 	gqlsrv.AddTransport(transport.GET{})
 	gqlsrv.AddTransport(transport.POST{})
-	gqlsrv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	gqlsrv.SetQueryCache(lru.New[*ast.QueryDocument](opts.QueryCacheSize))
 	gqlsrv.Use(extension.Introspection{})
-	gqlsrv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](100)})
+	gqlsrv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](opts.APQCacheSize)})
+	gqlsrv.Use(newMetricsExtension(metrics, opts.MetricOperations))
+	gqlsrv.Use(newTracingExtension())
 	return gqlsrv
 }
@@ -0,0 +1,69 @@
+package gqlhandler
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+type Metrics interface {
+	ObserveGraphQL(opType, name string, failed bool, d time.Duration)
+}
+
+// metricsExtension measures the latency of every GraphQL operation. The
+// operation name comes from the client, so only the known names are used as
+// labels.
+type metricsExtension struct {
+	metrics Metrics
+	known   map[string]bool
+}
+
+func newMetricsExtension(m Metrics, operations []string) metricsExtension {
+	known := make(map[string]bool, len(operations))
+	for _, name := range operations {
+		known[name] = true
+	}
+	return metricsExtension{metrics: m, known: known}
+}
+
+var (
+	_ graphql.HandlerExtension    = metricsExtension{}
+	_ graphql.ResponseInterceptor = metricsExtension{}
+)
+
+func (metricsExtension) ExtensionName() string {
+	return "Metrics"
+}
+
+func (metricsExtension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (e metricsExtension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	start := time.Now()
+	resp := next(ctx)
+
+	opType, name := "unknown", "anonymous"
+	if graphql.HasOperationContext(ctx) {
+		oc := graphql.GetOperationContext(ctx)
+		if oc.Operation != nil {
+			opType = string(oc.Operation.Operation)
+		}
+		name = e.label(oc.OperationName)
+	}
+
+	failed := resp == nil || len(resp.Errors) > 0
+	e.metrics.ObserveGraphQL(opType, name, failed, time.Since(start))
+	return resp
+}
+
+func (e metricsExtension) label(name string) string {
+	switch {
+	case name == "":
+		return "anonymous"
+	case e.known[name]:
+		return name
+	}
+	return "other"
+}
@@ -0,0 +1,42 @@
+package gqlhandler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+type recordedMetrics struct {
+	labels [][2]string
+}
+
+func (m *recordedMetrics) ObserveGraphQL(opType, name string, failed bool, d time.Duration) {
+	m.labels = append(m.labels, [2]string{opType, name})
+}
+
+func TestMetricsExtension_Labels(t *testing.T) {
+	m := &recordedMetrics{}
+	e := newMetricsExtension(m, []string{"Ports"})
+	next := func(ctx context.Context) *graphql.Response { return &graphql.Response{} }
+
+	for _, name := range []string{"Ports", "", "Ports1", "x-random-name"} {
+		ctx := graphql.WithOperationContext(context.Background(), &graphql.OperationContext{
+			OperationName: name,
+			Operation:     &ast.OperationDefinition{Operation: ast.Query},
+		})
+		e.InterceptResponse(ctx, next)
+	}
+	e.InterceptResponse(context.Background(), next)
+
+	assert.Equal(t, [][2]string{
+		{"query", "Ports"},
+		{"query", "anonymous"},
+		{"query", "other"},
+		{"query", "other"},
+		{"unknown", "anonymous"},
+	}, m.labels)
+}
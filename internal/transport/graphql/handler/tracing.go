@@ -0,0 +1,81 @@
+package gqlhandler
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/axmz/go-port-service/internal/transport/graphql"
+
+// tracingExtension starts a span for every operation and for every field
+// that is backed by a resolver. Trivial field reads are not traced.
+type tracingExtension struct {
+	tracer trace.Tracer
+}
+
+var (
+	_ graphql.HandlerExtension     = tracingExtension{}
+	_ graphql.OperationInterceptor = tracingExtension{}
+	_ graphql.FieldInterceptor     = tracingExtension{}
+)
+
+func newTracingExtension() tracingExtension {
+	return tracingExtension{tracer: otel.Tracer(tracerName)}
+}
+
+func (tracingExtension) ExtensionName() string {
+	return "Tracing"
+}
+
+func (tracingExtension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (e tracingExtension) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	name := "graphql"
+	if oc.OperationName != "" {
+		name += " " + oc.OperationName
+	}
+
+	ctx, span := e.tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("graphql.operation.name", oc.OperationName),
+	))
+	if oc.Operation != nil {
+		span.SetAttributes(attribute.String("graphql.operation.type", string(oc.Operation.Operation)))
+	}
+
+	handler := next(ctx)
+	return func(ctx context.Context) *graphql.Response {
+		resp := handler(ctx)
+		if resp == nil || len(resp.Errors) > 0 {
+			span.SetStatus(codes.Error, "graphql errors")
+		}
+		span.End()
+		return resp
+	}
+}
+
+func (e tracingExtension) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	ctx, span := e.tracer.Start(ctx, fc.Object+"."+fc.Field.Name, trace.WithAttributes(
+		attribute.String("graphql.field.path", fc.Path().String()),
+	))
+	defer span.End()
+
+	res, err := next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return res, err
+}
@@ -1,11 +1,22 @@
 package graph
 
-import "github.com/axmz/go-port-service/internal/services/port"
+import (
+	"context"
+
+	"github.com/axmz/go-port-service/internal/services/port"
+	"github.com/axmz/go-port-service/internal/services/user"
+)
 
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
+type SessionManager interface {
+	GetString(ctx context.Context, key string) string
+}
+
 type Resolver struct {
 	PortService *port.Service
+	UserService *user.Service
+	Session     SessionManager
 }
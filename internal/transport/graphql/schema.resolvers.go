@@ -7,6 +7,7 @@ package graph
 import (
 	"context"
 
+	"github.com/axmz/go-port-service/internal/domain/session"
 	"github.com/axmz/go-port-service/internal/transport/graphql/model"
 )
 
@@ -37,6 +38,19 @@ func (r *queryResolver) PortsCount(ctx context.Context) (int32, error) {
 	return int32(r.PortService.Count(ctx)), nil
 }
 
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	id := r.Session.GetString(ctx, session.UserIDKey)
+	if id == "" {
+		return nil, nil
+	}
+	u, err := r.UserService.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return convertToGraphQLUser(u), nil
+}
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
@@ -0,0 +1,613 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: portv1/port.proto
+
+package portv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PortEvent_Type int32
+
+const (
+	PortEvent_TYPE_UNSPECIFIED PortEvent_Type = 0
+	PortEvent_TYPE_UPSERTED    PortEvent_Type = 1
+	PortEvent_TYPE_DELETED     PortEvent_Type = 2
+)
+
+// Enum value maps for PortEvent_Type.
+var (
+	PortEvent_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "TYPE_UPSERTED",
+		2: "TYPE_DELETED",
+	}
+	PortEvent_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"TYPE_UPSERTED":    1,
+		"TYPE_DELETED":     2,
+	}
+)
+
+func (x PortEvent_Type) Enum() *PortEvent_Type {
+	p := new(PortEvent_Type)
+	*p = x
+	return p
+}
+
+func (x PortEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PortEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_portv1_port_proto_enumTypes[0].Descriptor()
+}
+
+func (PortEvent_Type) Type() protoreflect.EnumType {
+	return &file_portv1_port_proto_enumTypes[0]
+}
+
+func (x PortEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PortEvent_Type.Descriptor instead.
+func (PortEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_portv1_port_proto_rawDescGZIP(), []int{6, 0}
+}
+
+type Port struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name    string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Code    string                 `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	City    string                 `protobuf:"bytes,4,opt,name=city,proto3" json:"city,omitempty"`
+	Country string                 `protobuf:"bytes,5,opt,name=country,proto3" json:"country,omitempty"`
+	Alias   []string               `protobuf:"bytes,6,rep,name=alias,proto3" json:"alias,omitempty"`
+	Regions []string               `protobuf:"bytes,7,rep,name=regions,proto3" json:"regions,omitempty"`
+	// Longitude and latitude.
+	Coordinates   []float64 `protobuf:"fixed64,8,rep,packed,name=coordinates,proto3" json:"coordinates,omitempty"`
+	Province      string    `protobuf:"bytes,9,opt,name=province,proto3" json:"province,omitempty"`
+	Timezone      string    `protobuf:"bytes,10,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	Unlocs        []string  `protobuf:"bytes,11,rep,name=unlocs,proto3" json:"unlocs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Port) Reset() {
+	*x = Port{}
+	mi := &file_portv1_port_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Port) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Port) ProtoMessage() {}
+
+func (x *Port) ProtoReflect() protoreflect.Message {
+	mi := &file_portv1_port_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Port.ProtoReflect.Descriptor instead.
+func (*Port) Descriptor() ([]byte, []int) {
+	return file_portv1_port_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Port) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Port) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Port) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Port) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *Port) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *Port) GetAlias() []string {
+	if x != nil {
+		return x.Alias
+	}
+	return nil
+}
+
+func (x *Port) GetRegions() []string {
+	if x != nil {
+		return x.Regions
+	}
+	return nil
+}
+
+func (x *Port) GetCoordinates() []float64 {
+	if x != nil {
+		return x.Coordinates
+	}
+	return nil
+}
+
+func (x *Port) GetProvince() string {
+	if x != nil {
+		return x.Province
+	}
+	return ""
+}
+
+func (x *Port) GetTimezone() string {
+	if x != nil {
+		return x.Timezone
+	}
+	return ""
+}
+
+func (x *Port) GetUnlocs() []string {
+	if x != nil {
+		return x.Unlocs
+	}
+	return nil
+}
+
+type GetPortRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPortRequest) Reset() {
+	*x = GetPortRequest{}
+	mi := &file_portv1_port_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPortRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPortRequest) ProtoMessage() {}
+
+func (x *GetPortRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portv1_port_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPortRequest.ProtoReflect.Descriptor instead.
+func (*GetPortRequest) Descriptor() ([]byte, []int) {
+	return file_portv1_port_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetPortRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// Empty filters match every port. Text filters are case-insensitive.
+type ListPortsRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Country string                 `protobuf:"bytes,1,opt,name=country,proto3" json:"country,omitempty"`
+	City    string                 `protobuf:"bytes,2,opt,name=city,proto3" json:"city,omitempty"`
+	// name_contains matches ports whose name contains it.
+	NameContains string `protobuf:"bytes,3,opt,name=name_contains,json=nameContains,proto3" json:"name_contains,omitempty"`
+	// limit caps the number of ports streamed; 0 means no limit.
+	Limit         int32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPortsRequest) Reset() {
+	*x = ListPortsRequest{}
+	mi := &file_portv1_port_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPortsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPortsRequest) ProtoMessage() {}
+
+func (x *ListPortsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portv1_port_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPortsRequest.ProtoReflect.Descriptor instead.
+func (*ListPortsRequest) Descriptor() ([]byte, []int) {
+	return file_portv1_port_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListPortsRequest) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *ListPortsRequest) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *ListPortsRequest) GetNameContains() string {
+	if x != nil {
+		return x.NameContains
+	}
+	return ""
+}
+
+func (x *ListPortsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type UpsertPortsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Upserted      int32                  `protobuf:"varint,1,opt,name=upserted,proto3" json:"upserted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpsertPortsResponse) Reset() {
+	*x = UpsertPortsResponse{}
+	mi := &file_portv1_port_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpsertPortsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpsertPortsResponse) ProtoMessage() {}
+
+func (x *UpsertPortsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_portv1_port_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpsertPortsResponse.ProtoReflect.Descriptor instead.
+func (*UpsertPortsResponse) Descriptor() ([]byte, []int) {
+	return file_portv1_port_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UpsertPortsResponse) GetUpserted() int32 {
+	if x != nil {
+		return x.Upserted
+	}
+	return 0
+}
+
+type DeletePortRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePortRequest) Reset() {
+	*x = DeletePortRequest{}
+	mi := &file_portv1_port_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePortRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePortRequest) ProtoMessage() {}
+
+func (x *DeletePortRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portv1_port_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePortRequest.ProtoReflect.Descriptor instead.
+func (*DeletePortRequest) Descriptor() ([]byte, []int) {
+	return file_portv1_port_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DeletePortRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type WatchPortsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ids restricts the events to these ports; empty means every port.
+	Ids           []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchPortsRequest) Reset() {
+	*x = WatchPortsRequest{}
+	mi := &file_portv1_port_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPortsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPortsRequest) ProtoMessage() {}
+
+func (x *WatchPortsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portv1_port_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPortsRequest.ProtoReflect.Descriptor instead.
+func (*WatchPortsRequest) Descriptor() ([]byte, []int) {
+	return file_portv1_port_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WatchPortsRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type PortEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  PortEvent_Type         `protobuf:"varint,1,opt,name=type,proto3,enum=port.v1.PortEvent_Type" json:"type,omitempty"`
+	// port is the port after an upsert, or as it was before a delete.
+	Port          *Port `protobuf:"bytes,2,opt,name=port,proto3" json:"port,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PortEvent) Reset() {
+	*x = PortEvent{}
+	mi := &file_portv1_port_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortEvent) ProtoMessage() {}
+
+func (x *PortEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_portv1_port_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortEvent.ProtoReflect.Descriptor instead.
+func (*PortEvent) Descriptor() ([]byte, []int) {
+	return file_portv1_port_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PortEvent) GetType() PortEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return PortEvent_TYPE_UNSPECIFIED
+}
+
+func (x *PortEvent) GetPort() *Port {
+	if x != nil {
+		return x.Port
+	}
+	return nil
+}
+
+var File_portv1_port_proto protoreflect.FileDescriptor
+
+const file_portv1_port_proto_rawDesc = "" +
+	"\n" +
+	"\x11portv1/port.proto\x12\aport.v1\"\x8e\x02\n" +
+	"\x04Port\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\x12\x12\n" +
+	"\x04city\x18\x04 \x01(\tR\x04city\x12\x18\n" +
+	"\acountry\x18\x05 \x01(\tR\acountry\x12\x14\n" +
+	"\x05alias\x18\x06 \x03(\tR\x05alias\x12\x18\n" +
+	"\aregions\x18\a \x03(\tR\aregions\x12 \n" +
+	"\vcoordinates\x18\b \x03(\x01R\vcoordinates\x12\x1a\n" +
+	"\bprovince\x18\t \x01(\tR\bprovince\x12\x1a\n" +
+	"\btimezone\x18\n" +
+	" \x01(\tR\btimezone\x12\x16\n" +
+	"\x06unlocs\x18\v \x03(\tR\x06unlocs\" \n" +
+	"\x0eGetPortRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"{\n" +
+	"\x10ListPortsRequest\x12\x18\n" +
+	"\acountry\x18\x01 \x01(\tR\acountry\x12\x12\n" +
+	"\x04city\x18\x02 \x01(\tR\x04city\x12#\n" +
+	"\rname_contains\x18\x03 \x01(\tR\fnameContains\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"1\n" +
+	"\x13UpsertPortsResponse\x12\x1a\n" +
+	"\bupserted\x18\x01 \x01(\x05R\bupserted\"#\n" +
+	"\x11DeletePortRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"%\n" +
+	"\x11WatchPortsRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\"\x9e\x01\n" +
+	"\tPortEvent\x12+\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x17.port.v1.PortEvent.TypeR\x04type\x12!\n" +
+	"\x04port\x18\x02 \x01(\v2\r.port.v1.PortR\x04port\"A\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rTYPE_UPSERTED\x10\x01\x12\x10\n" +
+	"\fTYPE_DELETED\x10\x022\xb0\x02\n" +
+	"\vPortService\x121\n" +
+	"\aGetPort\x12\x17.port.v1.GetPortRequest\x1a\r.port.v1.Port\x127\n" +
+	"\tListPorts\x12\x19.port.v1.ListPortsRequest\x1a\r.port.v1.Port0\x01\x12<\n" +
+	"\vUpsertPorts\x12\r.port.v1.Port\x1a\x1c.port.v1.UpsertPortsResponse(\x01\x127\n" +
+	"\n" +
+	"DeletePort\x12\x1a.port.v1.DeletePortRequest\x1a\r.port.v1.Port\x12>\n" +
+	"\n" +
+	"WatchPorts\x12\x1a.port.v1.WatchPortsRequest\x1a\x12.port.v1.PortEvent0\x01B@Z>github.com/axmz/go-port-service/internal/transport/grpc/portv1b\x06proto3"
+
+var (
+	file_portv1_port_proto_rawDescOnce sync.Once
+	file_portv1_port_proto_rawDescData []byte
+)
+
+func file_portv1_port_proto_rawDescGZIP() []byte {
+	file_portv1_port_proto_rawDescOnce.Do(func() {
+		file_portv1_port_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_portv1_port_proto_rawDesc), len(file_portv1_port_proto_rawDesc)))
+	})
+	return file_portv1_port_proto_rawDescData
+}
+
+var file_portv1_port_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_portv1_port_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_portv1_port_proto_goTypes = []any{
+	(PortEvent_Type)(0),         // 0: port.v1.PortEvent.Type
+	(*Port)(nil),                // 1: port.v1.Port
+	(*GetPortRequest)(nil),      // 2: port.v1.GetPortRequest
+	(*ListPortsRequest)(nil),    // 3: port.v1.ListPortsRequest
+	(*UpsertPortsResponse)(nil), // 4: port.v1.UpsertPortsResponse
+	(*DeletePortRequest)(nil),   // 5: port.v1.DeletePortRequest
+	(*WatchPortsRequest)(nil),   // 6: port.v1.WatchPortsRequest
+	(*PortEvent)(nil),           // 7: port.v1.PortEvent
+}
+var file_portv1_port_proto_depIdxs = []int32{
+	0, // 0: port.v1.PortEvent.type:type_name -> port.v1.PortEvent.Type
+	1, // 1: port.v1.PortEvent.port:type_name -> port.v1.Port
+	2, // 2: port.v1.PortService.GetPort:input_type -> port.v1.GetPortRequest
+	3, // 3: port.v1.PortService.ListPorts:input_type -> port.v1.ListPortsRequest
+	1, // 4: port.v1.PortService.UpsertPorts:input_type -> port.v1.Port
+	5, // 5: port.v1.PortService.DeletePort:input_type -> port.v1.DeletePortRequest
+	6, // 6: port.v1.PortService.WatchPorts:input_type -> port.v1.WatchPortsRequest
+	1, // 7: port.v1.PortService.GetPort:output_type -> port.v1.Port
+	1, // 8: port.v1.PortService.ListPorts:output_type -> port.v1.Port
+	4, // 9: port.v1.PortService.UpsertPorts:output_type -> port.v1.UpsertPortsResponse
+	1, // 10: port.v1.PortService.DeletePort:output_type -> port.v1.Port
+	7, // 11: port.v1.PortService.WatchPorts:output_type -> port.v1.PortEvent
+	7, // [7:12] is the sub-list for method output_type
+	2, // [2:7] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_portv1_port_proto_init() }
+func file_portv1_port_proto_init() {
+	if File_portv1_port_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_portv1_port_proto_rawDesc), len(file_portv1_port_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_portv1_port_proto_goTypes,
+		DependencyIndexes: file_portv1_port_proto_depIdxs,
+		EnumInfos:         file_portv1_port_proto_enumTypes,
+		MessageInfos:      file_portv1_port_proto_msgTypes,
+	}.Build()
+	File_portv1_port_proto = out.File
+	file_portv1_port_proto_goTypes = nil
+	file_portv1_port_proto_depIdxs = nil
+}
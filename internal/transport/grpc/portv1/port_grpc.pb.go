@@ -0,0 +1,293 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: portv1/port.proto
+
+package portv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PortService_GetPort_FullMethodName     = "/port.v1.PortService/GetPort"
+	PortService_ListPorts_FullMethodName   = "/port.v1.PortService/ListPorts"
+	PortService_UpsertPorts_FullMethodName = "/port.v1.PortService/UpsertPorts"
+	PortService_DeletePort_FullMethodName  = "/port.v1.PortService/DeletePort"
+	PortService_WatchPorts_FullMethodName  = "/port.v1.PortService/WatchPorts"
+)
+
+// PortServiceClient is the client API for PortService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PortService stores maritime ports.
+type PortServiceClient interface {
+	// GetPort returns a port by ID, NOT_FOUND if there is none.
+	GetPort(ctx context.Context, in *GetPortRequest, opts ...grpc.CallOption) (*Port, error)
+	// ListPorts streams the ports matching the filters, ordered by ID.
+	ListPorts(ctx context.Context, in *ListPortsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Port], error)
+	// UpsertPorts stores every streamed port, replacing ports with the same
+	// ID. The first invalid port fails the call with INVALID_ARGUMENT; ports
+	// received before it are kept.
+	UpsertPorts(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Port, UpsertPortsResponse], error)
+	// DeletePort removes a port and returns it, NOT_FOUND if there is none.
+	DeletePort(ctx context.Context, in *DeletePortRequest, opts ...grpc.CallOption) (*Port, error)
+	// WatchPorts streams changes to ports as they happen until the client
+	// cancels. A watcher that falls behind is ended with RESOURCE_EXHAUSTED.
+	WatchPorts(ctx context.Context, in *WatchPortsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PortEvent], error)
+}
+
+type portServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPortServiceClient(cc grpc.ClientConnInterface) PortServiceClient {
+	return &portServiceClient{cc}
+}
+
+func (c *portServiceClient) GetPort(ctx context.Context, in *GetPortRequest, opts ...grpc.CallOption) (*Port, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Port)
+	err := c.cc.Invoke(ctx, PortService_GetPort_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portServiceClient) ListPorts(ctx context.Context, in *ListPortsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Port], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PortService_ServiceDesc.Streams[0], PortService_ListPorts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListPortsRequest, Port]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PortService_ListPortsClient = grpc.ServerStreamingClient[Port]
+
+func (c *portServiceClient) UpsertPorts(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Port, UpsertPortsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PortService_ServiceDesc.Streams[1], PortService_UpsertPorts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Port, UpsertPortsResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PortService_UpsertPortsClient = grpc.ClientStreamingClient[Port, UpsertPortsResponse]
+
+func (c *portServiceClient) DeletePort(ctx context.Context, in *DeletePortRequest, opts ...grpc.CallOption) (*Port, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Port)
+	err := c.cc.Invoke(ctx, PortService_DeletePort_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portServiceClient) WatchPorts(ctx context.Context, in *WatchPortsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PortEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PortService_ServiceDesc.Streams[2], PortService_WatchPorts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchPortsRequest, PortEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PortService_WatchPortsClient = grpc.ServerStreamingClient[PortEvent]
+
+// PortServiceServer is the server API for PortService service.
+// All implementations must embed UnimplementedPortServiceServer
+// for forward compatibility.
+//
+// PortService stores maritime ports.
+type PortServiceServer interface {
+	// GetPort returns a port by ID, NOT_FOUND if there is none.
+	GetPort(context.Context, *GetPortRequest) (*Port, error)
+	// ListPorts streams the ports matching the filters, ordered by ID.
+	ListPorts(*ListPortsRequest, grpc.ServerStreamingServer[Port]) error
+	// UpsertPorts stores every streamed port, replacing ports with the same
+	// ID. The first invalid port fails the call with INVALID_ARGUMENT; ports
+	// received before it are kept.
+	UpsertPorts(grpc.ClientStreamingServer[Port, UpsertPortsResponse]) error
+	// DeletePort removes a port and returns it, NOT_FOUND if there is none.
+	DeletePort(context.Context, *DeletePortRequest) (*Port, error)
+	// WatchPorts streams changes to ports as they happen until the client
+	// cancels. A watcher that falls behind is ended with RESOURCE_EXHAUSTED.
+	WatchPorts(*WatchPortsRequest, grpc.ServerStreamingServer[PortEvent]) error
+	mustEmbedUnimplementedPortServiceServer()
+}
+
+// UnimplementedPortServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPortServiceServer struct{}
+
+func (UnimplementedPortServiceServer) GetPort(context.Context, *GetPortRequest) (*Port, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPort not implemented")
+}
+func (UnimplementedPortServiceServer) ListPorts(*ListPortsRequest, grpc.ServerStreamingServer[Port]) error {
+	return status.Errorf(codes.Unimplemented, "method ListPorts not implemented")
+}
+func (UnimplementedPortServiceServer) UpsertPorts(grpc.ClientStreamingServer[Port, UpsertPortsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method UpsertPorts not implemented")
+}
+func (UnimplementedPortServiceServer) DeletePort(context.Context, *DeletePortRequest) (*Port, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePort not implemented")
+}
+func (UnimplementedPortServiceServer) WatchPorts(*WatchPortsRequest, grpc.ServerStreamingServer[PortEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPorts not implemented")
+}
+func (UnimplementedPortServiceServer) mustEmbedUnimplementedPortServiceServer() {}
+func (UnimplementedPortServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafePortServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PortServiceServer will
+// result in compilation errors.
+type UnsafePortServiceServer interface {
+	mustEmbedUnimplementedPortServiceServer()
+}
+
+func RegisterPortServiceServer(s grpc.ServiceRegistrar, srv PortServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPortServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PortService_ServiceDesc, srv)
+}
+
+func _PortService_GetPort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPortRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortServiceServer).GetPort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortService_GetPort_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortServiceServer).GetPort(ctx, req.(*GetPortRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortService_ListPorts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListPortsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PortServiceServer).ListPorts(m, &grpc.GenericServerStream[ListPortsRequest, Port]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PortService_ListPortsServer = grpc.ServerStreamingServer[Port]
+
+func _PortService_UpsertPorts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PortServiceServer).UpsertPorts(&grpc.GenericServerStream[Port, UpsertPortsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PortService_UpsertPortsServer = grpc.ClientStreamingServer[Port, UpsertPortsResponse]
+
+func _PortService_DeletePort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePortRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortServiceServer).DeletePort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortService_DeletePort_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortServiceServer).DeletePort(ctx, req.(*DeletePortRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortService_WatchPorts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPortsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PortServiceServer).WatchPorts(m, &grpc.GenericServerStream[WatchPortsRequest, PortEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PortService_WatchPortsServer = grpc.ServerStreamingServer[PortEvent]
+
+// PortService_ServiceDesc is the grpc.ServiceDesc for PortService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PortService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "port.v1.PortService",
+	HandlerType: (*PortServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPort",
+			Handler:    _PortService_GetPort_Handler,
+		},
+		{
+			MethodName: "DeletePort",
+			Handler:    _PortService_DeletePort_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListPorts",
+			Handler:       _PortService_ListPorts_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "UpsertPorts",
+			Handler:       _PortService_UpsertPorts_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchPorts",
+			Handler:       _PortService_WatchPorts_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "portv1/port.proto",
+}
@@ -0,0 +1,309 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/axmz/go-port-service/internal/domain/audit"
+	"github.com/axmz/go-port-service/internal/logger"
+	auditService "github.com/axmz/go-port-service/internal/services/audit"
+	"github.com/axmz/go-port-service/internal/transport/grpc/portv1"
+	"github.com/axmz/go-port-service/pkg/ratelimit"
+)
+
+func recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+	defer recoverer(ctx, info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+func recoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverer(ss.Context(), info.FullMethod, &err)
+	return handler(srv, ss)
+}
+
+func recoverer(ctx context.Context, method string, err *error) {
+	if rec := recover(); rec != nil {
+		logger.FromContext(ctx).Error("panic recovered",
+			slog.Any("error", rec),
+			slog.String("stack", string(debug.Stack())),
+			slog.String("method", method),
+		)
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
+
+// logUnary puts a logger for the call into the context and logs the outcome.
+func logUnary(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		l := log.With(slog.String("rpc", info.FullMethod))
+		res, err := handler(logger.WithContext(ctx, l), req)
+		logCall(ctx, l, err, time.Since(start))
+		return res, err
+	}
+}
+
+func logStream(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		l := log.With(slog.String("rpc", info.FullMethod))
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: logger.WithContext(ss.Context(), l)})
+		logCall(ss.Context(), l, err, time.Since(start))
+		return err
+	}
+}
+
+func logCall(ctx context.Context, l *slog.Logger, err error, d time.Duration) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled, codes.NotFound, codes.InvalidArgument, codes.AlreadyExists:
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	l.LogAttrs(ctx, level, "completed rpc", slog.String("code", code.String()), slog.Duration("latency", d))
+}
+
+// contextStream replaces the context of the stream, e.g. to carry the
+// call's logger.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// callFilter checks a call before its handler runs and may replace its
+// context. Unary and stream turn one into interceptors.
+type callFilter func(ctx context.Context, method string) (context.Context, error)
+
+func unary(f callFilter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := f(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func stream(f callFilter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := f(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// public reports whether method may be called without an API key. Only
+// health checks are, so that probes and load balancers need no key.
+func public(method string) bool {
+	return strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+// apiKeyMetadata is the metadata key API keys are sent in, like the HTTP
+// X-Api-Key header. A bearer token in authorization works too.
+const apiKeyMetadata = "x-api-key"
+
+type clientCtxKey struct{}
+
+// apiClient returns the client whose API key authenticated the call, or ""
+// if none did.
+func apiClient(ctx context.Context) string {
+	client, _ := ctx.Value(clientCtxKey{}).(string)
+	return client
+}
+
+// authenticate puts the client whose API key the call carries into the
+// context, see apiClient. keys map client names to their keys. Calls are
+// only rejected by requireClient, after the rate limits, so that guessing
+// keys is limited like anything else.
+func authenticate(keys map[string]string) callFilter {
+	sums := make(map[string][sha256.Size]byte, len(keys))
+	for client, key := range keys {
+		sums[client] = sha256.Sum256([]byte(key))
+	}
+
+	return func(ctx context.Context, _ string) (context.Context, error) {
+		key := callAPIKey(ctx)
+		if key == "" {
+			return ctx, nil
+		}
+
+		// Compare digests of equal length with every key, so that the time
+		// taken tells nothing about the keys.
+		sum := sha256.Sum256([]byte(key))
+		client := ""
+		for name, s := range sums {
+			if subtle.ConstantTimeCompare(sum[:], s[:]) == 1 {
+				client = name
+			}
+		}
+		if client == "" {
+			return ctx, nil
+		}
+		return context.WithValue(ctx, clientCtxKey{}, client), nil
+	}
+}
+
+func callAPIKey(ctx context.Context) string {
+	if v := metadata.ValueFromIncomingContext(ctx, apiKeyMetadata); len(v) > 0 && v[0] != "" {
+		return v[0]
+	}
+	if v := metadata.ValueFromIncomingContext(ctx, "authorization"); len(v) > 0 {
+		auth := v[0]
+		if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+			return auth[len("Bearer "):]
+		}
+	}
+	return ""
+}
+
+// requireClient rejects the calls that authenticate didn't accept a key for.
+func requireClient(ctx context.Context, method string) (context.Context, error) {
+	if public(method) || apiClient(ctx) != "" {
+		return ctx, nil
+	}
+	logger.FromContext(ctx).Warn("invalid api key")
+	return ctx, status.Error(codes.Unauthenticated, "a valid API key is required")
+}
+
+// rateLimit takes a token from the caller's bucket in the limiter for the
+// method: uploads from upload, every other call from api. Callers are keyed
+// like over HTTP, by the client of their API key, else by peer IP.
+func rateLimit(api, upload *ratelimit.Limiter) callFilter {
+	return func(ctx context.Context, method string) (context.Context, error) {
+		if public(method) {
+			return ctx, nil
+		}
+		l := api
+		if method == portv1.PortService_UpsertPorts_FullMethodName {
+			l = upload
+		}
+
+		key := "key:" + apiClient(ctx)
+		if apiClient(ctx) == "" {
+			key = "ip:" + peerHost(ctx)
+		}
+		res := l.Allow(key)
+		if res.Allowed {
+			return ctx, nil
+		}
+
+		logger.FromContext(ctx).Warn("rate limit exceeded",
+			slog.String("client", key),
+		)
+		retry := time.Duration(math.Ceil(res.RetryAfter.Seconds())) * time.Second
+		st := status.New(codes.ResourceExhausted, fmt.Sprintf("rate limit exceeded, retry in %s", retry))
+		if d, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retry)}); err == nil {
+			st = d
+		}
+		return ctx, st.Err()
+	}
+}
+
+func peerHost(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+type AuditRecorder interface {
+	Record(ctx context.Context, e *audit.Entry)
+}
+
+// auditedMethods are the calls that change ports, with the audit action each
+// one is recorded as.
+var auditedMethods = map[string]audit.Action{
+	portv1.PortService_UpsertPorts_FullMethodName: audit.ActionPortUpload,
+	portv1.PortService_DeletePort_FullMethodName:  audit.ActionPortDelete,
+}
+
+// auditUnary records the outcome of the audited unary calls. Like the HTTP
+// audit middleware, handlers can refine the entry with auditService.Annotate.
+func auditUnary(rec AuditRecorder) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		action, ok := auditedMethods[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+		e := newAuditEntry(ctx, action)
+		res, err := handler(auditService.WithEntry(ctx, e), req)
+		recordCall(ctx, rec, e, err)
+		return res, err
+	}
+}
+
+func auditStream(rec AuditRecorder) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		action, ok := auditedMethods[info.FullMethod]
+		if !ok {
+			return handler(srv, ss)
+		}
+		e := newAuditEntry(ss.Context(), action)
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: auditService.WithEntry(ss.Context(), e)})
+		recordCall(ss.Context(), rec, e, err)
+		return err
+	}
+}
+
+// newAuditEntry starts the entry of a call, attributed to the client of its
+// API key.
+func newAuditEntry(ctx context.Context, action audit.Action) *audit.Entry {
+	e := &audit.Entry{Actor: apiClient(ctx), Action: action}
+	if e.Actor == "" {
+		e.Actor = audit.Anonymous
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		e.RemoteAddr = p.Addr.String()
+	}
+	if ua := metadata.ValueFromIncomingContext(ctx, "user-agent"); len(ua) > 0 {
+		e.UserAgent = ua[0]
+	}
+	return e
+}
+
+// recordCall completes the entry with the outcome of the call. The detail of
+// a failed call starts with its status code.
+func recordCall(ctx context.Context, rec AuditRecorder, e *audit.Entry, err error) {
+	e.Outcome = audit.OutcomeSuccess
+	if err != nil {
+		e.Outcome = audit.OutcomeFailure
+		code := status.Code(err).String()
+		if e.Detail == "" {
+			e.Detail = code
+		} else {
+			e.Detail = code + ": " + e.Detail
+		}
+	}
+	rec.Record(ctx, e)
+}
@@ -0,0 +1,241 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/axmz/go-port-service/internal/config"
+	"github.com/axmz/go-port-service/internal/domain/port"
+	auditService "github.com/axmz/go-port-service/internal/services/audit"
+	portServices "github.com/axmz/go-port-service/internal/services/port"
+	"github.com/axmz/go-port-service/internal/transport/grpc/portv1"
+	"github.com/axmz/go-port-service/pkg/ratelimit"
+)
+
+type PortService interface {
+	Get(ctx context.Context, id string) (*port.Port, error)
+	GetAll(ctx context.Context) ([]*port.Port, error)
+	UploadBatch(ctx context.Context, ports []*port.Port) error
+	Delete(ctx context.Context, id string) (*port.Port, error)
+	Watch(ctx context.Context) *portServices.Watcher
+}
+
+type ports struct {
+	portv1.UnimplementedPortServiceServer
+
+	service  PortService
+	features func() config.Features
+	closing  <-chan struct{}
+
+	// uploads is shared with the HTTP upload endpoint.
+	uploads   *ratelimit.Concurrency
+	batchSize int
+}
+
+func newPorts(s PortService, features func() config.Features, closing <-chan struct{}, uploads *ratelimit.Concurrency, batchSize int) *ports {
+	return &ports{service: s, features: features, closing: closing, uploads: uploads, batchSize: max(batchSize, 1)}
+}
+
+func (h *ports) GetPort(ctx context.Context, req *portv1.GetPortRequest) (*portv1.Port, error) {
+	p, err := h.service.Get(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(p), nil
+}
+
+func (h *ports) ListPorts(req *portv1.ListPortsRequest, stream grpc.ServerStreamingServer[portv1.Port]) error {
+	if req.GetLimit() < 0 {
+		return status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+
+	all, err := h.service.GetAll(stream.Context())
+	if err != nil {
+		return toStatus(err)
+	}
+	slices.SortFunc(all, func(a, b *port.Port) int { return strings.Compare(a.ID(), b.ID()) })
+
+	name := strings.ToLower(req.GetNameContains())
+	sent := int32(0)
+	for _, p := range all {
+		if req.GetLimit() > 0 && sent == req.GetLimit() {
+			break
+		}
+		if req.GetCountry() != "" && !strings.EqualFold(p.Country(), req.GetCountry()) ||
+			req.GetCity() != "" && !strings.EqualFold(p.City(), req.GetCity()) ||
+			!strings.Contains(strings.ToLower(p.Name()), name) {
+			continue
+		}
+		if err := stream.Send(toProto(p)); err != nil {
+			return err
+		}
+		sent++
+	}
+	return nil
+}
+
+// UpsertPorts stores the streamed ports in batches. Ports of earlier batches
+// stay stored when a later one fails.
+func (h *ports) UpsertPorts(stream grpc.ClientStreamingServer[portv1.Port, portv1.UpsertPortsResponse]) error {
+	if !h.features().Upload {
+		return status.Error(codes.Unimplemented, "uploads are disabled")
+	}
+	if !h.uploads.TryAcquire() {
+		return status.Errorf(codes.ResourceExhausted, "at most %d uploads may run at once", h.uploads.Limit())
+	}
+	defer h.uploads.Release()
+
+	ctx := stream.Context()
+	var n int32
+	defer func() { auditService.Annotate(ctx, "", fmt.Sprintf("%d ports stored", n)) }()
+
+	batch := make([]*port.Port, 0, h.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := h.service.UploadBatch(ctx, batch); err != nil {
+			return toStatus(err)
+		}
+		n += int32(len(batch))
+		batch = make([]*port.Port, 0, h.batchSize)
+		return nil
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			if err := flush(); err != nil {
+				return err
+			}
+			return stream.SendAndClose(&portv1.UpsertPortsResponse{Upserted: n})
+		}
+		if err != nil {
+			return err
+		}
+
+		p, err := fromProto(msg)
+		if err != nil {
+			return toStatus(err)
+		}
+		batch = append(batch, p)
+		if len(batch) == h.batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (h *ports) DeletePort(ctx context.Context, req *portv1.DeletePortRequest) (*portv1.Port, error) {
+	if !h.features().PortWrites {
+		return nil, status.Error(codes.Unimplemented, "port writes are disabled")
+	}
+
+	auditService.Annotate(ctx, req.GetId(), "")
+	p, err := h.service.Delete(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(p), nil
+}
+
+func (h *ports) WatchPorts(req *portv1.WatchPortsRequest, stream grpc.ServerStreamingServer[portv1.PortEvent]) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	w := h.service.Watch(ctx)
+
+	for {
+		select {
+		case e, ok := <-w.Events():
+			if !ok {
+				if errors.Is(w.Err(), portServices.ErrWatcherBehind) {
+					return status.Error(codes.ResourceExhausted, w.Err().Error())
+				}
+				return toStatus(w.Err())
+			}
+			for _, p := range e.Ports {
+				if len(req.GetIds()) > 0 && !slices.Contains(req.GetIds(), p.ID()) {
+					continue
+				}
+				if err := stream.Send(&portv1.PortEvent{Type: eventType(e.Type), Port: toProto(p)}); err != nil {
+					return err
+				}
+			}
+		case <-h.closing:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
+
+// toStatus maps service errors to gRPC status errors. Validation errors carry
+// a BadRequest detail naming the invalid fields.
+func toStatus(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	if errors.Is(err, port.ErrNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, port.ErrValidation) {
+		st := status.New(codes.InvalidArgument, err.Error())
+		var verr *port.ValidationError
+		if errors.As(err, &verr) {
+			br := &errdetails.BadRequest{}
+			for _, f := range verr.Fields {
+				br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+					Field:       f.Field,
+					Description: f.Reason(),
+				})
+			}
+			if withDetails, derr := st.WithDetails(br); derr == nil {
+				st = withDetails
+			}
+		}
+		return st.Err()
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+func eventType(t portServices.EventType) portv1.PortEvent_Type {
+	switch t {
+	case portServices.EventUpserted:
+		return portv1.PortEvent_TYPE_UPSERTED
+	case portServices.EventDeleted:
+		return portv1.PortEvent_TYPE_DELETED
+	}
+	return portv1.PortEvent_TYPE_UNSPECIFIED
+}
+
+func toProto(p *port.Port) *portv1.Port {
+	return &portv1.Port{
+		Id:          p.ID(),
+		Name:        p.Name(),
+		Code:        p.Code(),
+		City:        p.City(),
+		Country:     p.Country(),
+		Alias:       p.Alias(),
+		Regions:     p.Regions(),
+		Coordinates: p.Coordinates(),
+		Province:    p.Province(),
+		Timezone:    p.Timezone(),
+		Unlocs:      p.Unlocs(),
+	}
+}
+
+func fromProto(p *portv1.Port) (*port.Port, error) {
+	return port.New(
+		p.GetId(), p.GetName(), p.GetCode(), p.GetCity(), p.GetCountry(),
+		p.GetAlias(), p.GetRegions(), p.GetCoordinates(),
+		p.GetProvince(), p.GetTimezone(), p.GetUnlocs(),
+	)
+}
@@ -0,0 +1,97 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/axmz/go-port-service/internal/app"
+	"github.com/axmz/go-port-service/internal/config"
+	"github.com/axmz/go-port-service/internal/transport/grpc/portv1"
+)
+
+type Server struct {
+	Addr   string
+	server *grpc.Server
+	health *health.Server
+
+	// closing ends the streams that would otherwise keep a graceful stop
+	// waiting forever.
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+func NewServer(app *app.App) *Server {
+	s := &Server{
+		Addr:    app.Config.GRPCServer.Port,
+		health:  health.NewServer(),
+		closing: make(chan struct{}),
+	}
+
+	// Like the HTTP API, calls are rate limited before keys that weren't
+	// accepted are rejected.
+	auth := authenticate(app.Config.Auth.APIKeys)
+	limit := rateLimit(app.RateLimiters.API, app.RateLimiters.Upload)
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoverUnary, logUnary(app.Log),
+			unary(auth), unary(limit), unary(requireClient), auditUnary(app.Services.Audit)),
+		grpc.ChainStreamInterceptor(recoverStream, logStream(app.Log),
+			stream(auth), stream(limit), stream(requireClient), auditStream(app.Services.Audit)),
+	)
+
+	features := func() config.Features { return *app.Features.Load() }
+	portv1.RegisterPortServiceServer(s.server, newPorts(app.Services.Port, features, s.closing, app.Uploads, app.Config.Upload.BatchSize))
+
+	healthpb.RegisterHealthServer(s.server, s.health)
+	s.health.SetServingStatus(portv1.PortService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	if app.Config.GRPCServer.Reflection {
+		reflection.Register(s.server)
+	}
+	return s
+}
+
+func (s *Server) Run() {
+	slog.Info(fmt.Sprintf("Starting gRPC server on %s", s.Addr))
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		log.Fatalf("gRPC server Listen: %v", err)
+	}
+	if err := s.Serve(ln); err != nil {
+		log.Fatalf("gRPC server Serve: %v", err)
+	}
+}
+
+// Serve serves on ln until Shutdown.
+func (s *Server) Serve(ln net.Listener) error {
+	return s.server.Serve(ln)
+}
+
+// Shutdown reports NOT_SERVING to health checks, ends watches and waits for
+// the other calls to finish. Calls still running when ctx is done are cut off.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+	s.closeOnce.Do(func() { close(s.closing) })
+
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,312 @@
+package grpcserver_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/axmz/go-port-service/internal/app"
+	"github.com/axmz/go-port-service/internal/domain/audit"
+	"github.com/axmz/go-port-service/internal/transport/grpc/portv1"
+	grpcserver "github.com/axmz/go-port-service/internal/transport/grpc/server"
+)
+
+const (
+	testClient = "billing"
+	testAPIKey = "0123456789abcdef"
+)
+
+// setup serves the app over gRPC. The client sends testAPIKey unless the
+// call's metadata already has an x-api-key.
+func setup(t *testing.T) (*app.App, *grpcserver.Server, *grpc.ClientConn) {
+	t.Helper()
+	t.Setenv("API_KEYS", testClient+"="+testAPIKey)
+	a := app.SetupApp()
+	srv := grpcserver.NewServer(a)
+
+	ln := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(withAPIKey(ctx), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(withAPIKey(ctx), desc, cc, method, opts...)
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return a, srv, conn
+}
+
+func withAPIKey(ctx context.Context) context.Context {
+	if md, _ := metadata.FromOutgoingContext(ctx); len(md.Get("x-api-key")) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "x-api-key", testAPIKey)
+}
+
+func upsert(t *testing.T, c portv1.PortServiceClient, ports ...*portv1.Port) (*portv1.UpsertPortsResponse, error) {
+	t.Helper()
+	stream, err := c.UpsertPorts(t.Context())
+	require.NoError(t, err)
+	for _, p := range ports {
+		require.NoError(t, stream.Send(p))
+	}
+	return stream.CloseAndRecv()
+}
+
+func list(t *testing.T, c portv1.PortServiceClient, req *portv1.ListPortsRequest) []string {
+	t.Helper()
+	stream, err := c.ListPorts(t.Context(), req)
+	require.NoError(t, err)
+	var ids []string
+	for {
+		p, err := stream.Recv()
+		if err == io.EOF {
+			return ids
+		}
+		require.NoError(t, err)
+		ids = append(ids, p.GetId())
+	}
+}
+
+func TestPortService(t *testing.T) {
+	_, _, conn := setup(t)
+	c := portv1.NewPortServiceClient(conn)
+
+	_, err := c.GetPort(t.Context(), &portv1.GetPortRequest{Id: "NOPE"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	res, err := upsert(t, c,
+		&portv1.Port{Id: "BBB", Name: "Bravo Harbour", City: "Bergen", Country: "Norway"},
+		&portv1.Port{Id: "AAA", Name: "Alpha", City: "Oslo", Country: "Norway", Coordinates: []float64{10.7, 59.9}},
+		&portv1.Port{Id: "CCC", Name: "Charlie Harbour", City: "Lisbon", Country: "Portugal"},
+	)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, res.GetUpserted())
+
+	p, err := c.GetPort(t.Context(), &portv1.GetPortRequest{Id: "AAA"})
+	require.NoError(t, err)
+	assert.Equal(t, "Oslo", p.GetCity())
+	assert.Equal(t, []float64{10.7, 59.9}, p.GetCoordinates())
+
+	t.Run("list filters", func(t *testing.T) {
+		assert.Equal(t, []string{"AAA", "BBB"}, list(t, c, &portv1.ListPortsRequest{Country: "norway"}))
+		assert.Equal(t, []string{"BBB", "CCC"}, list(t, c, &portv1.ListPortsRequest{NameContains: "harbour"}))
+		assert.Equal(t, []string{"BBB"}, list(t, c, &portv1.ListPortsRequest{NameContains: "harbour", Country: "Norway"}))
+		assert.Len(t, list(t, c, &portv1.ListPortsRequest{Limit: 2}), 2)
+	})
+
+	t.Run("invalid upsert", func(t *testing.T) {
+		_, err := upsert(t, c, &portv1.Port{Id: "DDD", City: "Porto"})
+		st := status.Convert(err)
+		require.Equal(t, codes.InvalidArgument, st.Code())
+
+		var fields []string
+		for _, d := range st.Details() {
+			if br, ok := d.(*errdetails.BadRequest); ok {
+				for _, v := range br.GetFieldViolations() {
+					fields = append(fields, v.GetField())
+				}
+			}
+		}
+		assert.Equal(t, []string{"name", "country"}, fields)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		p, err := c.DeletePort(t.Context(), &portv1.DeletePortRequest{Id: "CCC"})
+		require.NoError(t, err)
+		assert.Equal(t, "Lisbon", p.GetCity())
+
+		_, err = c.DeletePort(t.Context(), &portv1.DeletePortRequest{Id: "CCC"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestPortService_Uploads(t *testing.T) {
+	t.Setenv("UPLOAD_BATCH_SIZE", "2")
+	t.Setenv("UPLOAD_MAX_CONCURRENT", "1")
+	a, _, conn := setup(t)
+	c := portv1.NewPortServiceClient(conn)
+
+	entries := func(action audit.Action) []*audit.Entry {
+		e, err := a.Services.Audit.Query(t.Context(), audit.Filter{Action: action})
+		require.NoError(t, err)
+		return e
+	}
+
+	t.Run("stores earlier batches when a later one fails", func(t *testing.T) {
+		_, err := upsert(t, c,
+			&portv1.Port{Id: "AAA", Name: "Alpha", City: "Oslo", Country: "Norway"},
+			&portv1.Port{Id: "BBB", Name: "Bravo", City: "Bergen", Country: "Norway"},
+			&portv1.Port{Id: "CCC", City: "Lisbon"},
+		)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, []string{"AAA", "BBB"}, list(t, c, &portv1.ListPortsRequest{}))
+
+		e := entries(audit.ActionPortUpload)
+		require.Len(t, e, 1)
+		assert.Equal(t, audit.OutcomeFailure, e[0].Outcome)
+		assert.Equal(t, "InvalidArgument: 2 ports stored", e[0].Detail)
+		assert.Equal(t, testClient, e[0].Actor)
+		assert.NotEmpty(t, e[0].UserAgent)
+	})
+
+	t.Run("shares the upload cap", func(t *testing.T) {
+		require.True(t, a.Uploads.TryAcquire())
+		_, err := upsert(t, c, &portv1.Port{Id: "DDD", Name: "Delta", City: "Porto", Country: "Portugal"})
+		a.Uploads.Release()
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+		res, err := upsert(t, c, &portv1.Port{Id: "DDD", Name: "Delta", City: "Porto", Country: "Portugal"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, res.GetUpserted())
+		assert.Equal(t, "1 ports stored", entries(audit.ActionPortUpload)[2].Detail)
+	})
+
+	t.Run("audits deletes", func(t *testing.T) {
+		_, err := c.DeletePort(t.Context(), &portv1.DeletePortRequest{Id: "DDD"})
+		require.NoError(t, err)
+		_, err = c.DeletePort(t.Context(), &portv1.DeletePortRequest{Id: "DDD"})
+		require.Error(t, err)
+
+		e := entries(audit.ActionPortDelete)
+		require.Len(t, e, 2)
+		assert.Equal(t, "DDD", e[0].Target)
+		assert.Equal(t, audit.OutcomeSuccess, e[0].Outcome)
+		assert.Equal(t, audit.OutcomeFailure, e[1].Outcome)
+		assert.Equal(t, "NotFound", e[1].Detail)
+	})
+}
+
+func TestWatchPorts(t *testing.T) {
+	// The watch is awaited by uploading until it sees one.
+	t.Setenv("RATE_LIMIT_UPLOAD", "0")
+	_, srv, conn := setup(t)
+	c := portv1.NewPortServiceClient(conn)
+
+	stream, err := c.WatchPorts(t.Context(), &portv1.WatchPortsRequest{Ids: []string{"WWW"}})
+	require.NoError(t, err)
+
+	// The watch is registered once the server has the call; wait for it by
+	// retrying the write until an event shows up.
+	events := make(chan *portv1.PortEvent)
+	go func() {
+		for {
+			e, err := stream.Recv()
+			if err != nil {
+				close(events)
+				return
+			}
+			events <- e
+		}
+	}()
+
+	var e *portv1.PortEvent
+	require.Eventually(t, func() bool {
+		_, err := upsert(t, c,
+			&portv1.Port{Id: "OTHER", Name: "Other", City: "Riga", Country: "Latvia"},
+			&portv1.Port{Id: "WWW", Name: "Watched", City: "Tallinn", Country: "Estonia"},
+		)
+		require.NoError(t, err)
+		select {
+		case e = <-events:
+			return true
+		case <-time.After(20 * time.Millisecond):
+			return false
+		}
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, portv1.PortEvent_TYPE_UPSERTED, e.GetType())
+	assert.Equal(t, "WWW", e.GetPort().GetId())
+
+	_, err = c.DeletePort(t.Context(), &portv1.DeletePortRequest{Id: "WWW"})
+	require.NoError(t, err)
+	for e = range events {
+		if e.GetType() == portv1.PortEvent_TYPE_DELETED {
+			break
+		}
+	}
+	assert.Equal(t, "WWW", e.GetPort().GetId())
+
+	t.Run("shutdown ends the watch", func(t *testing.T) {
+		require.NoError(t, srv.Shutdown(t.Context()))
+		_, open := <-events
+		assert.False(t, open)
+	})
+}
+
+func TestHealth(t *testing.T) {
+	_, _, conn := setup(t)
+	// Health checks need no API key.
+	ctx := metadata.AppendToOutgoingContext(t.Context(), "x-api-key", "")
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "port.v1.PortService"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+}
+
+func TestAPIKeys(t *testing.T) {
+	_, _, conn := setup(t)
+	c := portv1.NewPortServiceClient(conn)
+
+	for name, md := range map[string][]string{
+		"none":         {"x-api-key", ""},
+		"wrong":        {"x-api-key", "fedcba9876543210"},
+		"wrong bearer": {"x-api-key", "", "authorization", "Bearer fedcba9876543210"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.GetPort(metadata.AppendToOutgoingContext(t.Context(), md...), &portv1.GetPortRequest{Id: "AAA"})
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+			stream, err := c.UpsertPorts(metadata.AppendToOutgoingContext(t.Context(), md...))
+			require.NoError(t, err)
+			_, err = stream.CloseAndRecv()
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+
+	t.Run("bearer token", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(t.Context(), "x-api-key", "", "authorization", "Bearer "+testAPIKey)
+		_, err := c.GetPort(ctx, &portv1.GetPortRequest{Id: "AAA"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Setenv("RATE_LIMIT_API", "1/1m")
+	_, _, conn := setup(t)
+	c := portv1.NewPortServiceClient(conn)
+
+	_, err := c.GetPort(t.Context(), &portv1.GetPortRequest{Id: "AAA"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = c.GetPort(t.Context(), &portv1.GetPortRequest{Id: "AAA"})
+	st := status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+	assert.Equal(t, time.Minute, st.Details()[0].(*errdetails.RetryInfo).GetRetryDelay().AsDuration())
+
+	// Keys that aren't accepted are limited by peer, apart from the client.
+	ctx := metadata.AppendToOutgoingContext(t.Context(), "x-api-key", "fedcba9876543210")
+	_, err = c.GetPort(ctx, &portv1.GetPortRequest{Id: "AAA"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = c.GetPort(ctx, &portv1.GetPortRequest{Id: "AAA"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -2,9 +2,13 @@ package http_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
 	"os"
+	"strings"
 	"testing"
 
 	"net/http"
@@ -14,6 +18,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/axmz/go-port-service/internal/app"
+	"github.com/axmz/go-port-service/internal/config"
+	"github.com/axmz/go-port-service/internal/domain/audit"
+	"github.com/axmz/go-port-service/internal/transport/http/middleware"
 	"github.com/axmz/go-port-service/internal/transport/http/response"
 	"github.com/axmz/go-port-service/internal/transport/http/server"
 )
@@ -22,6 +29,7 @@ const (
 	portsJsonPath = "../../../static/ports.json"
 	portsCount    = 1632.0
 	sampleID      = "ZWUTA"
+	apiKey        = "0123456789abcdef0123"
 )
 
 func TestE2E_PortAPI(t *testing.T) {
@@ -29,12 +37,28 @@ func TestE2E_PortAPI(t *testing.T) {
 	server := server.NewServer(app)
 	r := server.Router.Handler
 
+	// Unsafe API requests need the session cookie and its CSRF token.
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	cookies := w.Result().Cookies()
+	csrfToken := w.Header().Get(middleware.CSRFHeader)
+	require.NotEmpty(t, csrfToken, "expected CSRF token")
+
+	withCSRF := func(req *http.Request) *http.Request {
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		req.Header.Set(middleware.CSRFHeader, csrfToken)
+		return req
+	}
+
 	// Load ports.json
 	portsJson, err := os.ReadFile(portsJsonPath)
 	require.NoError(t, err, "failed to read ports.json")
 
 	t.Run("upload ports", func(t *testing.T) {
-		req := httptest.NewRequest("POST", "/api/ports", bytes.NewReader(portsJson))
+		req := withCSRF(httptest.NewRequest("POST", "/api/ports", bytes.NewReader(portsJson)))
 		w := httptest.NewRecorder()
 		r.ServeHTTP(w, req)
 		resp := response.Response{}
@@ -70,7 +94,7 @@ func TestE2E_PortAPI(t *testing.T) {
 	})
 
 	t.Run("delete port by id", func(t *testing.T) {
-		req := httptest.NewRequest("DELETE", "/api/ports/"+sampleID, nil)
+		req := withCSRF(httptest.NewRequest("DELETE", "/api/ports/"+sampleID, nil))
 		req.SetPathValue("id", sampleID)
 		w := httptest.NewRecorder()
 		r.ServeHTTP(w, req)
@@ -80,4 +104,659 @@ func TestE2E_PortAPI(t *testing.T) {
 	t.Run("get ports count after delete", func(t *testing.T) {
 		Count(t, portsCount-1)
 	})
+
+	t.Run("metrics", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, "expected 200 on metrics")
+		body := w.Body.String()
+		assert.Contains(t, body, "port_service_port_store_size 1631")
+		assert.Contains(t, body, `port_service_http_requests_total{code="200",method="POST",route="POST /api/ports"} 1`)
+		assert.Contains(t, body, "port_service_upload_ports_total 1632")
+		assert.Contains(t, body, "go_goroutines")
+	})
+
+	t.Run("audit log records mutations", func(t *testing.T) {
+		entries, err := app.Services.Audit.Query(context.Background(), audit.Filter{})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, audit.ActionPortUpload, entries[0].Action)
+		assert.Equal(t, audit.OutcomeSuccess, entries[0].Outcome)
+		assert.Equal(t, audit.ActionPortDelete, entries[1].Action)
+		assert.Equal(t, sampleID, entries[1].Target)
+		assert.Equal(t, audit.Anonymous, entries[1].Actor)
+		assert.NotEmpty(t, entries[1].RequestID)
+	})
+}
+
+func TestE2E_TraceContextPropagation(t *testing.T) {
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/api/ports/count", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("traceparent"), traceID, "expected the trace to be continued")
+}
+
+func TestE2E_CSRF(t *testing.T) {
+	t.Setenv("API_KEYS", "billing="+apiKey)
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	t.Run("rejects unsafe request without token", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/ports/"+sampleID, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("exempts bearer authenticated request", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/ports/"+sampleID, nil)
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("rejects unknown bearer token", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/ports/"+sampleID, nil)
+		req.Header.Set("Authorization", "Bearer not-a-key")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("allows safe request without token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/ports/count", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(middleware.CSRFHeader))
+	})
+
+	t.Run("probes and scrapes don't create sessions", func(t *testing.T) {
+		before := app.DB.Session.Len(context.Background())
+		for _, path := range []string{"/healthz", "/metrics", "/static/ports.json", "/api/ports/count"} {
+			for range 10 {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+				assert.Empty(t, w.Result().Cookies(), path)
+			}
+		}
+		assert.Equal(t, before, app.DB.Session.Len(context.Background()))
+	})
+}
+
+// Only authenticated API keys get a bucket of their own, so sending a new
+// key with every request doesn't get around the limits.
+func TestE2E_RateLimitIgnoresUnknownAPIKeys(t *testing.T) {
+	t.Setenv("API_KEYS", "billing="+apiKey)
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	remaining := func(key string) string {
+		req := httptest.NewRequest("GET", "/api/ports/count", nil)
+		req.Header.Set(middleware.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Header().Get("RateLimit-Remaining")
+	}
+
+	first := remaining("guess-1")
+	assert.NotEqual(t, first, remaining("guess-2"), "unknown keys share the IP's bucket")
+	assert.Equal(t, first, remaining(apiKey), "a valid key has a bucket of its own")
+}
+
+func TestE2E_Health(t *testing.T) {
+	// Templates are loaded relative to the repository root.
+	t.Chdir("../../..")
+
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get("/healthz"))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/readyz"), "not ready before the listener is bound")
+
+	app.Health.MarkStarted()
+	assert.Equal(t, http.StatusOK, get("/readyz"))
+	assert.Equal(t, http.StatusOK, get("/startupz"))
+
+	app.Health.MarkShuttingDown()
+	assert.Equal(t, http.StatusServiceUnavailable, get("/readyz"))
+	assert.Equal(t, http.StatusOK, get("/healthz"))
+}
+
+func TestE2E_CORS(t *testing.T) {
+	const origin = "https://frontend.example.com"
+	t.Setenv("CORS_API_ALLOWED_ORIGINS", origin)
+	t.Setenv("CORS_API_ALLOW_CREDENTIALS", "true")
+
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	req := httptest.NewRequest("OPTIONS", "/api/ports", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type, X-CSRF-Token")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, origin, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, HEAD, POST", w.Header().Get("Access-Control-Allow-Methods"))
+
+	// GraphQL falls back to the API policy.
+	req = httptest.NewRequest("OPTIONS", "/query", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, origin, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestE2E_ConditionalRequests(t *testing.T) {
+	// Templates and static assets are loaded relative to the repository root.
+	t.Chdir("../../..")
+
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	cookies := w.Result().Cookies()
+	csrfToken := w.Header().Get(middleware.CSRFHeader)
+	assert.Regexp(t, `src="/static/webauthn\.[0-9a-f]{10}\.js"`, w.Body.String(), "expected fingerprinted asset URL")
+
+	portsJson, err := os.ReadFile("static/ports.json")
+	require.NoError(t, err)
+	req = httptest.NewRequest("POST", "/api/ports", bytes.NewReader(portsJson))
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	req.Header.Set(middleware.CSRFHeader, csrfToken)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/ports", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w = get("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+	etag := w.Header().Get("ETag")
+	require.Regexp(t, `^"[0-9a-f]+-[0-9a-f]+-gzip"$`, etag)
+
+	w = get(etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+	assert.Equal(t, etag, w.Header().Get("ETag"), "the 304 keeps the coding suffix")
+
+	req = httptest.NewRequest("DELETE", "/api/ports/"+sampleID, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	req.Header.Set(middleware.CSRFHeader, csrfToken)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	w = get(etag)
+	assert.Equal(t, http.StatusOK, w.Code, "a change invalidates the ETag")
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", app.Assets.Path("webauthn.js"), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Cache-Control"), "immutable")
+}
+
+func TestE2E_ProblemDetails(t *testing.T) {
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	req := httptest.NewRequest("GET", "/api/ports/MISSING", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-404")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, response.ProblemContentType, w.Header().Get("Content-Type"))
+
+	var p response.Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	assert.Equal(t, response.TypeNotFound, p.Type)
+	assert.Equal(t, "/api/ports/MISSING", p.Instance)
+	assert.Equal(t, "req-404", p.RequestID)
+
+	// Errors raised by middleware are problems too.
+	req = httptest.NewRequest("DELETE", "/api/ports/MISSING", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, response.ProblemContentType, w.Header().Get("Content-Type"))
+}
+
+func TestE2E_Reload(t *testing.T) {
+	t.Chdir("../../..")
+
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	const origin = "https://frontend.example.com"
+	allowedOrigin := func() string {
+		req := httptest.NewRequest("OPTIONS", "/api/ports", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+	query := func() int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/query?query={portsCount}", nil))
+		return w.Code
+	}
+	metrics := func() string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return w.Body.String()
+	}
+
+	assert.Empty(t, allowedOrigin())
+	assert.Equal(t, http.StatusOK, query())
+
+	t.Run("applies reloadable settings", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.CORS.API.AllowedOrigins = []string{origin}
+		cfg.Features.GraphQL = false
+		cfg.Logging.Level = "error"
+		cfg.HTTPServer.Port = ":9999"
+
+		restart, err := app.Reload(func() (*config.Config, error) { return cfg, nil })
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http_server.port"}, restart)
+
+		assert.Equal(t, origin, allowedOrigin())
+		assert.Equal(t, http.StatusNotFound, query())
+		assert.Equal(t, slog.LevelError, app.LogLevel.Level())
+		assert.Contains(t, metrics(), `port_service_config_reloads_total{outcome="success"} 1`)
+	})
+
+	t.Run("keeps the current config on failure", func(t *testing.T) {
+		_, err := app.Reload(func() (*config.Config, error) { return nil, errors.New("invalid configuration") })
+		require.Error(t, err)
+
+		assert.Equal(t, origin, allowedOrigin())
+		assert.Equal(t, http.StatusNotFound, query())
+		m := metrics()
+		assert.Contains(t, m, `port_service_config_reloads_total{outcome="failure"} 1`)
+		assert.Contains(t, m, "port_service_config_last_reload_successful 0")
+	})
+}
+
+func TestE2E_OpenAPI(t *testing.T) {
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	t.Run("documents every route", func(t *testing.T) {
+		// Routes that aren't part of the REST API.
+		undocumented := map[string]bool{
+			"/static/":                      true,
+			"/":                             true,
+			"/private":                      true,
+			"/public":                       true,
+			"/metrics":                      true,
+			"GET /healthz":                  true,
+			"GET /readyz":                   true,
+			"GET /startupz":                 true,
+			"GET /openapi.json":             true,
+			"GET /docs":                     true,
+			"GET /docs/redoc.standalone.js": true,
+			"/playground":                   true,
+			"/query":                        true,
+		}
+		documented := map[string]bool{}
+		for _, op := range server.Spec.Operations() {
+			documented[op] = true
+		}
+
+		for _, route := range server.Routes {
+			if !undocumented[route] {
+				assert.True(t, documented[route], "route %q is missing from the OpenAPI specification", route)
+			}
+			delete(documented, route)
+		}
+		assert.Empty(t, documented, "the specification documents routes that aren't registered")
+	})
+
+	t.Run("serves the specification", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var doc struct {
+			OpenAPI string         `json:"openapi"`
+			Paths   map[string]any `json:"paths"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&doc))
+		assert.Equal(t, "3.1.0", doc.OpenAPI)
+		assert.Contains(t, doc.Paths, "/api/ports/{id}")
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/docs", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `spec-url="/openapi.json"`)
+		assert.Contains(t, w.Body.String(), `<script src="/docs/redoc.standalone.js">`, "Redoc is served by the service")
+	})
+
+	t.Run("doesn't validate requests by default", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/admin/users?page=first", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestE2E_OpenAPIValidation(t *testing.T) {
+	t.Setenv("OPENAPI_VALIDATE_REQUESTS", "true")
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	cookies := w.Result().Cookies()
+	csrfToken := w.Header().Get(middleware.CSRFHeader)
+
+	withCSRF := func(req *http.Request) *http.Request {
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		req.Header.Set(middleware.CSRFHeader, csrfToken)
+		return req
+	}
+
+	problem := func(t *testing.T, req *http.Request) response.Problem {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		var p response.Problem
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+		assert.Equal(t, response.TypeValidation, p.Type)
+		return p
+	}
+
+	t.Run("query parameters", func(t *testing.T) {
+		p := problem(t, httptest.NewRequest("GET", "/api/admin/users?page=first&page_size=0", nil))
+		assert.ElementsMatch(t, []response.FieldProblem{
+			{Field: "page", Detail: `want integer, got "first"`},
+			{Field: "page_size", Detail: "minimum: got 0, want 1"},
+		}, p.Errors)
+	})
+
+	t.Run("body", func(t *testing.T) {
+		req := withCSRF(httptest.NewRequest("POST", "/api/webauth/login/begin", strings.NewReader(`{"email":3}`)))
+		p := problem(t, req)
+		assert.Equal(t, []response.FieldProblem{{Field: "body.email", Detail: "got number, want string"}}, p.Errors)
+
+		req = withCSRF(httptest.NewRequest("PUT", "/api/admin/users/42/role", strings.NewReader(`{}`)))
+		p = problem(t, req)
+		assert.Equal(t, []response.FieldProblem{{Field: "body.role", Detail: "is required"}}, p.Errors)
+	})
+
+	t.Run("oversized body", func(t *testing.T) {
+		body := `{"display_name":"` + strings.Repeat("a", 1<<20) + `"}`
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, withCSRF(httptest.NewRequest("PUT", "/api/me", strings.NewReader(body))))
+		require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		var p response.Problem
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+		assert.Equal(t, response.TypeRequestTooLarge, p.Type)
+	})
+
+	t.Run("valid requests reach the handler", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/api/ports/MISSING", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, withCSRF(httptest.NewRequest("PUT", "/api/me", strings.NewReader(`{"display_name":"Ada"}`))))
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "the body is passed on")
+	})
+}
+
+func TestE2E_APIVersions(t *testing.T) {
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	cookies := w.Result().Cookies()
+	csrfToken := w.Header().Get(middleware.CSRFHeader)
+
+	upload := httptest.NewRequest("POST", "/api/v1/ports", strings.NewReader(`{
+		"AEAJM": {"name": "Ajman", "city": "Ajman", "country": "United Arab Emirates", "coordinates": [55.5136433, 25.4052165], "unlocs": ["AEAJM"]},
+		"AEAUH": {"name": "Abu Dhabi", "city": "Abu Dhabi", "country": "United Arab Emirates"},
+		"AEDXB": {"name": "Dubai", "city": "Dubai", "country": "United Arab Emirates"}
+	}`))
+	for _, c := range cookies {
+		upload.AddCookie(c)
+	}
+	upload.Header.Set(middleware.CSRFHeader, csrfToken)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, upload)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	t.Run("v1 keeps the envelope", func(t *testing.T) {
+		w := get("/api/v1/ports/AEAJM")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Deprecation"))
+
+		var resp response.Response
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, response.StatusOK, resp.Status)
+		assert.Equal(t, "AEAJM", resp.Data.(map[string]any)["id"])
+	})
+
+	t.Run("v2 pages without the envelope", func(t *testing.T) {
+		w := get("/api/v2/ports?page=2&page_size=2")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var page struct {
+			Items []struct {
+				ID        string             `json:"id"`
+				UNLocodes []string           `json:"unlocodes"`
+				Location  map[string]float64 `json:"location"`
+			} `json:"items"`
+			Page     int `json:"page"`
+			PageSize int `json:"page_size"`
+			Total    int `json:"total"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+		assert.Equal(t, 2, page.Page)
+		assert.Equal(t, 2, page.PageSize)
+		assert.Equal(t, 3, page.Total)
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, "AEDXB", page.Items[0].ID, "ordered by ID")
+
+		w = get("/api/v2/ports/AEAJM")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{
+			"id": "AEAJM", "name": "Ajman", "code": "", "city": "Ajman", "province": "",
+			"country": "United Arab Emirates", "timezone": "", "aliases": [], "regions": [],
+			"unlocodes": ["AEAJM"], "location": {"latitude": 25.4052165, "longitude": 55.5136433}
+		}`, w.Body.String())
+	})
+
+	t.Run("legacy paths are deprecated", func(t *testing.T) {
+		w := get("/api/ports/AEAJM")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "@1792368000", w.Header().Get("Deprecation"))
+		assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+		assert.Equal(t, `</api/v1/ports/AEAJM>; rel="successor-version"`, w.Header().Get("Link"))
+
+		var resp response.Response
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, response.StatusOK, resp.Status, "same behavior as v1")
+	})
+
+	t.Run("usage is counted per version", func(t *testing.T) {
+		body := get("/metrics").Body.String()
+		assert.Contains(t, body, `port_service_api_requests_total{version="legacy"} 1`)
+		assert.Contains(t, body, `port_service_api_requests_total{version="v1"} 2`)
+		assert.Contains(t, body, `port_service_api_requests_total{version="v2"} 2`)
+	})
+
+	t.Run("v2 renames", func(t *testing.T) {
+		rename := func(id, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("POST", "/api/v2/ports/"+id+"/rename", strings.NewReader(body))
+			for _, c := range cookies {
+				req.AddCookie(c)
+			}
+			req.Header.Set(middleware.CSRFHeader, csrfToken)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w
+		}
+
+		w := rename("AEAJM", `{"id": "AEAUH"}`)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		w = rename("AEAJM", `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+		w = rename("AEAJM", `{"id": "AEAJX"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusNotFound, get("/api/v2/ports/AEAJM").Code)
+		assert.Equal(t, http.StatusOK, get("/api/v2/ports/AEAJX").Code)
+
+		entries, err := app.Services.Audit.Query(t.Context(), audit.Filter{Action: audit.ActionPortRename})
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, "AEAJM", entries[2].Target)
+		assert.Equal(t, "renamed to AEAJX", entries[2].Detail)
+	})
+}
+
+func TestE2E_SnapshotPagination(t *testing.T) {
+	t.Setenv("OPENAPI_VALIDATE_REQUESTS", "true")
+	app := app.SetupApp()
+	server := server.NewServer(app)
+	r := server.Router.Handler
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	cookies := w.Result().Cookies()
+	csrfToken := w.Header().Get(middleware.CSRFHeader)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		req.Header.Set(middleware.CSRFHeader, csrfToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	type page struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+		Total    int    `json:"total"`
+		Snapshot string `json:"snapshot"`
+	}
+	list := func(query string) page {
+		t.Helper()
+		w := send("GET", "/api/v2/ports?"+query, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var p page
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+		return p
+	}
+
+	w = send("POST", "/api/v1/ports", `{
+		"AEAJM": {"name": "Ajman", "city": "Ajman", "country": "United Arab Emirates"},
+		"AEAUH": {"name": "Abu Dhabi", "city": "Abu Dhabi", "country": "United Arab Emirates"},
+		"AEDXB": {"name": "Dubai", "city": "Dubai", "country": "United Arab Emirates"}
+	}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	first := list("page=1&page_size=2")
+	require.NotEmpty(t, first.Snapshot)
+	assert.Equal(t, 3, first.Total)
+
+	// A port sorting before all others is uploaded between the pages.
+	w = send("POST", "/api/v1/ports", `{"AAAAA": {"name": "First", "city": "First", "country": "Nowhere"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	second := list("page=2&page_size=2&snapshot=" + first.Snapshot)
+	assert.Equal(t, first.Snapshot, second.Snapshot)
+	assert.Equal(t, 3, second.Total)
+	require.Len(t, second.Items, 1)
+	assert.Equal(t, "AEDXB", second.Items[0].ID, "not shifted by the new port")
+
+	assert.Equal(t, 4, list("page=1&page_size=2").Total)
+
+	t.Run("pages of a snapshot stay fresh", func(t *testing.T) {
+		path := "/api/v2/ports?page=1&page_size=2&snapshot=" + first.Snapshot
+		w := send("GET", path, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+		assert.Empty(t, w.Header().Get("Last-Modified"))
+
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code, "uploaded after the snapshot, the new port isn't on this page")
+
+		w = send("GET", "/api/v2/ports?page=1&page_size=2", "")
+		assert.NotEqual(t, etag, w.Header().Get("ETag"), "a new snapshot is a new page")
+		w = send("GET", "/api/v2/ports?page=1&page_size=3&snapshot="+first.Snapshot, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, etag, w.Header().Get("ETag"), "so is another page size")
+	})
+
+	w = send("GET", "/api/v2/ports?snapshot=1-999999", "")
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Equal(t, response.ProblemContentType, w.Header().Get("Content-Type"))
 }
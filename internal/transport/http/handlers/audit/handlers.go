@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/axmz/go-port-service/internal/domain/audit"
+	"github.com/axmz/go-port-service/internal/logger"
+	"github.com/axmz/go-port-service/internal/transport/http/response"
+)
+
+type AuditService interface {
+	Query(ctx context.Context, f audit.Filter) ([]*audit.Entry, error)
+}
+
+type Handlers struct {
+	audit AuditService
+}
+
+func New(s AuditService) *Handlers {
+	return &Handlers{
+		audit: s,
+	}
+}
+
+type Response struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target,omitempty"`
+	RequestID  string    `json:"request_id"`
+	RemoteAddr string    `json:"remote_addr"`
+	UserAgent  string    `json:"user_agent"`
+	Outcome    string    `json:"outcome"`
+	Status     int       `json:"status"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+func (h *Handlers) List(w http.ResponseWriter, r *http.Request) {
+	entries, ok := h.query(w, r)
+	if !ok {
+		return
+	}
+
+	res := make([]Response, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, fromDomainToResponse(e))
+	}
+	response.OK(w, res)
+}
+
+// Export streams matching entries as newline delimited JSON.
+func (h *Handlers) Export(w http.ResponseWriter, r *http.Request) {
+	entries, ok := h.query(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.ndjson"`)
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(fromDomainToResponse(e)); err != nil {
+			logger.FromContext(r.Context()).Info(fmt.Sprintf("error encoding audit entry: %v", err))
+			return
+		}
+	}
+}
+
+func (h *Handlers) query(w http.ResponseWriter, r *http.Request) ([]*audit.Entry, bool) {
+	f, err := parseFilter(r)
+	if err != nil {
+		response.Error(w, r, response.Invalid(err))
+		return nil, false
+	}
+
+	entries, err := h.audit.Query(r.Context(), f)
+	if err != nil {
+		response.Error(w, r, err)
+		return nil, false
+	}
+	return entries, true
+}
+
+func parseFilter(r *http.Request) (audit.Filter, error) {
+	q := r.URL.Query()
+	f := audit.Filter{
+		Actor:  q.Get("actor"),
+		Action: audit.Action(q.Get("action")),
+	}
+
+	var err error
+	if v := q.Get("from"); v != "" {
+		if f.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if f.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	return f, nil
+}
+
+func fromDomainToResponse(e *audit.Entry) Response {
+	return Response{
+		ID:         e.ID,
+		Time:       e.Time,
+		Actor:      e.Actor,
+		Action:     string(e.Action),
+		Target:     e.Target,
+		RequestID:  e.RequestID,
+		RemoteAddr: e.RemoteAddr,
+		UserAgent:  e.UserAgent,
+		Outcome:    string(e.Outcome),
+		Status:     e.Status,
+		Detail:     e.Detail,
+	}
+}
@@ -2,37 +2,78 @@ package port
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"log/slog"
+	"fmt"
 	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
 
 	"github.com/axmz/go-port-service/internal/domain/port"
+	portService "github.com/axmz/go-port-service/internal/services/port"
 	"github.com/axmz/go-port-service/internal/transport/http/response"
 )
 
+var tracer = otel.Tracer("github.com/axmz/go-port-service/internal/transport/http/handlers/port")
+
 type PortService interface {
 	Get(ctx context.Context, id string) (*port.Port, error)
 	Delete(ctx context.Context, id string) (*port.Port, error)
+	Rename(ctx context.Context, id, newID string) (*port.Port, error)
 	GetAll(ctx context.Context) ([]*port.Port, error)
+	List(ctx context.Context, snapshot port.SnapshotID, page, pageSize int) (*portService.Page, error)
 	Count(ctx context.Context) int
 	Upload(ctx context.Context, p *port.Port) error
+	UploadBatch(ctx context.Context, ports []*port.Port) error
+	Revision(ctx context.Context) (uint64, time.Time)
+}
+
+type UploadMetrics interface {
+	UploadedPorts(n int)
+	UploadedBytes(n int64)
+	UploadRejected(reason string)
 }
 
 type Handlers struct {
-	port PortService
+	port    PortService
+	metrics UploadMetrics
+	upload  UploadOptions
 }
 
-func New(s PortService) *Handlers {
+// New creates the port handlers. Zero Workers or BatchSize take their defaults.
+func New(s PortService, m UploadMetrics, opts UploadOptions) *Handlers {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultUploadWorkers
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultUploadBatchSize
+	}
 	return &Handlers{
-		port: s,
+		port:    s,
+		metrics: m,
+		upload:  opts,
 	}
 }
 
+func (h *Handlers) validators(ctx context.Context) (string, time.Time) {
+	return validators(ctx, h.port)
+}
+
+// validators derives a strong ETag from the store revision. The modification
+// time is part of it so that tags don't repeat after a restart.
+func validators(ctx context.Context, s PortService) (string, time.Time) {
+	rev, modified := s.Revision(ctx)
+	return fmt.Sprintf(`"%x-%x"`, modified.UnixNano(), rev), modified
+}
+
 func (h *Handlers) GetAll(w http.ResponseWriter, r *http.Request) {
+	etag, modified := h.validators(r.Context())
+	if response.NotModified(w, r, etag, modified) {
+		return
+	}
+
 	data, err := h.port.GetAll(r.Context())
 	if err != nil {
-		response.InternalServerError(w, err)
+		response.Error(w, r, err)
 		return
 	}
 	res := make([]Response, 0, len(data))
@@ -46,119 +87,49 @@ func (h *Handlers) Get(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
 	if id == "" {
-		response.BadRequest(w, "missing id")
+		response.ProblemStatus(w, r, http.StatusBadRequest, "missing id")
 		return
 	}
 
+	// Read the revision first: if the port changes in between, the tag is
+	// stale and the next request is a miss rather than a wrong hit.
+	etag, modified := h.validators(r.Context())
+
 	if p, err := h.port.Get(r.Context(), id); err != nil {
-		if errors.Is(err, port.ErrNotFound) {
-			response.NotFound(w)
-		} else {
-			response.InternalServerError(w, err)
-		}
+		response.Error(w, r, err)
 		return
-	} else {
+	} else if !response.NotModified(w, r, etag, modified) {
 		response.OK(w, h.fromDomainToResponse(p))
 	}
 }
 
 func (h *Handlers) Count(w http.ResponseWriter, r *http.Request) {
-	c := h.port.Count(r.Context())
-	response.OK(w, c)
-}
-
-func readBody(r *http.Request, portCh chan Request, errCh chan error, doneCh chan struct{}) {
-	defer close(portCh)
-	defer close(errCh)
-	defer close(doneCh)
-
-	dec := json.NewDecoder(r.Body)
-
-	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
-		errCh <- err
-		return
-	}
-
-	for dec.More() {
-		var id string
-		if t, err := dec.Token(); err != nil {
-			errCh <- err
-			return
-		} else {
-			id = t.(string)
-		}
-
-		var p Request
-		if err := dec.Decode(&p); err != nil {
-			errCh <- err
-			return
-		}
-
-		p.ID = id
-		portCh <- p
-	}
-
-	if _, err := dec.Token(); err != nil {
-		errCh <- err
+	etag, modified := h.validators(r.Context())
+	if response.NotModified(w, r, etag, modified) {
 		return
 	}
 
-	doneCh <- struct{}{}
-}
-
-func (h *Handlers) Upload(w http.ResponseWriter, r *http.Request) {
-	r.Body = http.MaxBytesReader(w, r.Body, 50<<20) // TODO: move to config or middleware
-
-	portCh := make(chan Request)
-	errCh := make(chan error)
-	doneCh := make(chan struct{})
-
-	go readBody(r, portCh, errCh, doneCh)
-	countPorts := 0
-
-	for {
-		select {
-		case <-r.Context().Done():
-			slog.Info("request cancelled")
-			return
-		case err := <-errCh:
-			slog.Info(err.Error())
-			response.Err(w, http.StatusBadRequest, err.Error())
-			return
-		case p := <-portCh:
-			countPorts++
-			if portDomain, err := fromRequestToDomain(&p); err != nil {
-				response.Err(w, http.StatusBadRequest, err.Error())
-				return
-			} else if err := h.port.Upload(r.Context(), portDomain); err != nil {
-				response.BadRequest(w, err.Error())
-				return
-			}
-		case <-doneCh:
-			slog.Info("data processed successfully")
-			response.OK(w, countPorts)
-			return
-		}
-	}
+	c := h.port.Count(r.Context())
+	response.OK(w, c)
 }
 
 func (h *Handlers) UpdatePort(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
 	if id == "" {
-		response.BadRequest(w, "missing id")
+		response.ProblemStatus(w, r, http.StatusBadRequest, "missing id")
 		return
 	}
 
 	if p, err := h.port.Get(r.Context(), id); err != nil {
-		handleError(w, err)
+		response.Error(w, r, err)
 		return
 	} else {
 		copy, _ := p.Copy()
 		// TODO: impl granular update or complete replace
 		_ = copy.SetName("TEST")
 		if err := h.port.Upload(r.Context(), copy); err != nil {
-			response.BadRequest(w, err.Error())
+			response.Error(w, r, err)
 			return
 		} else {
 			response.OK(w, h.fromDomainToResponse(copy))
@@ -170,23 +141,14 @@ func (h *Handlers) Delete(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
 	if id == "" {
-		response.BadRequest(w, "missing id")
+		response.ProblemStatus(w, r, http.StatusBadRequest, "missing id")
 		return
 	}
 
 	if p, err := h.port.Delete(r.Context(), id); err != nil {
-		handleError(w, err)
+		response.Error(w, r, err)
 		return
 	} else {
 		response.OK(w, h.fromDomainToResponse(p))
 	}
 }
-
-func handleError(w http.ResponseWriter, err error) {
-	switch {
-	case errors.Is(err, port.ErrNotFound):
-		response.NotFound(w)
-	default:
-		response.InternalServerError(w, err)
-	}
-}
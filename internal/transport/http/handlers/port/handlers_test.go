@@ -7,17 +7,27 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/axmz/go-port-service/internal/domain/port"
+	portService "github.com/axmz/go-port-service/internal/services/port"
 	"github.com/stretchr/testify/assert"
 )
 
 type mockPortService struct {
 	GetFunc    func(ctx context.Context, id string) (*port.Port, error)
 	DeleteFunc func(ctx context.Context, id string) (*port.Port, error)
+	RenameFunc func(ctx context.Context, id, newID string) (*port.Port, error)
 	GetAllFunc func(ctx context.Context) ([]*port.Port, error)
+	ListFunc   func(ctx context.Context, snapshot port.SnapshotID, page, pageSize int) (*portService.Page, error)
 	CountFunc  func(ctx context.Context) int
 	UploadFunc func(ctx context.Context, p *port.Port) error
+
+	UploadBatchFunc func(ctx context.Context, ports []*port.Port) error
+}
+
+func (m *mockPortService) Revision(ctx context.Context) (uint64, time.Time) {
+	return 1, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
 }
 
 func (m *mockPortService) Get(ctx context.Context, id string) (*port.Port, error) {
@@ -26,23 +36,38 @@ func (m *mockPortService) Get(ctx context.Context, id string) (*port.Port, error
 func (m *mockPortService) Delete(ctx context.Context, id string) (*port.Port, error) {
 	return m.DeleteFunc(ctx, id)
 }
+func (m *mockPortService) Rename(ctx context.Context, id, newID string) (*port.Port, error) {
+	return m.RenameFunc(ctx, id, newID)
+}
 func (m *mockPortService) GetAll(ctx context.Context) ([]*port.Port, error) {
 	return m.GetAllFunc(ctx)
 }
+func (m *mockPortService) List(ctx context.Context, snapshot port.SnapshotID, page, pageSize int) (*portService.Page, error) {
+	return m.ListFunc(ctx, snapshot, page, pageSize)
+}
 func (m *mockPortService) Count(ctx context.Context) int {
 	return m.CountFunc(ctx)
 }
 func (m *mockPortService) Upload(ctx context.Context, p *port.Port) error {
 	return m.UploadFunc(ctx, p)
 }
+func (m *mockPortService) UploadBatch(ctx context.Context, ports []*port.Port) error {
+	return m.UploadBatchFunc(ctx, ports)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) UploadedPorts(n int)          {}
+func (nopMetrics) UploadedBytes(n int64)        {}
+func (nopMetrics) UploadRejected(reason string) {}
 
 func TestUpload_Success(t *testing.T) {
 	mockSvc := &mockPortService{
-		UploadFunc: func(ctx context.Context, p *port.Port) error {
+		UploadBatchFunc: func(ctx context.Context, ports []*port.Port) error {
 			return nil
 		},
 	}
-	h := New(mockSvc)
+	h := New(mockSvc, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
 
 	body := `{"id1": {"name": "Port1", "city": "City1", "country": "Country1", "code": "C1", "alias": [], "regions": [], "coordinates": [], "province": "", "timezone": "", "unlocs": []}}`
 	req := httptest.NewRequest("POST", "/api/ports", strings.NewReader(body))
@@ -55,7 +80,7 @@ func TestUpload_Success(t *testing.T) {
 
 func TestUpload_BadJSON(t *testing.T) {
 	mockSvc := &mockPortService{}
-	h := New(mockSvc)
+	h := New(mockSvc, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
 
 	req := httptest.NewRequest("POST", "/api/ports", strings.NewReader("notjson"))
 	w := httptest.NewRecorder()
@@ -70,7 +95,7 @@ func TestGetAll(t *testing.T) {
 		GetAllFunc: func(ctx context.Context) ([]*port.Port, error) {
 			return []*port.Port{}, nil
 		},
-	})
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
 	req := httptest.NewRequest("GET", "/api/ports", nil)
 	w := httptest.NewRecorder()
 	h.GetAll(w, req)
@@ -82,7 +107,7 @@ func TestGetAll_Error(t *testing.T) {
 		GetAllFunc: func(ctx context.Context) ([]*port.Port, error) {
 			return nil, errors.New("fail")
 		},
-	})
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
 	req := httptest.NewRequest("GET", "/api/ports", nil)
 	w := httptest.NewRecorder()
 	h.GetAll(w, req)
@@ -94,7 +119,7 @@ func TestGet(t *testing.T) {
 		GetFunc: func(ctx context.Context, id string) (*port.Port, error) {
 			return &port.Port{}, nil
 		},
-	})
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
 	req := httptest.NewRequest("GET", "/api/ports/123", nil)
 	req.SetPathValue("id", "123")
 	w := httptest.NewRecorder()
@@ -107,7 +132,7 @@ func TestGet_NotFound(t *testing.T) {
 		GetFunc: func(ctx context.Context, id string) (*port.Port, error) {
 			return nil, port.ErrNotFound
 		},
-	})
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
 	req := httptest.NewRequest("GET", "/api/ports/123", nil)
 	req.SetPathValue("id", "123")
 	w := httptest.NewRecorder()
@@ -120,7 +145,7 @@ func TestGet_Error(t *testing.T) {
 		GetFunc: func(ctx context.Context, id string) (*port.Port, error) {
 			return nil, errors.New("fail")
 		},
-	})
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
 	req := httptest.NewRequest("GET", "/api/ports/123", nil)
 	req.SetPathValue("id", "123")
 	w := httptest.NewRecorder()
@@ -133,7 +158,7 @@ func TestCount(t *testing.T) {
 		CountFunc: func(ctx context.Context) int {
 			return 42
 		},
-	})
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
 	req := httptest.NewRequest("GET", "/api/ports/count", nil)
 	w := httptest.NewRecorder()
 	h.Count(w, req)
@@ -145,7 +170,7 @@ func TestDelete(t *testing.T) {
 		DeleteFunc: func(ctx context.Context, id string) (*port.Port, error) {
 			return &port.Port{}, nil
 		},
-	})
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
 	req := httptest.NewRequest("DELETE", "/api/ports/123", nil)
 	req.SetPathValue("id", "123")
 	w := httptest.NewRecorder()
@@ -158,7 +183,7 @@ func TestDelete_NotFound(t *testing.T) {
 		DeleteFunc: func(ctx context.Context, id string) (*port.Port, error) {
 			return nil, port.ErrNotFound
 		},
-	})
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
 	req := httptest.NewRequest("DELETE", "/api/ports/123", nil)
 	req.SetPathValue("id", "123")
 	w := httptest.NewRecorder()
@@ -171,7 +196,7 @@ func TestDelete_Error(t *testing.T) {
 		DeleteFunc: func(ctx context.Context, id string) (*port.Port, error) {
 			return nil, errors.New("fail")
 		},
-	})
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
 	req := httptest.NewRequest("DELETE", "/api/ports/123", nil)
 	req.SetPathValue("id", "123")
 	w := httptest.NewRecorder()
@@ -36,3 +36,31 @@ func fromRequestToDomain(p *Request) (*port.Port, error) {
 		append([]string(nil), p.Unlocs...),
 	)
 }
+
+func fromDomainToV2(p *port.Port) PortV2 {
+	r := PortV2{
+		ID:        p.ID(),
+		Name:      p.Name(),
+		Code:      p.Code(),
+		City:      p.City(),
+		Province:  p.Province(),
+		Country:   p.Country(),
+		Timezone:  p.Timezone(),
+		Aliases:   nonNil(p.Alias()),
+		Regions:   nonNil(p.Regions()),
+		UNLocodes: nonNil(p.Unlocs()),
+	}
+	// Coordinates are stored longitude first.
+	if c := p.Coordinates(); len(c) == 2 {
+		r.Location = &Location{Longitude: c[0], Latitude: c[1]}
+	}
+	return r
+}
+
+// nonNil makes empty lists encode as [] rather than null.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
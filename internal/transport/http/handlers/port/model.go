@@ -14,3 +14,36 @@ type Response struct {
 	Timezone    string    `json:"timezone"`
 	Unlocs      []string  `json:"unlocs"`
 }
+
+// RenameRequest moves a port to a new ID.
+type RenameRequest struct {
+	ID string `json:"id"`
+}
+
+// PortV2 is a port in version 2 of the API.
+type PortV2 struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Code      string    `json:"code"`
+	City      string    `json:"city"`
+	Province  string    `json:"province"`
+	Country   string    `json:"country"`
+	Timezone  string    `json:"timezone"`
+	Aliases   []string  `json:"aliases"`
+	Regions   []string  `json:"regions"`
+	UNLocodes []string  `json:"unlocodes"`
+	Location  *Location `json:"location"`
+}
+
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type PageV2 struct {
+	Items    []PortV2 `json:"items"`
+	Page     int      `json:"page"`
+	PageSize int      `json:"page_size"`
+	Total    int      `json:"total"`
+	Snapshot string   `json:"snapshot"`
+}
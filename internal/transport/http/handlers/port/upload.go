@@ -0,0 +1,261 @@
+package port
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/internal/logger"
+	auditService "github.com/axmz/go-port-service/internal/services/audit"
+	"github.com/axmz/go-port-service/internal/transport/http/response"
+)
+
+const (
+	DefaultUploadWorkers   = 4
+	DefaultUploadBatchSize = 256
+
+	// uploadProgressEvery is how often, in ports, the upload span gets a progress event.
+	uploadProgressEvery = 500
+)
+
+// ErrNotObject is returned for an upload body that isn't a JSON object.
+var ErrNotObject = errors.New("upload must be a JSON object of ports keyed by id")
+
+type UploadOptions struct {
+	// MaxBytes caps the size of an upload body.
+	MaxBytes int64
+	// Workers validate ports in parallel.
+	Workers int
+	// BatchSize is how many decoded ports may wait for each worker.
+	BatchSize int
+}
+
+// uploadError tells why an upload stopped, for metrics and the span.
+type uploadError struct {
+	reason string
+	err    error
+}
+
+func (e *uploadError) Error() string { return e.err.Error() }
+func (e *uploadError) Unwrap() error { return e.err }
+
+// Upload streams a JSON object of ports into the store. A decoder feeds a pool
+// of workers that validate and stage ports. Ports with the same id always go
+// to the same worker, so the last one in the body wins. Once the whole body
+// is read, the staged ports are stored in one write, so readers see all of
+// them or none. The first failure stops the pipeline and stores nothing.
+func (h *Handlers) Upload(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "PortHandlers.Upload", trace.WithAttributes(
+		attribute.Int("upload.workers", h.upload.Workers),
+		attribute.Int("upload.batch_size", h.upload.BatchSize),
+	))
+	defer span.End()
+
+	body := &countingReader{r: http.MaxBytesReader(w, r.Body, h.upload.MaxBytes)}
+	r.Body = body
+	defer func() { h.metrics.UploadedBytes(body.n) }()
+
+	g, gctx := errgroup.WithContext(ctx)
+	queues := make([]chan Request, h.upload.Workers)
+	for i := range queues {
+		queues[i] = make(chan Request, h.upload.BatchSize)
+	}
+
+	g.Go(func() error {
+		defer func() {
+			for _, q := range queues {
+				close(q)
+			}
+		}()
+		return readBody(gctx, body, queues)
+	})
+
+	var validated atomic.Int64
+	staged := make([][]*port.Port, len(queues))
+	for i, q := range queues {
+		g.Go(func() (err error) {
+			staged[i], err = h.stagePorts(gctx, q, &validated, span)
+			return err
+		})
+	}
+
+	err := g.Wait()
+	countPorts := 0
+	if err == nil {
+		err = h.storeStaged(ctx, staged)
+	}
+	if err == nil {
+		for _, ports := range staged {
+			countPorts += len(ports)
+		}
+	}
+	h.metrics.UploadedPorts(countPorts)
+
+	if err == nil {
+		logger.FromContext(ctx).Info("data processed successfully")
+		span.AddEvent("upload completed", trace.WithAttributes(
+			attribute.Int("ports.stored", countPorts),
+			attribute.Int64("bytes.read", body.n),
+		))
+		auditService.Annotate(ctx, "", fmt.Sprintf("%d ports uploaded", countPorts))
+		response.OK(w, countPorts)
+		return
+	}
+
+	// A client that went away gets no response, whatever failed first.
+	reason, cause := "cancelled", ctx.Err()
+	var uerr *uploadError
+	if cause == nil && errors.As(err, &uerr) {
+		reason, cause = uerr.reason, uerr.err
+	} else if cause == nil {
+		reason, cause = "storage", err
+	}
+
+	h.metrics.UploadRejected(reason)
+	span.AddEvent("upload rejected", trace.WithAttributes(
+		attribute.String("reason", reason),
+		attribute.Int("ports.stored", countPorts),
+	))
+	span.RecordError(cause)
+	span.SetStatus(codes.Error, reason)
+
+	switch reason {
+	case "cancelled":
+		logger.FromContext(ctx).Info("request cancelled")
+	case "malformed", "too_large":
+		logger.FromContext(ctx).Info(cause.Error())
+		response.Error(w, r, response.Invalid(cause))
+	default:
+		response.Error(w, r, cause)
+	}
+}
+
+// readBody decodes ports one at a time and hands each to the queue its id
+// hashes to. It gives up as soon as ctx is done.
+func readBody(ctx context.Context, body io.Reader, queues []chan Request) error {
+	dec := json.NewDecoder(body)
+
+	malformed := func(err error) error {
+		return &uploadError{reason: rejectionReason(err), err: err}
+	}
+
+	if t, err := dec.Token(); err != nil {
+		return malformed(err)
+	} else if t != json.Delim('{') {
+		return malformed(ErrNotObject)
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return malformed(err)
+		}
+		id, ok := t.(string)
+		if !ok {
+			return malformed(ErrNotObject)
+		}
+
+		var p Request
+		if err := dec.Decode(&p); err != nil {
+			return malformed(err)
+		}
+		p.ID = id
+
+		select {
+		case queues[queueFor(id, len(queues))] <- p:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return malformed(err)
+	}
+	return nil
+}
+
+// stagePorts validates the ports from q until q is closed and returns them.
+func (h *Handlers) stagePorts(ctx context.Context, q <-chan Request, validated *atomic.Int64, span trace.Span) ([]*port.Port, error) {
+	var staged []*port.Port
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case p, ok := <-q:
+			if !ok {
+				return staged, nil
+			}
+			portDomain, err := fromRequestToDomain(&p)
+			if err != nil {
+				return nil, &uploadError{reason: "invalid", err: err}
+			}
+			staged = append(staged, portDomain)
+			if total := validated.Add(1); total%uploadProgressEvery == 0 {
+				span.AddEvent("ports validated", trace.WithAttributes(attribute.Int64("ports.validated", total)))
+			}
+		}
+	}
+}
+
+// storeStaged stores the ports every worker staged in one write. Workers
+// stage disjoint ids, so their order doesn't matter.
+func (h *Handlers) storeStaged(ctx context.Context, staged [][]*port.Port) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var all []*port.Port
+	for _, ports := range staged {
+		all = append(all, ports...)
+	}
+	if len(all) == 0 {
+		return nil
+	}
+	if err := h.port.UploadBatch(ctx, all); err != nil {
+		return &uploadError{reason: "storage", err: err}
+	}
+	return nil
+}
+
+// queueFor picks one of n queues by FNV-1a hash of id.
+func queueFor(id string, n int) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(id); i++ {
+		h ^= uint32(id[i])
+		h *= 16777619
+	}
+	return int(h % uint32(n))
+}
+
+func rejectionReason(err error) string {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return "too_large"
+	}
+	return "malformed"
+}
+
+// countingReader counts the bytes read from the request body.
+type countingReader struct {
+	r io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return c.r.Close()
+}
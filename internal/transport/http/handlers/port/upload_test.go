@@ -0,0 +1,199 @@
+package port
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+	portRepository "github.com/axmz/go-port-service/internal/repository/port"
+	portService "github.com/axmz/go-port-service/internal/services/port"
+	"github.com/axmz/go-port-service/pkg/inmem"
+)
+
+func portsBody(n int) string {
+	var b strings.Builder
+	b.WriteString("{")
+	for i := range n {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `"P%05d": {"name": "Port%d", "city": "City", "country": "Country", "code": "C"}`, i, i)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func TestUpload_StoresOnce(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	var (
+		mu      sync.Mutex
+		stored  = map[string]string{}
+		batches int
+	)
+	h := New(&mockPortService{
+		UploadBatchFunc: func(ctx context.Context, ports []*port.Port) error {
+			mu.Lock()
+			defer mu.Unlock()
+			batches++
+			for _, p := range ports {
+				stored[p.ID()] = p.Name()
+			}
+			return nil
+		},
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20, Workers: 3, BatchSize: 10})
+
+	// The duplicate id must keep the last value, whichever worker it goes to.
+	body := strings.TrimSuffix(portsBody(100), "}") + `, "P00007": {"name": "Last", "city": "City", "country": "Country", "code": "C"}}`
+	w := httptest.NewRecorder()
+	h.Upload(w, httptest.NewRequest("POST", "/api/ports", strings.NewReader(body)))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, stored, 100)
+	assert.Equal(t, "Last", stored["P00007"])
+	assert.Equal(t, 1, batches, "readers see the whole upload at once")
+}
+
+func TestUpload_NotAnObject(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	h := New(&mockPortService{}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20})
+	for _, body := range []string{`[]`, `"ports"`, `42`, ``} {
+		w := httptest.NewRecorder()
+		h.Upload(w, httptest.NewRequest("POST", "/api/ports", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, "body %q", body)
+	}
+}
+
+func TestUpload_MalformedDoesNotLeak(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	h := New(&mockPortService{
+		UploadBatchFunc: func(ctx context.Context, ports []*port.Port) error { return nil },
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20, Workers: 4, BatchSize: 2})
+
+	body := strings.TrimSuffix(portsBody(50), "}") + `, "broken": {"name": }`
+	w := httptest.NewRecorder()
+	h.Upload(w, httptest.NewRequest("POST", "/api/ports", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpload_InvalidPortStops(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	var stored bool
+	h := New(&mockPortService{
+		UploadBatchFunc: func(ctx context.Context, ports []*port.Port) error {
+			stored = true
+			return nil
+		},
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20, Workers: 2, BatchSize: 1})
+
+	body := `{"A": {"name": "Port A"}, "B": {"name": ""}, "C": {"name": "Port C"}}`
+	w := httptest.NewRecorder()
+	h.Upload(w, httptest.NewRequest("POST", "/api/ports", strings.NewReader(body)))
+
+	assert.NotEqual(t, http.StatusOK, w.Code)
+	assert.False(t, stored, "ports staged before the failure are dropped")
+}
+
+func TestUpload_CancelMidBodyStoresNothing(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	var stored bool
+	h := New(&mockPortService{
+		UploadBatchFunc: func(ctx context.Context, ports []*port.Port) error {
+			stored = true
+			return nil
+		},
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20, Workers: 2, BatchSize: 4})
+
+	// The client sends half the ports and goes away.
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("POST", "/api/ports", pr).WithContext(ctx)
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Upload(w, req)
+	}()
+
+	body := portsBody(100)
+	_, err := pw.Write([]byte(body[:len(body)/2]))
+	require.NoError(t, err)
+	cancel()
+	pw.CloseWithError(context.Canceled)
+	<-done
+
+	assert.False(t, stored)
+	assert.Zero(t, w.Body.Len())
+}
+
+func TestUpload_CancelDoesNotLeak(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	storing := make(chan struct{})
+	var once sync.Once
+
+	// Storage blocks until the client goes away, after the whole body was
+	// read and staged.
+	h := New(&mockPortService{
+		UploadBatchFunc: func(ctx context.Context, ports []*port.Port) error {
+			once.Do(func() { close(storing) })
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20, Workers: 2, BatchSize: 4})
+
+	req := httptest.NewRequest("POST", "/api/ports", strings.NewReader(portsBody(1000))).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Upload(w, req)
+	}()
+
+	<-storing
+	cancel()
+	<-done
+
+	assert.Zero(t, w.Body.Len(), "a cancelled upload gets no response")
+}
+
+func BenchmarkUpload(b *testing.B) {
+	body, err := os.ReadFile("../../../../../static/ports.json")
+	require.NoError(b, err)
+
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			svc := portService.New(portRepository.New(inmem.NewSharded[*portRepository.Port](inmem.ShardedOptions{})), nil)
+			h := New(svc, nopMetrics{}, UploadOptions{MaxBytes: 50 << 20, Workers: workers})
+
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			for b.Loop() {
+				w := httptest.NewRecorder()
+				h.Upload(w, httptest.NewRequest("POST", "/api/ports", bytes.NewReader(body)))
+				if w.Code != http.StatusOK {
+					b.Fatalf("status %d: %s", w.Code, w.Body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package port
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+	auditService "github.com/axmz/go-port-service/internal/services/audit"
+	"github.com/axmz/go-port-service/internal/transport/http/response"
+)
+
+// V2 serves version 2 of the ports API: responses aren't wrapped in an
+// envelope, some fields are renamed and lists are paginated. It maps the same
+// PortService as Handlers, which keeps serving version 1.
+type V2 struct {
+	port PortService
+}
+
+func NewV2(s PortService) *V2 {
+	return &V2{
+		port: s,
+	}
+}
+
+func (h *V2) List(w http.ResponseWriter, r *http.Request) {
+	page, err := intQuery(r, "page")
+	if err != nil {
+		response.Error(w, r, response.Invalid(err))
+		return
+	}
+	pageSize, err := intQuery(r, "page_size")
+	if err != nil {
+		response.Error(w, r, response.Invalid(err))
+		return
+	}
+	snapshot, err := snapshotQuery(r)
+	if err != nil {
+		response.Error(w, r, response.Invalid(err))
+		return
+	}
+
+	p, err := h.port.List(r.Context(), snapshot, page, pageSize)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	// A page of a snapshot never changes, whatever happened to the ports
+	// since. There is no Last-Modified, since the snapshot may be older
+	// than the latest change.
+	etag := fmt.Sprintf(`"s%s-%x-%x"`, p.Snapshot, p.Page, p.PageSize)
+	if response.NotModified(w, r, etag, time.Time{}) {
+		return
+	}
+	res := PageV2{
+		Items:    make([]PortV2, 0, len(p.Ports)),
+		Page:     p.Page,
+		PageSize: p.PageSize,
+		Total:    p.Total,
+		Snapshot: p.Snapshot.String(),
+	}
+	for _, v := range p.Ports {
+		res.Items = append(res.Items, fromDomainToV2(v))
+	}
+	response.JSON(w, http.StatusOK, res)
+}
+
+func (h *V2) Get(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if id == "" {
+		response.ProblemStatus(w, r, http.StatusBadRequest, "missing id")
+		return
+	}
+
+	etag, modified := validators(r.Context(), h.port)
+
+	if p, err := h.port.Get(r.Context(), id); err != nil {
+		response.Error(w, r, err)
+	} else if !response.NotModified(w, r, etag, modified) {
+		response.JSON(w, http.StatusOK, fromDomainToV2(p))
+	}
+}
+
+func (h *V2) Delete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if id == "" {
+		response.ProblemStatus(w, r, http.StatusBadRequest, "missing id")
+		return
+	}
+
+	if _, err := h.port.Delete(r.Context(), id); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Rename moves the port to the ID in the body. Readers see it under either
+// ID, never both or neither.
+func (h *V2) Rename(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if id == "" {
+		response.ProblemStatus(w, r, http.StatusBadRequest, "missing id")
+		return
+	}
+
+	var req RenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, response.Invalid(err))
+		return
+	}
+	if req.ID == "" {
+		response.Error(w, r, fmt.Errorf("%w: id", port.ErrRequired))
+		return
+	}
+	auditService.Annotate(r.Context(), "", "renamed to "+req.ID)
+
+	p, err := h.port.Rename(r.Context(), id, req.ID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, fromDomainToV2(p))
+}
+
+// intQuery parses an optional integer query parameter, 0 when absent.
+func intQuery(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q is not an integer", name, v)
+	}
+	return n, nil
+}
+
+// snapshotQuery parses the snapshot a client got with an earlier page, the
+// zero SnapshotID when absent.
+func snapshotQuery(r *http.Request) (port.SnapshotID, error) {
+	v := r.URL.Query().Get("snapshot")
+	if v == "" {
+		return port.SnapshotID{}, nil
+	}
+	id, err := port.ParseSnapshotID(v)
+	if err != nil || id.IsZero() {
+		return port.SnapshotID{}, fmt.Errorf("invalid snapshot: %q", v)
+	}
+	return id, nil
+}
@@ -0,0 +1,113 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+	portService "github.com/axmz/go-port-service/internal/services/port"
+)
+
+func TestV2_List(t *testing.T) {
+	p, err := port.New("ZWUTA", "Mutare", "", "Mutare", "Zimbabwe", nil, nil, []float64{32.65, -18.97}, "Manicaland", "Africa/Harare", []string{"ZWUTA"})
+	require.NoError(t, err)
+
+	var gotPage, gotSize int
+	snapshot := port.SnapshotID{Revision: 7, Modified: 0x1a2b}
+	var gotSnapshot port.SnapshotID
+	h := NewV2(&mockPortService{
+		ListFunc: func(ctx context.Context, snapshot port.SnapshotID, page, pageSize int) (*portService.Page, error) {
+			gotSnapshot, gotPage, gotSize = snapshot, page, pageSize
+			return &portService.Page{Ports: []*port.Port{p}, Page: 2, PageSize: 1, Total: 5, Snapshot: snapshot}, nil
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/api/v2/ports?page=2&page_size=1&snapshot=1a2b-7", nil)
+	w := httptest.NewRecorder()
+	h.List(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, snapshot, gotSnapshot)
+	assert.Equal(t, 2, gotPage)
+	assert.Equal(t, 1, gotSize)
+	assert.JSONEq(t, `{
+		"items": [{
+			"id": "ZWUTA", "name": "Mutare", "code": "", "city": "Mutare", "province": "Manicaland",
+			"country": "Zimbabwe", "timezone": "Africa/Harare", "aliases": [], "regions": [],
+			"unlocodes": ["ZWUTA"], "location": {"latitude": -18.97, "longitude": 32.65}
+		}],
+		"page": 2, "page_size": 1, "total": 5, "snapshot": "1a2b-7"
+	}`, w.Body.String())
+	assert.Equal(t, `"s1a2b-7-2-1"`, w.Header().Get("ETag"))
+
+	req.Header.Set("If-None-Match", `"s1a2b-7-2-1"`)
+	w = httptest.NewRecorder()
+	h.List(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
+
+func TestV2_List_InvalidPage(t *testing.T) {
+	h := NewV2(&mockPortService{})
+
+	for _, query := range []string{"page=two", "snapshot=7", "snapshot=0-0", "snapshot=1a2b-x", "snapshot=-1"} {
+		req := httptest.NewRequest("GET", "/api/v2/ports?"+query, nil)
+		w := httptest.NewRecorder()
+		h.List(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestV2_Delete(t *testing.T) {
+	h := NewV2(&mockPortService{
+		DeleteFunc: func(ctx context.Context, id string) (*port.Port, error) {
+			return port.New(id, "Mutare", "", "Mutare", "Zimbabwe", nil, nil, nil, "", "", nil)
+		},
+	})
+
+	req := httptest.NewRequest("DELETE", "/api/v2/ports/ZWUTA", nil)
+	req.SetPathValue("id", "ZWUTA")
+	w := httptest.NewRecorder()
+	h.Delete(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestV2_Rename(t *testing.T) {
+	h := NewV2(&mockPortService{
+		RenameFunc: func(ctx context.Context, id, newID string) (*port.Port, error) {
+			if newID == "ZWHRE" {
+				return nil, fmt.Errorf("%w: %s", port.ErrExists, newID)
+			}
+			return port.New(newID, "Mutare", "", "Mutare", "Zimbabwe", nil, nil, nil, "", "", nil)
+		},
+	})
+
+	for _, tt := range []struct {
+		body string
+		code int
+	}{
+		{`{"id": "ZWMUT"}`, http.StatusOK},
+		{`{"id": "ZWHRE"}`, http.StatusConflict},
+		{`{"id": ""}`, http.StatusBadRequest},
+		{`{`, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest("POST", "/api/v2/ports/ZWUTA/rename", strings.NewReader(tt.body))
+		req.SetPathValue("id", "ZWUTA")
+		w := httptest.NewRecorder()
+		h.Rename(w, req)
+
+		assert.Equal(t, tt.code, w.Code, tt.body)
+		if tt.code == http.StatusOK {
+			assert.Contains(t, w.Body.String(), `"id":"ZWMUT"`)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/axmz/go-port-service/internal/domain/session"
+	"github.com/axmz/go-port-service/internal/transport/http/response"
+)
+
+type SessionService interface {
+	List(ctx context.Context, userID string) ([]*session.Session, error)
+	Revoke(ctx context.Context, userID, id string) error
+	RevokeAll(ctx context.Context, userID string) (int, error)
+}
+
+type SessionManager interface {
+	GetString(ctx context.Context, key string) string
+	Token(ctx context.Context) string
+	Destroy(ctx context.Context) error
+}
+
+type Handlers struct {
+	sessions SessionService
+	manager  SessionManager
+}
+
+func New(s SessionService, m SessionManager) *Handlers {
+	return &Handlers{
+		sessions: s,
+		manager:  m,
+	}
+}
+
+type Response struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	RemoteAddr string    `json:"remote_addr"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+func (h *Handlers) List(w http.ResponseWriter, r *http.Request) {
+	userID := h.manager.GetString(r.Context(), session.UserIDKey)
+	if userID == "" {
+		response.ProblemStatus(w, r, http.StatusUnauthorized, "not logged in")
+		return
+	}
+
+	sessions, err := h.sessions.List(r.Context(), userID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	current := h.manager.Token(r.Context())
+	res := make([]Response, 0, len(sessions))
+	for _, s := range sessions {
+		res = append(res, Response{
+			ID:         s.ID(),
+			UserAgent:  s.UserAgent,
+			RemoteAddr: s.RemoteAddr,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.UpdatedAt,
+			ExpiresAt:  s.Expiry,
+			Current:    s.Token == current,
+		})
+	}
+	response.OK(w, res)
+}
+
+func (h *Handlers) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID := h.manager.GetString(r.Context(), session.UserIDKey)
+	if userID == "" {
+		response.ProblemStatus(w, r, http.StatusUnauthorized, "not logged in")
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		response.ProblemStatus(w, r, http.StatusBadRequest, "missing id")
+		return
+	}
+
+	if err := h.sessions.Revoke(r.Context(), userID, id); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	// Revoking the current session must not be undone by the session
+	// middleware committing it again at the end of the request.
+	if session.IDFromToken(h.manager.Token(r.Context())) == id {
+		if err := h.manager.Destroy(r.Context()); err != nil {
+			response.Error(w, r, err)
+			return
+		}
+	}
+
+	response.OK(w, "Session revoked")
+}
+
+func (h *Handlers) RevokeAll(w http.ResponseWriter, r *http.Request) {
+	userID := h.manager.GetString(r.Context(), session.UserIDKey)
+	if userID == "" {
+		response.ProblemStatus(w, r, http.StatusUnauthorized, "not logged in")
+		return
+	}
+
+	n, err := h.sessions.RevokeAll(r.Context(), userID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	if err := h.manager.Destroy(r.Context()); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.OK(w, n)
+}
@@ -1,7 +1,6 @@
 package static
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/axmz/go-port-service/internal/renderer"
@@ -18,19 +17,15 @@ func New(r *renderer.TemplateRenderer) *Handlers {
 }
 
 func (h *Handlers) Home(w http.ResponseWriter, r *http.Request) {
-	err := h.TemplateRenderer.Render(w, "home.html", nil)
+	err := h.TemplateRenderer.Render(w, r, "home.html", nil)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
 func (h *Handlers) Private(w http.ResponseWriter, r *http.Request) {
-	err := h.TemplateRenderer.Render(w, "private.html", nil)
+	err := h.TemplateRenderer.Render(w, r, "private.html", nil)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
-
-func (h *Handlers) Metrics(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintln(w, "Hello, metrics!")
-}
@@ -0,0 +1,147 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/axmz/go-port-service/internal/domain/session"
+	"github.com/axmz/go-port-service/internal/domain/user"
+	userService "github.com/axmz/go-port-service/internal/services/user"
+	"github.com/axmz/go-port-service/internal/transport/http/response"
+)
+
+type UserService interface {
+	Get(ctx context.Context, id string) (*user.User, error)
+	List(ctx context.Context, page, pageSize int) (*userService.Page, error)
+	UpdateProfile(ctx context.Context, id, displayName, email string) (*user.User, error)
+	SetRole(ctx context.Context, id string, role user.Role) (*user.User, error)
+	Disable(ctx context.Context, id string) (*user.User, error)
+	Enable(ctx context.Context, id string) (*user.User, error)
+	Delete(ctx context.Context, id string) (*user.User, error)
+}
+
+type SessionManager interface {
+	GetString(ctx context.Context, key string) string
+}
+
+type Handlers struct {
+	user    UserService
+	session SessionManager
+}
+
+func New(s UserService, sm SessionManager) *Handlers {
+	return &Handlers{
+		user:    s,
+		session: sm,
+	}
+}
+
+func (h *Handlers) Me(w http.ResponseWriter, r *http.Request) {
+	id := h.session.GetString(r.Context(), session.UserIDKey)
+	if id == "" {
+		response.ProblemStatus(w, r, http.StatusUnauthorized, "not logged in")
+		return
+	}
+
+	u, err := h.user.Get(r.Context(), id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.OK(w, fromDomainToResponse(u))
+}
+
+func (h *Handlers) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	id := h.session.GetString(r.Context(), session.UserIDKey)
+	if id == "" {
+		response.ProblemStatus(w, r, http.StatusUnauthorized, "not logged in")
+		return
+	}
+
+	var req ProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, response.Invalid(err))
+		return
+	}
+
+	u, err := h.user.UpdateProfile(r.Context(), id, req.DisplayName, req.Email)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.OK(w, fromDomainToResponse(u))
+}
+
+func (h *Handlers) List(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+
+	p, err := h.user.List(r.Context(), page, pageSize)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	res := PageResponse{
+		Items:    make([]Response, 0, len(p.Users)),
+		Page:     p.Page,
+		PageSize: p.PageSize,
+		Total:    p.Total,
+	}
+	for _, u := range p.Users {
+		res.Items = append(res.Items, fromDomainToResponse(u))
+	}
+	response.OK(w, res)
+}
+
+func (h *Handlers) Get(w http.ResponseWriter, r *http.Request) {
+	h.byID(w, r, h.user.Get)
+}
+
+func (h *Handlers) Disable(w http.ResponseWriter, r *http.Request) {
+	h.byID(w, r, h.user.Disable)
+}
+
+func (h *Handlers) Enable(w http.ResponseWriter, r *http.Request) {
+	h.byID(w, r, h.user.Enable)
+}
+
+func (h *Handlers) Delete(w http.ResponseWriter, r *http.Request) {
+	h.byID(w, r, h.user.Delete)
+}
+
+func (h *Handlers) SetRole(w http.ResponseWriter, r *http.Request) {
+	var req RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, response.Invalid(err))
+		return
+	}
+
+	role, err := user.ParseRole(req.Role)
+	if err != nil {
+		response.Error(w, r, response.Invalid(err))
+		return
+	}
+
+	h.byID(w, r, func(ctx context.Context, id string) (*user.User, error) {
+		return h.user.SetRole(ctx, id, role)
+	})
+}
+
+func (h *Handlers) byID(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, id string) (*user.User, error)) {
+	id := r.PathValue("id")
+
+	if id == "" {
+		response.ProblemStatus(w, r, http.StatusBadRequest, "missing id")
+		return
+	}
+
+	u, err := fn(r.Context(), id)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.OK(w, fromDomainToResponse(u))
+}
@@ -0,0 +1,27 @@
+package user
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/axmz/go-port-service/internal/domain/user"
+)
+
+func fromDomainToResponse(u *user.User) Response {
+	suspicious := make([]string, 0)
+	for _, id := range u.SuspiciousCredentials() {
+		suspicious = append(suspicious, base64.RawURLEncoding.EncodeToString(id))
+	}
+	return Response{
+		ID:                    string(u.ID),
+		Name:                  u.Name,
+		DisplayName:           u.DisplayName,
+		Email:                 u.Email,
+		Role:                  string(u.Role),
+		Status:                string(u.Status(time.Now())),
+		Credentials:           len(u.WebAuthnCredentials()),
+		SuspiciousCredentials: suspicious,
+		CreatedAt:             u.CreatedAt,
+		UpdatedAt:             u.UpdatedAt,
+	}
+}
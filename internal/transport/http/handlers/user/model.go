@@ -0,0 +1,34 @@
+package user
+
+import "time"
+
+type Response struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+	Role        string `json:"role"`
+	Status      string `json:"status"`
+	Credentials int    `json:"credentials"`
+	// SuspiciousCredentials are the base64url IDs of passkeys flagged as
+	// possibly cloned under the flag clone policy.
+	SuspiciousCredentials []string  `json:"suspicious_credentials"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+type PageResponse struct {
+	Items    []Response `json:"items"`
+	Page     int        `json:"page"`
+	PageSize int        `json:"page_size"`
+	Total    int        `json:"total"`
+}
+
+type ProfileRequest struct {
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+}
+
+type RoleRequest struct {
+	Role string `json:"role"`
+}
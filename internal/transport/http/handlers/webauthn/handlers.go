@@ -5,7 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/axmz/go-port-service/internal/domain/security"
+	domainSession "github.com/axmz/go-port-service/internal/domain/session"
+	auditService "github.com/axmz/go-port-service/internal/services/audit"
 	"github.com/axmz/go-port-service/internal/transport/http/response"
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
@@ -14,16 +18,19 @@ import (
 const WebauthSessionKey = "webauthn_session"
 
 type WebAuthnService interface {
-	BeginRegistration(ctx context.Context, userID string) (*protocol.CredentialCreation, *webauthn.SessionData, error)
-	FinishRegistration(ctx context.Context, session webauthn.SessionData, r *http.Request) error
+	BeginRegistration(ctx context.Context, userID, currentUserID string) (*protocol.CredentialCreation, *webauthn.SessionData, error)
+	FinishRegistration(ctx context.Context, session webauthn.SessionData, currentUserID string, r *http.Request) (bool, error)
 	BeginLogin(ctx context.Context, userID string) (*protocol.CredentialAssertion, *webauthn.SessionData, error)
 	FinishLogin(ctx context.Context, session webauthn.SessionData, r *http.Request) error
+	Unlock(ctx context.Context, userID string) error
+	SecurityEvents(ctx context.Context, userID string) ([]*security.Event, error)
 }
 
 type SessionManager interface {
 	Put(ctx context.Context, key string, data any)
 	Get(ctx context.Context, key string) any
 	Remove(ctx context.Context, key string)
+	RenewToken(ctx context.Context) error
 }
 
 type Handlers struct {
@@ -53,13 +60,14 @@ func getUserID(r *http.Request) (string, error) {
 func (h *Handlers) BeginRegistration(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserID(r)
 	if err != nil {
-		panic(err) // FIXME: handle error
+		response.Error(w, r, response.Invalid(err))
+		return
 	}
+	auditService.Annotate(r.Context(), userID, "")
 
-	options, session, err := h.webauthn.BeginRegistration(r.Context(), userID)
+	options, session, err := h.webauthn.BeginRegistration(r.Context(), userID, h.currentUserID(r))
 	if err != nil {
-		msg := fmt.Sprintf("can't begin registration: %s", err.Error())
-		response.BadRequest(w, msg)
+		response.Error(w, r, response.Invalid(fmt.Errorf("can't begin registration: %w", err)))
 		return
 	}
 
@@ -72,28 +80,44 @@ func (h *Handlers) BeginRegistration(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) FinishRegistration(w http.ResponseWriter, r *http.Request) {
-	session := h.session.Get(r.Context(), WebauthSessionKey).(webauthn.SessionData)
+	session, ok := h.session.Get(r.Context(), WebauthSessionKey).(webauthn.SessionData)
+	if !ok {
+		response.ProblemStatus(w, r, http.StatusBadRequest, "no registration in progress")
+		return
+	}
 
-	err := h.webauthn.FinishRegistration(r.Context(), session, r)
+	auditService.Annotate(r.Context(), string(session.UserID), "")
+
+	created, err := h.webauthn.FinishRegistration(r.Context(), session, h.currentUserID(r), r)
 	if err != nil {
-		msg := fmt.Sprintf("can't finish registration: %s", err.Error())
-		response.BadRequest(w, msg)
+		auditService.Annotate(r.Context(), "", err.Error())
+		response.Error(w, r, response.Invalid(fmt.Errorf("can't finish registration: %w", err)))
 		return
 	}
 
+	// Only a new account logs in by registering; a logged-in user adding an
+	// authenticator keeps the session it has.
+	if created {
+		if err := h.startUserSession(r, string(session.UserID)); err != nil {
+			response.Error(w, r, err)
+			return
+		}
+	}
+
 	response.OK(w, "Registration Success")
 }
 
 func (h *Handlers) BeginLogin(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserID(r)
 	if err != nil {
-		panic(err) // FIXME: handle error
+		response.Error(w, r, response.Invalid(err))
+		return
 	}
+	auditService.Annotate(r.Context(), userID, "")
 
 	options, session, err := h.webauthn.BeginLogin(r.Context(), userID)
 	if err != nil {
-		msg := fmt.Sprintf("can't begin login: %s", err.Error())
-		response.BadRequest(w, msg)
+		response.Error(w, r, response.Invalid(fmt.Errorf("can't begin login: %w", err)))
 		return
 	}
 
@@ -106,21 +130,101 @@ func (h *Handlers) BeginLogin(w http.ResponseWriter, r *http.Request) {
 
 }
 func (h *Handlers) FinishLogin(w http.ResponseWriter, r *http.Request) {
-	session := h.session.Get(r.Context(), WebauthSessionKey).(webauthn.SessionData)
+	session, ok := h.session.Get(r.Context(), WebauthSessionKey).(webauthn.SessionData)
+	if !ok {
+		response.ProblemStatus(w, r, http.StatusBadRequest, "no login in progress")
+		return
+	}
+
+	auditService.Annotate(r.Context(), string(session.UserID), "")
 
 	err := h.webauthn.FinishLogin(r.Context(), session, r)
 	if err != nil {
-		msg := fmt.Sprintf("can't finish registration: %s", err.Error())
-		response.BadRequest(w, msg)
+		auditService.Annotate(r.Context(), "", err.Error())
+		response.Error(w, r, response.Invalid(fmt.Errorf("can't finish login: %w", err)))
 		return
 	}
 
-	response.OK(w, "Registration Success")
+	if err := h.startUserSession(r, string(session.UserID)); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.OK(w, "Login Success")
 }
 
 func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
 	// Remove the webauthn session data
 	h.session.Remove(r.Context(), WebauthSessionKey)
+	h.session.Remove(r.Context(), domainSession.UserIDKey)
 
 	response.OK(w, "Logged out")
 }
+
+// currentUserID returns the id of the logged-in user, if any.
+func (h *Handlers) currentUserID(r *http.Request) string {
+	id, _ := h.session.Get(r.Context(), domainSession.UserIDKey).(string)
+	return id
+}
+
+// startUserSession binds the session to the authenticated user. The token is
+// renewed to prevent session fixation.
+func (h *Handlers) startUserSession(r *http.Request, userID string) error {
+	if err := h.session.RenewToken(r.Context()); err != nil {
+		return err
+	}
+	h.session.Put(r.Context(), domainSession.UserIDKey, userID)
+	h.session.Put(r.Context(), domainSession.UserAgentKey, r.UserAgent())
+	h.session.Put(r.Context(), domainSession.RemoteAddrKey, r.RemoteAddr)
+	return nil
+}
+
+func (h *Handlers) Unlock(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if id == "" {
+		response.ProblemStatus(w, r, http.StatusBadRequest, "missing id")
+		return
+	}
+
+	if err := h.webauthn.Unlock(r.Context(), id); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.OK(w, "Unlocked")
+}
+
+type SecurityEvent struct {
+	ID                string    `json:"id"`
+	Type              string    `json:"type"`
+	UserID            string    `json:"user_id"`
+	CredentialID      string    `json:"credential_id,omitempty"`
+	StoredSignCount   uint32    `json:"stored_sign_count"`
+	ReceivedSignCount uint32    `json:"received_sign_count"`
+	Detail            string    `json:"detail,omitempty"`
+	Time              time.Time `json:"time"`
+}
+
+func (h *Handlers) SecurityEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := h.webauthn.SecurityEvents(r.Context(), r.URL.Query().Get("user_id"))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	res := make([]SecurityEvent, 0, len(events))
+	for _, e := range events {
+		res = append(res, SecurityEvent{
+			ID:                e.ID,
+			Type:              string(e.Type),
+			UserID:            e.UserID,
+			CredentialID:      e.CredentialID,
+			StoredSignCount:   e.StoredSignCount,
+			ReceivedSignCount: e.ReceivedSignCount,
+			Detail:            e.Detail,
+			Time:              e.Time,
+		})
+	}
+	response.OK(w, res)
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	var got *http.Request
+	h := APIKeyAuth(map[string]string{"billing": "0123456789abcdef"}, "session",
+		RejectInvalidAPIKey(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = r })))
+
+	do := func(header, value string) *httptest.ResponseRecorder {
+		got = nil
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(&http.Cookie{Name: "session", Value: "s"})
+		r.AddCookie(&http.Cookie{Name: "other", Value: "o"})
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, do("", "").Code)
+	assert.Empty(t, APIClient(got.Context()))
+	_, err := got.Cookie("session")
+	assert.NoError(t, err, "requests without a key keep their session")
+
+	for _, tt := range []struct{ header, value string }{
+		{APIKeyHeader, "0123456789abcdef"},
+		{"Authorization", "Bearer 0123456789abcdef"},
+		{"Authorization", "bearer 0123456789abcdef"},
+	} {
+		assert.Equal(t, http.StatusOK, do(tt.header, tt.value).Code, tt.value)
+		assert.Equal(t, "billing", APIClient(got.Context()))
+		_, err := got.Cookie("session")
+		assert.ErrorIs(t, err, http.ErrNoCookie, "the session is ignored")
+		_, err = got.Cookie("other")
+		assert.NoError(t, err)
+	}
+
+	w := do(APIKeyHeader, "0123456789abcdeX")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Nil(t, got)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/alexedwards/scs/v2"
+
+	"github.com/axmz/go-port-service/internal/domain/audit"
+	domainSession "github.com/axmz/go-port-service/internal/domain/session"
+	auditService "github.com/axmz/go-port-service/internal/services/audit"
+)
+
+type AuditRecorder interface {
+	Record(ctx context.Context, e *audit.Entry)
+}
+
+// Audit records the outcome of the wrapped handler as action. The target
+// defaults to the "id" path value; handlers can refine it with
+// auditService.Annotate. Responses below 400 count as success.
+func Audit(rec AuditRecorder, session *scs.SessionManager, action audit.Action, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		e := &audit.Entry{
+			Actor:      session.GetString(ctx, domainSession.UserIDKey),
+			Action:     action,
+			Target:     r.PathValue("id"),
+			RequestID:  GetReqID(ctx),
+			RemoteAddr: r.RemoteAddr,
+			UserAgent:  r.UserAgent(),
+		}
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(auditService.WithEntry(ctx, e)))
+
+		// A ceremony that just logged the user in is attributed to them.
+		if e.Actor == "" {
+			e.Actor = session.GetString(ctx, domainSession.UserIDKey)
+		}
+		if e.Actor == "" {
+			e.Actor = audit.Anonymous
+		}
+
+		e.Status = sw.Status()
+		e.Outcome = audit.OutcomeSuccess
+		if e.Status >= http.StatusBadRequest {
+			e.Outcome = audit.OutcomeFailure
+		}
+
+		rec.Record(ctx, e)
+	})
+}
+
+// statusWriter remembers the status code and the number of bytes written.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,296 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DefaultCompressMinSize is the smallest body worth compressing.
+const DefaultCompressMinSize = 1024
+
+type encoder struct {
+	name string
+	pool *sync.Pool
+}
+
+// encoders are listed in order of preference for equally weighted codings.
+var encoders = []encoder{
+	{"br", &sync.Pool{New: func() any { return brotli.NewWriterLevel(nil, brotli.DefaultCompression) }}},
+	{"gzip", &sync.Pool{New: func() any { return gzip.NewWriter(nil) }}},
+}
+
+type compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+	Flush() error
+}
+
+var compressibleTypes = []string{
+	"application/json",
+	"application/problem+json",
+	"application/x-ndjson",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+func compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mt, "text/") {
+		return true
+	}
+	for _, t := range compressibleTypes {
+		if mt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Compress negotiates a content coding from Accept-Encoding and compresses
+// text-like responses of at least minSize bytes. Strong ETags get the coding
+// appended, as two encodings are different representations, and the suffix is
+// removed from If-None-Match before the handler compares validators. A 304
+// gets back the suffix of the tag the client sent, so that it still names the
+// representation the client has.
+func Compress(minSize int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		enc, ok := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		// Byte ranges refer to the identity representation.
+		if !ok || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var sent map[string]string
+		if inm := r.Header.Get("If-None-Match"); inm != "" {
+			var stripped string
+			stripped, sent = stripETagSuffixes(inm)
+			r.Header.Set("If-None-Match", stripped)
+		}
+
+		cw := &compressWriter{ResponseWriter: w, enc: enc, minSize: minSize, sent: sent}
+		defer cw.Close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks the acceptable coding with the highest q-value.
+// Codings listed explicitly take precedence over "*".
+func negotiateEncoding(header string) (encoder, bool) {
+	q := make([]float64, len(encoders))
+	explicit := make([]bool, len(encoders))
+	for i := range q {
+		q[i] = -1
+	}
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		weight := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		for i, e := range encoders {
+			switch {
+			case strings.EqualFold(name, e.name):
+				q[i], explicit[i] = weight, true
+			case name == "*" && !explicit[i]:
+				q[i] = weight
+			}
+		}
+	}
+
+	best := -1
+	for i := range encoders {
+		if q[i] > 0 && (best < 0 || q[i] > q[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return encoder{}, false
+	}
+	return encoders[best], true
+}
+
+// stripETagSuffixes removes the coding suffixes from the tags of an
+// If-None-Match header. It also returns the coding each stripped tag had.
+func stripETagSuffixes(header string) (string, map[string]string) {
+	sent := make(map[string]string)
+	tags := strings.Split(header, ",")
+	for i, t := range tags {
+		t = strings.TrimSpace(t)
+		for _, e := range encoders {
+			if stripped, ok := strings.CutSuffix(t, "-"+e.name+`"`); ok {
+				t = stripped + `"`
+				sent[t] = e.name
+				break
+			}
+		}
+		tags[i] = t
+	}
+	return strings.Join(tags, ", "), sent
+}
+
+// compressWriter buffers the first minSize bytes to decide whether the
+// response is worth compressing.
+type compressWriter struct {
+	http.ResponseWriter
+	enc     encoder
+	minSize int
+	// sent maps the If-None-Match tags to the coding they were sent with.
+	sent map[string]string
+
+	status   int
+	buf      []byte
+	decided  bool
+	compress compressor
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	// Informational, empty and not-modified responses pass straight through.
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.minSize && !w.knownLarge() {
+			return len(b), nil
+		}
+		w.decide(w.eligible())
+		return len(b), w.flushBuffer()
+	}
+	if w.compress != nil {
+		return w.compress.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// knownLarge reports whether a declared Content-Length already exceeds minSize.
+func (w *compressWriter) knownLarge() bool {
+	n, err := strconv.Atoi(w.Header().Get("Content-Length"))
+	return err == nil && n >= w.minSize
+}
+
+func (w *compressWriter) eligible() bool {
+	h := w.Header()
+	return h.Get("Content-Encoding") == "" &&
+		w.status != http.StatusPartialContent &&
+		compressible(h.Get("Content-Type"))
+}
+
+func (w *compressWriter) decide(compress bool) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	h := w.Header()
+	if w.status == http.StatusNotModified {
+		if coding, ok := w.sent[h.Get("ETag")]; ok {
+			setETagSuffix(h, coding)
+		}
+	}
+	if compress {
+		h.Set("Content-Encoding", w.enc.name)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		setETagSuffix(h, w.enc.name)
+		w.compress = w.enc.pool.Get().(compressor)
+		w.compress.Reset(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// setETagSuffix appends the coding to a strong ETag.
+func setETagSuffix(h http.Header, coding string) {
+	if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		h.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+coding+`"`)
+	}
+}
+
+func (w *compressWriter) flushBuffer() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	var err error
+	if w.compress != nil {
+		_, err = w.compress.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Close writes out anything still buffered and finishes the compressed stream.
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		// The whole body fit below minSize.
+		w.decide(false)
+	}
+	err := w.flushBuffer()
+	if w.compress != nil {
+		err = errors.Join(err, w.compress.Close())
+		w.compress.Reset(nil)
+		w.enc.pool.Put(w.compress)
+		w.compress = nil
+	}
+	return err
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.eligible())
+	}
+	_ = w.flushBuffer()
+	if w.compress != nil {
+		_ = w.compress.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, *", "gzip"},
+		{"*;q=0", ""},
+	}
+	for _, tt := range tests {
+		enc, ok := negotiateEncoding(tt.header)
+		assert.Equal(t, tt.want != "", ok, tt.header)
+		assert.Equal(t, tt.want, enc.name, tt.header)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat(`{"name":"Ajman"}`, 200)
+
+	h := Compress(DefaultCompressMinSize, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{}`))
+		case "/binary":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte(body))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"v1"`)
+			if strings.Contains(r.Header.Get("If-None-Match"), `"v1"`) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			// Written in chunks smaller than the threshold.
+			for i := 0; i < len(body); i += 100 {
+				_, _ = w.Write([]byte(body[i:min(i+100, len(body))]))
+			}
+		}
+	}))
+
+	get := func(path, encoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept-Encoding", encoding)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("gzip", func(t *testing.T) {
+		w := get("/", "gzip")
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, `"v1-gzip"`, w.Header().Get("ETag"))
+		assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+		zr, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		got, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(got))
+	})
+
+	t.Run("brotli", func(t *testing.T) {
+		w := get("/", "gzip, br")
+		assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+		got, err := io.ReadAll(brotli.NewReader(w.Body))
+		require.NoError(t, err)
+		assert.Equal(t, body, string(got))
+	})
+
+	t.Run("identity", func(t *testing.T) {
+		w := get("/", "")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+		assert.Equal(t, body, w.Body.String())
+	})
+
+	t.Run("small and incompressible bodies are left alone", func(t *testing.T) {
+		w := get("/small", "gzip")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, `{}`, w.Body.String())
+
+		w = get("/binary", "gzip")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, body, w.Body.String())
+	})
+
+	t.Run("encoded etag revalidates", func(t *testing.T) {
+		for _, tt := range []struct{ encoding, sent string }{
+			{"gzip", `"v1-gzip"`},
+			{"br", `"v1-br"`},
+			{"gzip", `"v1"`},
+			{"gzip", `"v0-gzip", "v1-br"`},
+		} {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", tt.encoding)
+			r.Header.Set("If-None-Match", tt.sent)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			assert.Equal(t, http.StatusNotModified, w.Code, tt.sent)
+			assert.Empty(t, w.Body.Bytes())
+			want := tt.sent[strings.LastIndex(tt.sent, `"v1`):]
+			assert.Equal(t, want, w.Header().Get("ETag"), "the 304 names the representation the client has")
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy describes which cross-origin requests browsers may make.
+type CORSPolicy struct {
+	// AllowedOrigins may be "*" or contain a single "*" wildcard,
+	// e.g. "https://*.example.com".
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+func (p *CORSPolicy) allowOrigin(origin string) bool {
+	for _, o := range p.AllowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(o, "*"); ok &&
+			len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) allowHeader(h string) bool {
+	return slices.ContainsFunc(p.AllowedHeaders, func(a string) bool {
+		return a == "*" || strings.EqualFold(a, h)
+	})
+}
+
+// CORS applies the policy returned for the request, if any. Preflight
+// requests are answered here, before they reach the router, because method
+// specific patterns such as "POST /api/ports" never match OPTIONS; the allowed
+// methods are the policy's methods that router has a pattern for.
+func CORS(router Router, policy func(r *http.Request) *CORSPolicy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := policy(r)
+		origin := r.Header.Get("Origin")
+		if p == nil || len(p.AllowedOrigins) == 0 || origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if !p.allowOrigin(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// A policy open to any origin never allows credentials: echoing the
+		// origin with them would let every site read responses on behalf of
+		// the logged in user. Configuration rejects the combination anyway.
+		if slices.Contains(p.AllowedOrigins, "*") {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+			if p.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if !preflight {
+			if len(p.ExposedHeaders) > 0 {
+				h.Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		methods := routeMethods(router, r, p.AllowedMethods)
+		if len(methods) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		if !slices.Contains(methods, r.Header.Get("Access-Control-Request-Method")) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var headers []string
+		for _, hdr := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+			if hdr = strings.TrimSpace(hdr); hdr == "" {
+				continue
+			}
+			if !p.allowHeader(hdr) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			headers = append(headers, hdr)
+		}
+
+		h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if len(headers) > 0 {
+			h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+		}
+		if p.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// routeMethods returns the candidate methods router has a pattern for at r's
+// path. The catch-all "/" pattern matches everything and is ignored.
+func routeMethods(router Router, r *http.Request, candidates []string) []string {
+	var methods []string
+	for _, m := range candidates {
+		probe := r.Clone(r.Context())
+		probe.Method = m
+		if _, pattern := router.Handler(probe); pattern != "" && pattern != "/" {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
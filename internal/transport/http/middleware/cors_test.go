@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	mux := http.NewServeMux()
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	mux.HandleFunc("POST /api/ports", noop)
+	mux.HandleFunc("GET /api/ports/{id}", noop)
+	mux.HandleFunc("DELETE /api/ports/{id}", noop)
+
+	policy := &CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.preview.example.com"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           time.Minute,
+	}
+	h := CORS(mux, func(*http.Request) *CORSPolicy { return policy }, mux)
+
+	preflight := func(origin, path, method, headers string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodOptions, path, nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			r.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("preflight for method specific pattern", func(t *testing.T) {
+		w := preflight("https://app.example.com", "/api/ports/AEAJM", "DELETE", "content-type, x-csrf-token")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "GET, DELETE", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "content-type, x-csrf-token", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "60", w.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("wildcard origin", func(t *testing.T) {
+		w := preflight("https://pr-1.preview.example.com", "/api/ports", "POST", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://pr-1.preview.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("rejects disallowed preflights", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, preflight("https://evil.example.com", "/api/ports", "POST", "").Code)
+		assert.Equal(t, http.StatusForbidden, preflight("https://app.example.com", "/api/ports", "PUT", "").Code)
+		assert.Equal(t, http.StatusForbidden, preflight("https://app.example.com", "/api/ports", "POST", "X-Other").Code)
+		assert.Equal(t, http.StatusNotFound, preflight("https://app.example.com", "/api/unknown", "GET", "").Code)
+	})
+
+	t.Run("actual request", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/ports/AEAJM", nil)
+		r.Header.Set("Origin", "https://app.example.com")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "X-Request-Id", w.Header().Get("Access-Control-Expose-Headers"))
+		assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+		r.Header.Set("Origin", "https://evil.example.com")
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestCORS_AnyOrigin(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /query", func(w http.ResponseWriter, r *http.Request) {})
+	policy := &CORSPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}}
+	h := CORS(mux, func(*http.Request) *CORSPolicy { return policy }, mux)
+
+	r := httptest.NewRequest(http.MethodGet, "/query", nil)
+	r.Header.Set("Origin", "https://anywhere.example.com")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+
+	policy.AllowCredentials = true
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"), "the origin is never echoed")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
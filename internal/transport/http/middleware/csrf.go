@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/alexedwards/scs/v2"
+
+	"github.com/axmz/go-port-service/internal/logger"
+	"github.com/axmz/go-port-service/internal/transport/http/response"
+)
+
+var (
+	CSRFHeader          = "X-CSRF-Token"
+	CSRFCookie          = "csrf_token"
+	CSRFFormField       = "csrf_token"
+	CSRFProtectedPrefix = "/api/"
+)
+
+const csrfSessionKey = "csrf_token"
+
+type csrfCtxType int
+
+const (
+	CSRFTokenKey csrfCtxType = 0
+)
+
+// CSRF issues a per-session token and rejects unsafe requests under
+// CSRFProtectedPrefix that don't echo it back in CSRFHeader or CSRFFormField.
+// The token is handed to JS through CSRFCookie and the response header, and to
+// templates through CSRFToken. It is only created, and with it the session,
+// for the HTML pages page reports and for unsafe API requests, so that probes,
+// scrapes and assets don't fill the session store. Requests authenticated by
+// an API key are exempt: APIKeyAuth has dropped their session cookie, so a
+// forged request can't act for a logged in user.
+func CSRF(session *scs.SessionManager, secure bool, page func(r *http.Request) bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		check := requiresCSRFCheck(r)
+
+		token := session.GetString(ctx, csrfSessionKey)
+		if token == "" && (check || page(r)) {
+			var err error
+			if token, err = newCSRFToken(); err != nil {
+				response.Error(w, r, err)
+				return
+			}
+			session.Put(ctx, csrfSessionKey, token)
+		}
+
+		if token != "" {
+			if c, err := r.Cookie(CSRFCookie); err != nil || c.Value != token {
+				http.SetCookie(w, &http.Cookie{
+					Name:     CSRFCookie,
+					Value:    token,
+					Path:     "/",
+					Secure:   secure,
+					SameSite: http.SameSiteStrictMode,
+				})
+			}
+			w.Header().Set(CSRFHeader, token)
+			ctx = context.WithValue(ctx, CSRFTokenKey, token)
+		}
+
+		if check && !validCSRFToken(r, token) {
+			logger.FromContext(ctx).Warn("csrf token mismatch",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+			)
+			response.WriteProblem(w, r, &response.Problem{
+				Type:   response.TypeInvalidCSRFToken,
+				Title:  "Invalid CSRF token",
+				Status: http.StatusForbidden,
+				Detail: "the " + CSRFHeader + " header or " + CSRFFormField + " form field must match the session's token",
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// CSRFToken returns the token issued for the request by the CSRF middleware.
+func CSRFToken(r *http.Request) string {
+	if token, ok := r.Context().Value(CSRFTokenKey).(string); ok {
+		return token
+	}
+	return ""
+}
+
+func requiresCSRFCheck(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	return strings.HasPrefix(r.URL.Path, CSRFProtectedPrefix) && APIClient(r.Context()) == ""
+}
+
+func validCSRFToken(r *http.Request, token string) bool {
+	got := r.Header.Get(CSRFHeader)
+	if got == "" {
+		got = r.PostFormValue(CSRFFormField)
+	}
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
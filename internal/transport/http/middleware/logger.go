@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/axmz/go-port-service/internal/logger"
+)
+
+const redacted = "[REDACTED]"
+
+type HTTPMetrics interface {
+	InFlight(route string) func()
+	ObserveHTTP(route, method string, status int, d time.Duration)
+}
+
+// AccessLogOptions controls what the access log records.
+type AccessLogOptions struct {
+	// SampleRate is the fraction of successful, fast requests that are logged.
+	// Failed requests and those slower than SlowThreshold are always logged.
+	SampleRate    float64
+	SlowThreshold time.Duration
+	// RedactHeaders lists request headers whose values are never logged.
+	RedactHeaders []string
+}
+
+// Logger stores a request-scoped logger carrying the request, trace and user
+// IDs in the context and writes one access log line per request.
+func Logger(log *slog.Logger, router Router, m HTTPMetrics, userID func(ctx context.Context) string, opts AccessLogOptions, next http.Handler) http.Handler {
+	redact := make(map[string]bool, len(opts.RedactHeaders))
+	for _, h := range opts.RedactHeaders {
+		redact[http.CanonicalHeaderKey(h)] = true
+	}
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := r.Context()
+		_, route := router.Handler(r)
+
+		reqLog := log.With(slog.String("req_id", GetReqID(ctx)))
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			reqLog = reqLog.With(slog.String("trace_id", sc.TraceID().String()))
+		}
+		uid := userID(ctx)
+		if uid != "" {
+			reqLog = reqLog.With(slog.String("user_id", uid))
+		}
+
+		if reqLog.Enabled(ctx, slog.LevelDebug) {
+			reqLog.Debug("started request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				headerAttrs(r.Header, redact),
+			)
+		}
+
+		done := m.InFlight(route)
+		sw := &statusWriter{ResponseWriter: w}
+
+		defer func() {
+			done()
+			latency := time.Since(start)
+			status := sw.Status()
+			m.ObserveHTTP(route, r.Method, status, latency)
+
+			if !sampled(status, latency, opts) {
+				return
+			}
+
+			attrs := []any{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Int("bytes", sw.bytes),
+				slog.Duration("latency", latency),
+				slog.String("remote", r.RemoteAddr),
+				slog.String("agent", r.UserAgent()),
+			}
+			// The request may have logged the user in or out.
+			if after := userID(ctx); after != uid && after != "" {
+				attrs = append(attrs, slog.String("user_id", after))
+			}
+
+			reqLog.Log(ctx, accessLevel(status), "completed request", attrs...)
+		}()
+
+		next.ServeHTTP(sw, r.WithContext(logger.WithContext(ctx, reqLog)))
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+func sampled(status int, latency time.Duration, opts AccessLogOptions) bool {
+	if status >= http.StatusBadRequest {
+		return true
+	}
+	if opts.SlowThreshold > 0 && latency >= opts.SlowThreshold {
+		return true
+	}
+	return opts.SampleRate >= 1 || rand.Float64() < opts.SampleRate
+}
+
+func accessLevel(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func headerAttrs(h http.Header, redact map[string]bool) slog.Attr {
+	attrs := make([]any, 0, len(h))
+	for k, v := range h {
+		val := strings.Join(v, ", ")
+		if redact[k] {
+			val = redacted
+		}
+		attrs = append(attrs, slog.String(k, val))
+	}
+	return slog.Group("headers", attrs...)
+}
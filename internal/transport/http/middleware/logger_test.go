@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axmz/go-port-service/internal/logger"
+)
+
+type nopMetrics struct{}
+
+func (nopMetrics) InFlight(string) func()                         { return func() {} }
+func (nopMetrics) ObserveHTTP(string, string, int, time.Duration) {}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var m map[string]any
+		require.NoError(t, dec.Decode(&m))
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/ports/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info("from handler")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("missing"))
+	})
+
+	userID := func(context.Context) string { return "alice" }
+	opts := AccessLogOptions{SampleRate: 0, RedactHeaders: []string{"authorization"}}
+	h := RequestID(Logger(log, mux, nopMetrics{}, userID, opts, mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ports/AEAJM", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.Header.Set("Authorization", "Bearer secret")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 3, "failed requests are logged despite sampling")
+
+	started := lines[0]
+	headers := started["headers"].(map[string]any)
+	assert.Equal(t, redacted, headers["Authorization"])
+	assert.NotContains(t, buf.String(), "secret")
+
+	handler := lines[1]
+	assert.Equal(t, "from handler", handler["msg"])
+	assert.Equal(t, "req-1", handler["req_id"])
+	assert.Equal(t, "alice", handler["user_id"])
+
+	access := lines[2]
+	assert.Equal(t, "WARN", access["level"])
+	assert.Equal(t, "GET /api/ports/{id}", access["route"])
+	assert.EqualValues(t, http.StatusNotFound, access["status"])
+	assert.EqualValues(t, len("missing"), access["bytes"])
+	assert.Contains(t, access, "latency")
+}
+
+func TestLogger_Sampling(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {})
+
+	userID := func(context.Context) string { return "" }
+	h := Logger(log, mux, nopMetrics{}, userID, AccessLogOptions{SampleRate: 0}, mux)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, buf.String())
+
+	h = Logger(log, mux, nopMetrics{}, userID, AccessLogOptions{SampleRate: 1}, mux)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Len(t, decodeLines(t, &buf), 1)
+}
@@ -2,20 +2,21 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"runtime/debug"
 	"sync/atomic"
-	"time"
 
 	"github.com/alexedwards/scs/v2"
-	wah "github.com/axmz/go-port-service/internal/transport/http/handlers/webauthn"
-	"github.com/go-webauthn/webauthn/webauthn"
+	domainSession "github.com/axmz/go-port-service/internal/domain/session"
+	"github.com/axmz/go-port-service/internal/logger"
+	"github.com/axmz/go-port-service/internal/transport/http/response"
 )
 
 var (
-	RequestIDHeader = "X-Request-Id"
+	RequestIDHeader = response.RequestIDHeader
 	reqid           uint64
 )
 
@@ -34,6 +35,7 @@ func RequestID(next http.Handler) http.Handler {
 			requestID = fmt.Sprintf("%06d", id)
 		}
 		ctx = context.WithValue(ctx, RequestIDKey, requestID)
+		w.Header().Set(RequestIDHeader, requestID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 	return http.HandlerFunc(fn)
@@ -49,50 +51,23 @@ func GetReqID(ctx context.Context) string {
 	return ""
 }
 
-func Logger(next http.Handler) http.Handler {
-	fn := func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		reqID := GetReqID(r.Context())
-
-		slog.With(
-			slog.String("method", r.Method),
-			slog.String("path", r.URL.Path),
-			slog.String("remote", r.RemoteAddr),
-			slog.String("agent", r.UserAgent()),
-			slog.String("req_id", reqID),
-		).Info("started request")
-
-		defer func() {
-			slog.With(
-				slog.String("req_id", reqID),
-				slog.String("duration", time.Since(start).String()),
-			).Info("completed request")
-		}()
-
-		next.ServeHTTP(w, r)
-	}
-
-	return http.HandlerFunc(fn)
+// Router resolves the pattern a request will be routed to. *http.ServeMux implements it.
+type Router interface {
+	Handler(r *http.Request) (h http.Handler, pattern string)
 }
 
 func Recoverer(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if rec := recover(); rec != nil {
-				reqID := GetReqID(r.Context())
-
-				slog.Error("panic recovered",
+				logger.FromContext(r.Context()).Error("panic recovered",
 					slog.Any("error", rec),
 					slog.String("stack", string(debug.Stack())),
-					slog.String("req_id", reqID),
 					slog.String("method", r.Method),
 					slog.String("path", r.URL.Path),
 				)
 
-				http.Error(
-					w,
-					http.StatusText(http.StatusInternalServerError),
-					http.StatusInternalServerError)
+				response.ProblemStatus(w, r, http.StatusInternalServerError, "")
 			}
 		}()
 
@@ -100,23 +75,74 @@ func Recoverer(next http.Handler) http.Handler {
 	})
 }
 
+// LoggedInMiddleware lets through requests whose session has finished a
+// login. The WebAuthn session data doesn't count: BeginLogin stores it, with
+// the user ID, before any credential is verified.
 func LoggedInMiddleware(session *scs.SessionManager, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		s, ok := session.Get(r.Context(), wah.WebauthSessionKey).(webauthn.SessionData)
-		if !ok {
-			slog.Error("session not found",
-				slog.String("req_id", GetReqID(r.Context())),
+		if session.GetString(r.Context(), domainSession.UserIDKey) == "" {
+			logger.FromContext(r.Context()).Error("session not found",
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
 			)
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		if s.UserID == nil { // check expired?
-			// Option 1: return 401 Unauthorized
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			// Option 2: redirect to login page
-			// http.Redirect(w, r, "/login", http.StatusSeeOther)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminMiddleware lets through requests of logged in users isAdmin accepts.
+// Like LoggedInMiddleware, it only trusts the user ID set by a verified login.
+func AdminMiddleware(session *scs.SessionManager, isAdmin func(ctx context.Context, userID string) bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := session.GetString(r.Context(), domainSession.UserIDKey)
+		if userID == "" {
+			response.ProblemStatus(w, r, http.StatusUnauthorized, "not logged in")
+			return
+		}
+		if !isAdmin(r.Context(), userID) {
+			logger.FromContext(r.Context()).Error("admin access denied",
+				slog.String("user_id", userID),
+				slog.String("path", r.URL.Path),
+			)
+			response.ProblemStatus(w, r, http.StatusForbidden, "administrator role required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Feature hides next behind a toggle: while enabled reports false, requests
+// get a 404 as if the route didn't exist.
+func Feature(enabled func() bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !enabled() {
+			response.ProblemStatus(w, r, http.StatusNotFound, "")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestValidator checks a request against the API specification of the
+// route it was matched to.
+type RequestValidator interface {
+	ValidateRequest(r *http.Request, pattern string) error
+}
+
+// ValidateRequest rejects requests that don't match the specification with a
+// problem listing every invalid field, and bodies too large to check with 413.
+// It has to wrap a handler registered on a ServeMux, which sets the pattern
+// and path values it checks.
+func ValidateRequest(v RequestValidator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.ValidateRequest(r, r.Pattern); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if !errors.As(err, &maxBytesErr) {
+				err = response.Invalid(err)
+			}
+			response.Error(w, r, err)
 			return
 		}
 		next.ServeHTTP(w, r)
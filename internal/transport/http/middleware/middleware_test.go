@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domainSession "github.com/axmz/go-port-service/internal/domain/session"
+	wah "github.com/axmz/go-port-service/internal/transport/http/handlers/webauthn"
+)
+
+// A login that was begun but not finished must not authorize anything:
+// BeginLogin stores the user ID of whoever is named in the request.
+func TestLoginRequired(t *testing.T) {
+	sm := scs.New()
+	isAdmin := func(_ context.Context, userID string) bool { return userID == "admin" }
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handlers := map[string]http.Handler{
+		"logged in": LoggedInMiddleware(sm, ok),
+		"admin":     AdminMiddleware(sm, isAdmin, ok),
+	}
+
+	do := func(h http.Handler, set func(ctx context.Context)) int {
+		ctx, err := sm.Load(context.Background(), "")
+		require.NoError(t, err)
+		set(ctx)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+		return w.Code
+	}
+
+	for name, h := range handlers {
+		code := do(h, func(ctx context.Context) {
+			sm.Put(ctx, wah.WebauthSessionKey, webauthn.SessionData{UserID: []byte("admin")})
+		})
+		assert.Equal(t, http.StatusUnauthorized, code, "%s: begun login", name)
+
+		code = do(h, func(ctx context.Context) {
+			sm.Put(ctx, domainSession.UserIDKey, "admin")
+		})
+		assert.Equal(t, http.StatusOK, code, "%s: finished login", name)
+	}
+
+	code := do(handlers["admin"], func(ctx context.Context) {
+		sm.Put(ctx, domainSession.UserIDKey, "alice")
+	})
+	assert.Equal(t, http.StatusForbidden, code)
+}
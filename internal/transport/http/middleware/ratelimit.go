@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/axmz/go-port-service/internal/logger"
+	"github.com/axmz/go-port-service/internal/transport/http/response"
+	"github.com/axmz/go-port-service/pkg/ratelimit"
+)
+
+type RateLimiter interface {
+	Allow(key string) ratelimit.Result
+	Policy() ratelimit.Policy
+}
+
+// ClientKey identifies the client a request is accounted to: the client of
+// the API key that APIKeyAuth accepted, else the logged in user, else the
+// remote IP. Keys that weren't accepted don't count, or sending a new one
+// with every request would get around the limits.
+func ClientKey(userID func(ctx context.Context) string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if client := APIClient(r.Context()); client != "" {
+			return "key:" + client
+		}
+		if id := userID(r.Context()); id != "" {
+			return "user:" + id
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return "ip:" + host
+	}
+}
+
+// RateLimit rejects requests with 429 once the client's bucket is empty and
+// advertises the limit with RateLimit-* headers. Requests for which key
+// returns "" are not limited.
+func RateLimit(l RateLimiter, key func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k := key(r)
+		if k == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		res := l.Allow(k)
+		if res.Limit > 0 {
+			p := l.Policy()
+			h := w.Header()
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", p.Limit, ceilSeconds(p.Period)))
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		}
+
+		if !res.Allowed {
+			logger.FromContext(r.Context()).Warn("rate limit exceeded",
+				slog.String("client", k),
+				slog.String("path", r.URL.Path),
+			)
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			response.WriteProblem(w, r, &response.Problem{
+				Type:   response.TypeRateLimited,
+				Title:  "Rate limit exceeded",
+				Status: http.StatusTooManyRequests,
+				Detail: fmt.Sprintf("retry in %d seconds", ceilSeconds(res.RetryAfter)),
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ConcurrencyLimit serves requests while c has a free slot and rejects the
+// rest with 429 instead of queueing them.
+func ConcurrencyLimit(c *ratelimit.Concurrency, next http.Handler) http.Handler {
+	if c.Limit() == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.TryAcquire() {
+			logger.FromContext(r.Context()).Warn("concurrency limit reached",
+				slog.Int("limit", c.Limit()),
+				slog.String("path", r.URL.Path),
+			)
+			w.Header().Set("Retry-After", "1")
+			response.WriteProblem(w, r, &response.Problem{
+				Type:   response.TypeRateLimited,
+				Title:  "Too many concurrent requests",
+				Status: http.StatusTooManyRequests,
+				Detail: fmt.Sprintf("at most %d requests may run at once", c.Limit()),
+			})
+			return
+		}
+		defer c.Release()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axmz/go-port-service/pkg/ratelimit"
+)
+
+func TestClientKey(t *testing.T) {
+	key := ClientKey(func(ctx context.Context) string { return "" })
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	assert.Equal(t, "ip:10.0.0.1", key(r))
+
+	r.Header.Set(APIKeyHeader, "not-a-key")
+	assert.Equal(t, "ip:10.0.0.1", key(r), "unauthenticated keys are ignored")
+
+	r = r.WithContext(context.WithValue(r.Context(), apiClientKey, "billing"))
+	assert.Equal(t, "key:billing", key(r))
+
+	key = ClientKey(func(ctx context.Context) string { return "alice" })
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, "user:alice", key(r))
+}
+
+func TestRateLimit(t *testing.T) {
+	l := ratelimit.New(ratelimit.Policy{Limit: 1, Period: time.Minute})
+	key := func(r *http.Request) string { return r.Header.Get("X-Client") }
+	h := RateLimit(l, key, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(client string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Client", client)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("a")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1;w=60", w.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+
+	w = do("a")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, do("b").Code)
+
+	w = do("")
+	assert.Equal(t, http.StatusOK, w.Code, "empty key is exempt")
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	h := ConcurrencyLimit(ratelimit.NewConcurrency(1), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		done <- w.Code
+	}()
+	<-entered
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	close(release)
+	require.Equal(t, http.StatusOK, <-done)
+
+	go func() { <-entered }()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "slot is released")
+}
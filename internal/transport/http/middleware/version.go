@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type APIVersionMetrics interface {
+	APIRequest(version string)
+}
+
+// APIVersion counts the requests to an API version.
+func APIVersion(m APIVersionMetrics, version string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.APIRequest(version)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Deprecation describes a deprecated resource.
+type Deprecation struct {
+	// Since is when the resource was deprecated.
+	Since time.Time
+	// Sunset is when the resource is expected to go away.
+	Sunset time.Time
+	// Successor returns the resource replacing the requested one, if any.
+	Successor func(r *http.Request) string
+}
+
+// Deprecated announces on every response that the resource is deprecated
+// (RFC 9745) and when it will stop working (RFC 8594), linking to its
+// successor.
+func Deprecated(d Deprecation, next http.Handler) http.Handler {
+	deprecation := fmt.Sprintf("@%d", d.Since.Unix())
+	sunset := d.Sunset.UTC().Format(http.TimeFormat)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Deprecation", deprecation)
+		h.Set("Sunset", sunset)
+		if d.Successor != nil {
+			if s := d.Successor(r); s != "" {
+				h.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, s))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,442 @@
+// Package openapi serves the REST API's OpenAPI document and validates
+// requests against it.
+package openapi
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"gopkg.in/yaml.v3"
+)
+
+// The specification is maintained as YAML, which is easier to review, and
+// served as JSON.
+//
+//go:embed openapi.yaml
+var source []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+// redoc holds the Redoc bundle fetched by make redoc, which the docs page
+// loads from the service itself rather than from a CDN.
+//
+//go:embed redoc
+var redoc embed.FS
+
+// maxValidatedBody is the largest body read for validation. Streamed bodies,
+// such as uploads, aren't read at all.
+const maxValidatedBody = 1 << 20
+
+// ErrInvalidRequest matches errors of requests that don't match the
+// specification.
+var ErrInvalidRequest = errors.New("request doesn't match the API specification")
+
+// FieldError is a problem with a single parameter or body field.
+type FieldError struct {
+	// Field is a parameter name, or a dotted path into the body such as
+	// "body.coordinates.0".
+	Field  string
+	Detail string
+}
+
+// RequestError lists every problem found with a request.
+type RequestError struct {
+	Fields []FieldError
+}
+
+func (e *RequestError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Detail)
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidRequest, strings.Join(msgs, "; "))
+}
+
+func (e *RequestError) Unwrap() error {
+	return ErrInvalidRequest
+}
+
+type Spec struct {
+	json []byte
+	// operations are keyed by the ServeMux pattern they document, e.g.
+	// "GET /api/ports/{id}".
+	operations map[string]*operation
+}
+
+type operation struct {
+	params       []*parameter
+	body         *jsonschema.Schema
+	bodyRequired bool
+	// streamed bodies are too large to buffer; only the handler reads them.
+	streamed bool
+}
+
+type parameter struct {
+	name     string
+	in       string
+	required bool
+	// typ is the schema type values are converted to before validation.
+	typ    string
+	schema *jsonschema.Schema
+}
+
+// Load parses the embedded specification and compiles its schemas.
+func Load() (*Spec, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(source, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse the OpenAPI document: %w", err)
+	}
+	if err := addLegacyPaths(doc); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the OpenAPI document: %w", err)
+	}
+
+	// Schemas are compiled in place so that their $refs resolve against the
+	// whole document.
+	res, err := jsonschema.UnmarshalJSON(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	if err := c.AddResource(docURL, res); err != nil {
+		return nil, err
+	}
+
+	s := &Spec{json: b, operations: make(map[string]*operation)}
+	paths, _ := res.(map[string]any)["paths"].(map[string]any)
+	for path, item := range paths {
+		item := item.(map[string]any)
+		for method, op := range item {
+			if !isMethod(method) {
+				continue
+			}
+			o, err := compileOperation(c, res, path, method, item, op.(map[string]any))
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			s.operations[strings.ToUpper(method)+" "+path] = o
+		}
+	}
+	return s, nil
+}
+
+const docURL = "openapi.json"
+
+// addLegacyPaths documents the deprecated unversioned paths, which serve the
+// same operations as /api/v1.
+func addLegacyPaths(doc map[string]any) error {
+	paths, _ := doc["paths"].(map[string]any)
+	aliases := map[string]any{}
+	for path, item := range paths {
+		rest, ok := strings.CutPrefix(path, "/api/v1/")
+		if !ok {
+			continue
+		}
+
+		// Copy the item so that the two paths don't share operations.
+		b, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		var legacy map[string]any
+		if err := json.Unmarshal(b, &legacy); err != nil {
+			return err
+		}
+
+		for method, op := range legacy {
+			op, ok := op.(map[string]any)
+			if !isMethod(method) || !ok {
+				continue
+			}
+			op["deprecated"] = true
+			if id, ok := op["operationId"].(string); ok {
+				op["operationId"] = id + "Legacy"
+			}
+			desc, _ := op["description"].(string)
+			op["description"] = strings.TrimSpace(fmt.Sprintf(
+				"Deprecated alias of `%s %s`; see the `Sunset` response header for when it goes away.\n\n%s",
+				strings.ToUpper(method), path, desc))
+		}
+		aliases["/api/"+rest] = legacy
+	}
+	maps.Copy(paths, aliases)
+	return nil
+}
+
+func isMethod(m string) bool {
+	switch m {
+	case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+		return true
+	}
+	return false
+}
+
+func compileOperation(c *jsonschema.Compiler, doc any, path, method string, item, op map[string]any) (*operation, error) {
+	opPtr := "/paths/" + escape(path) + "/" + method
+	o := &operation{}
+	o.streamed, _ = op["x-streamed-body"].(bool)
+
+	// Operation parameters override path item parameters of the same name
+	// and location.
+	byKey := map[string]*parameter{}
+	var order []string
+	for _, src := range []struct {
+		ptr    string
+		params any
+	}{
+		{"/paths/" + escape(path) + "/parameters", item["parameters"]},
+		{opPtr + "/parameters", op["parameters"]},
+	} {
+		list, _ := src.params.([]any)
+		for i := range list {
+			ptr := fmt.Sprintf("%s/%d", src.ptr, i)
+			raw, ptr := resolve(doc, list[i], ptr)
+			p, err := compileParameter(c, doc, raw, ptr)
+			if err != nil {
+				return nil, err
+			}
+			key := p.in + ":" + p.name
+			if _, ok := byKey[key]; !ok {
+				order = append(order, key)
+			}
+			byKey[key] = p
+		}
+	}
+	for _, k := range order {
+		o.params = append(o.params, byKey[k])
+	}
+
+	if rb, ok := op["requestBody"]; ok {
+		raw, ptr := resolve(doc, rb, opPtr+"/requestBody")
+		o.bodyRequired, _ = raw["required"].(bool)
+		content, _ := raw["content"].(map[string]any)
+		if media, ok := content["application/json"].(map[string]any); ok && media["schema"] != nil {
+			sch, err := c.Compile(docURL + "#" + ptr + "/content/application~1json/schema")
+			if err != nil {
+				return nil, err
+			}
+			o.body = sch
+		}
+	}
+	return o, nil
+}
+
+func compileParameter(c *jsonschema.Compiler, doc any, raw map[string]any, ptr string) (*parameter, error) {
+	p := &parameter{}
+	p.name, _ = raw["name"].(string)
+	p.in, _ = raw["in"].(string)
+	p.required, _ = raw["required"].(bool)
+	if raw["schema"] == nil {
+		return p, nil
+	}
+	sch, err := c.Compile(docURL + "#" + ptr + "/schema")
+	if err != nil {
+		return nil, err
+	}
+	p.schema = sch
+	schema, _ := resolve(doc, raw["schema"], "")
+	p.typ, _ = schema["type"].(string)
+	return p, nil
+}
+
+// resolve follows a local $ref, returning the referenced object and its
+// JSON pointer.
+func resolve(doc, v any, ptr string) (map[string]any, string) {
+	m, _ := v.(map[string]any)
+	ref, ok := m["$ref"].(string)
+	if !ok || !strings.HasPrefix(ref, "#/") {
+		return m, ptr
+	}
+	ptr = strings.TrimPrefix(ref, "#")
+	cur := doc
+	for _, tok := range strings.Split(ptr[1:], "/") {
+		tok = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+		cur = cur.(map[string]any)[tok]
+	}
+	return resolve(doc, cur, ptr)
+}
+
+func escape(tok string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(tok)
+}
+
+// Operations returns the ServeMux patterns of every documented operation,
+// sorted.
+func (s *Spec) Operations() []string {
+	res := make([]string, 0, len(s.operations))
+	for k := range s.operations {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Handler serves the specification as JSON.
+func (s *Spec) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = w.Write(s.json)
+	})
+}
+
+// DocsHandler serves a page rendering the specification with Redoc.
+func DocsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(docsPage)
+	})
+}
+
+// RedocHandler serves the embedded Redoc bundle, or 404 if the binary was
+// built without it.
+func RedocHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFileFS(w, r, redoc, "redoc/redoc.standalone.js")
+	})
+}
+
+// ValidateRequest checks r against the operation documented for the
+// ServeMux pattern it was routed to. Requests to undocumented patterns pass.
+// The body, if read, is replaced so that handlers can read it again. Errors
+// other than a *RequestError come from reading the body.
+func (s *Spec) ValidateRequest(r *http.Request, pattern string) error {
+	op, ok := s.operations[pattern]
+	if !ok {
+		return nil
+	}
+
+	var fields []FieldError
+	for _, p := range op.params {
+		fields = append(fields, p.validate(r)...)
+	}
+
+	if op.body != nil && !op.streamed {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBody+1))
+		if err != nil {
+			return err
+		}
+		if len(body) > maxValidatedBody {
+			return &http.MaxBytesError{Limit: maxValidatedBody}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if len(bytes.TrimSpace(body)) == 0 {
+			if op.bodyRequired {
+				fields = append(fields, FieldError{Field: "body", Detail: "is required"})
+			}
+		} else {
+			v, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+			if err != nil {
+				return err
+			}
+			fields = append(fields, schemaErrors("body", op.body.Validate(v))...)
+		}
+	}
+
+	if len(fields) > 0 {
+		return &RequestError{Fields: fields}
+	}
+	return nil
+}
+
+func (p *parameter) validate(r *http.Request) []FieldError {
+	var raw string
+	var present bool
+	switch p.in {
+	case "path":
+		raw = r.PathValue(p.name)
+		present = raw != ""
+	case "query":
+		raw, present = r.URL.Query().Get(p.name), r.URL.Query().Has(p.name)
+	case "header":
+		raw = r.Header.Get(p.name)
+		present = raw != ""
+	case "cookie":
+		c, err := r.Cookie(p.name)
+		if present = err == nil; present {
+			raw = c.Value
+		}
+	}
+
+	if !present {
+		if p.required {
+			return []FieldError{{Field: p.name, Detail: "is required"}}
+		}
+		return nil
+	}
+	if p.schema == nil {
+		return nil
+	}
+
+	v, err := p.convert(raw)
+	if err != nil {
+		return []FieldError{{Field: p.name, Detail: fmt.Sprintf("want %s, got %q", p.typ, raw)}}
+	}
+	return schemaErrors(p.name, p.schema.Validate(v))
+}
+
+// convert turns a raw parameter value into the type its schema expects.
+func (p *parameter) convert(raw string) (any, error) {
+	switch p.typ {
+	case "integer", "number":
+		var n json.Number
+		if err := json.Unmarshal([]byte(raw), &n); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case "boolean":
+		return strconv.ParseBool(raw)
+	}
+	return raw, nil
+}
+
+var printer = message.NewPrinter(language.English)
+
+// schemaErrors flattens a schema validation error into the failing leaves,
+// naming fields by their dotted path below field.
+func schemaErrors(field string, err error) []FieldError {
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return nil
+	}
+
+	var res []FieldError
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) > 0 {
+			for _, c := range e.Causes {
+				walk(c)
+			}
+			return
+		}
+		name := strings.Join(append([]string{field}, e.InstanceLocation...), ".")
+		if req, ok := e.ErrorKind.(*kind.Required); ok {
+			for _, m := range req.Missing {
+				res = append(res, FieldError{Field: name + "." + m, Detail: "is required"})
+			}
+			return
+		}
+		res = append(res, FieldError{Field: name, Detail: e.ErrorKind.LocalizedString(printer)})
+	}
+	walk(verr)
+	return res
+}
@@ -0,0 +1,99 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/internal/domain/session"
+	"github.com/axmz/go-port-service/internal/domain/user"
+	"github.com/axmz/go-port-service/internal/transport/http/openapi"
+)
+
+type problemMapping struct {
+	err    error
+	status int
+	typ    string
+	title  string
+}
+
+// problems maps domain errors to problems. The first match wins, so errors
+// must come before the errors they wrap.
+var problems = []problemMapping{
+	{port.ErrNotFound, http.StatusNotFound, TypeNotFound, "Resource not found"},
+	{user.ErrNotFound, http.StatusNotFound, TypeNotFound, "Resource not found"},
+	{session.ErrNotFound, http.StatusNotFound, TypeNotFound, "Resource not found"},
+	{port.ErrExists, http.StatusConflict, TypeConflict, "Resource already exists"},
+	{user.ErrExists, http.StatusConflict, TypeConflict, "Resource already exists"},
+	{port.ErrSnapshotExpired, http.StatusGone, TypeSnapshotExpired, "Snapshot expired"},
+	{user.ErrLocked, http.StatusLocked, TypeAccountLocked, "Account locked"},
+	{user.ErrDisabled, http.StatusForbidden, TypeAccountDisabled, "Account disabled"},
+	{user.ErrReregistrationRequired, http.StatusForbidden, TypeReregistrationRequired, "Authenticator must be registered again"},
+	{user.ErrCloneDetected, http.StatusForbidden, TypeCloneDetected, "Authenticator clone detected"},
+	{port.ErrValidation, http.StatusBadRequest, TypeValidation, "Validation failed"},
+	{user.ErrValidation, http.StatusBadRequest, TypeValidation, "Validation failed"},
+	{openapi.ErrInvalidRequest, http.StatusBadRequest, TypeValidation, "Validation failed"},
+}
+
+type invalidRequest struct {
+	err error
+}
+
+func (e *invalidRequest) Error() string { return e.err.Error() }
+func (e *invalidRequest) Unwrap() error { return e.err }
+
+// Invalid marks err as caused by the client, so that it is reported as
+// 400 Bad Request unless it matches a more specific problem.
+func Invalid(err error) error {
+	return &invalidRequest{err: err}
+}
+
+// ProblemFor is the single place where errors become problems. Unknown errors
+// are internal server errors.
+func ProblemFor(err error) *Problem {
+	for _, m := range problems {
+		if errors.Is(err, m.err) {
+			p := &Problem{Type: m.typ, Title: m.title, Status: m.status, Detail: err.Error()}
+			p.Errors = fieldProblems(err)
+			return p
+		}
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var invalid *invalidRequest
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &Problem{Type: TypeRequestTooLarge, Title: "Request body too large", Status: http.StatusRequestEntityTooLarge, Detail: err.Error()}
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return &Problem{Type: TypeMalformedBody, Title: "Malformed request body", Status: http.StatusBadRequest, Detail: err.Error()}
+	case errors.As(err, &invalid):
+		return NewProblem(http.StatusBadRequest, err.Error())
+	}
+
+	return NewProblem(http.StatusInternalServerError, "")
+}
+
+func fieldProblems(err error) []FieldProblem {
+	var verr *port.ValidationError
+	var rerr *openapi.RequestError
+	switch {
+	case errors.As(err, &verr):
+		res := make([]FieldProblem, 0, len(verr.Fields))
+		for _, f := range verr.Fields {
+			res = append(res, FieldProblem{Field: f.Field, Detail: f.Reason()})
+		}
+		return res
+	case errors.As(err, &rerr):
+		res := make([]FieldProblem, 0, len(rerr.Fields))
+		for _, f := range rerr.Fields {
+			res = append(res, FieldProblem{Field: f.Field, Detail: f.Detail})
+		}
+		return res
+	}
+	return nil
+}
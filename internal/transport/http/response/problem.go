@@ -0,0 +1,90 @@
+package response
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/axmz/go-port-service/internal/logger"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+	// RequestIDHeader is set on the response by the request ID middleware and
+	// copied into problems so clients can quote it.
+	RequestIDHeader = "X-Request-Id"
+)
+
+// Problem types. A problem without a more specific type uses "about:blank",
+// whose title is the HTTP status text (RFC 9457 4.2.1).
+const (
+	TypeBlank                  = "about:blank"
+	TypeValidation             = "/problems/validation-error"
+	TypeNotFound               = "/problems/not-found"
+	TypeMalformedBody          = "/problems/malformed-body"
+	TypeRequestTooLarge        = "/problems/request-too-large"
+	TypeAccountLocked          = "/problems/account-locked"
+	TypeAccountDisabled        = "/problems/account-disabled"
+	TypeCloneDetected          = "/problems/authenticator-clone-detected"
+	TypeReregistrationRequired = "/problems/reregistration-required"
+	TypeRateLimited            = "/problems/rate-limited"
+	TypeInvalidCSRFToken       = "/problems/invalid-csrf-token"
+	TypeSnapshotExpired        = "/problems/snapshot-expired"
+	TypeConflict               = "/problems/conflict"
+)
+
+// Problem is an RFC 7807 (RFC 9457) problem details object.
+type Problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
+	Instance  string         `json:"instance,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Errors    []FieldProblem `json:"errors,omitempty"`
+}
+
+// FieldProblem describes what is wrong with a single request field.
+type FieldProblem struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// NewProblem returns a problem of type about:blank for status.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   TypeBlank,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// WriteProblem completes p with the request's instance and ID and writes it.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = w.Header().Get(RequestIDHeader)
+	}
+	writeJSON(w, p.Status, ProblemContentType, p)
+}
+
+// ProblemStatus writes an about:blank problem, for errors that originate in the
+// transport layer such as a missing path parameter.
+func ProblemStatus(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	WriteProblem(w, r, NewProblem(status, detail))
+}
+
+// Error maps err to a problem, see ProblemFor, and writes it. Server errors
+// are logged and their detail isn't leaked to the client.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	p := ProblemFor(err)
+	if p.Status >= http.StatusInternalServerError {
+		logger.FromContext(r.Context()).Error("request failed",
+			slog.String("path", r.URL.Path),
+			slog.Any("error", err),
+		)
+	}
+	WriteProblem(w, r, p)
+}
@@ -0,0 +1,79 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axmz/go-port-service/internal/domain/port"
+	"github.com/axmz/go-port-service/internal/domain/user"
+)
+
+func TestProblemFor(t *testing.T) {
+	_, portErr := port.New("id", "", "code", "city", "", nil, nil, nil, "", "", nil)
+	var syntaxErr error = &json.SyntaxError{}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		typ    string
+	}{
+		{"port not found", port.ErrNotFound, http.StatusNotFound, TypeNotFound},
+		{"wrapped user not found", fmt.Errorf("get: %w", user.ErrNotFound), http.StatusNotFound, TypeNotFound},
+		{"clone detected", fmt.Errorf("can't finish login: %w", user.ErrCloneDetected), http.StatusForbidden, TypeCloneDetected},
+		{"reregistration wins over the clone it wraps", fmt.Errorf("can't finish login: %w", user.ErrReregistrationRequired), http.StatusForbidden, TypeReregistrationRequired},
+		{"locked wins over invalid", Invalid(fmt.Errorf("can't begin login: %w", user.ErrLocked)), http.StatusLocked, TypeAccountLocked},
+		{"validation", portErr, http.StatusBadRequest, TypeValidation},
+		{"too large", &http.MaxBytesError{Limit: 1}, http.StatusRequestEntityTooLarge, TypeRequestTooLarge},
+		{"malformed", syntaxErr, http.StatusBadRequest, TypeMalformedBody},
+		{"invalid", Invalid(errors.New("bad")), http.StatusBadRequest, TypeBlank},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, TypeBlank},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := ProblemFor(tt.err)
+			assert.Equal(t, tt.status, p.Status)
+			assert.Equal(t, tt.typ, p.Type)
+			assert.NotEmpty(t, p.Title)
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	_, err := port.New("id", "", "code", "city", "", nil, nil, nil, "", "", nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/ports", nil)
+	w := httptest.NewRecorder()
+	w.Header().Set(RequestIDHeader, "000042")
+	Error(w, r, err)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+	var p Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	assert.Equal(t, TypeValidation, p.Type)
+	assert.Equal(t, "/api/ports", p.Instance)
+	assert.Equal(t, "000042", p.RequestID)
+	assert.Equal(t, []FieldProblem{
+		{Field: "name", Detail: "value cannot be empty"},
+		{Field: "country", Detail: "value cannot be empty"},
+	}, p.Errors)
+}
+
+func TestError_HidesInternalDetail(t *testing.T) {
+	w := httptest.NewRecorder()
+	Error(w, httptest.NewRequest(http.MethodGet, "/api/ports", nil), errors.New("database password is hunter2"))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.False(t, strings.Contains(w.Body.String(), "hunter2"))
+}
@@ -7,8 +7,6 @@ import (
 	"net/http"
 )
 
-// TODO: implement as per RFC 7807
-
 type Response struct {
 	Status string `json:"status"`
 	Error  string `json:"error,omitempty"`
@@ -21,7 +19,11 @@ const (
 )
 
 func JSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writeJSON(w, status, "application/json; charset=utf-8", data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, contentType string, data any) {
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		slog.Info(fmt.Sprintf("error encoding JSON: %v", err))
@@ -32,24 +34,6 @@ func OK(w http.ResponseWriter, data any) {
 	JSON(w, http.StatusOK, Response{Status: StatusOK, Data: data})
 }
 
-func Err(w http.ResponseWriter, status int, msg string) {
-	JSON(w, status, Response{Status: StatusError, Error: msg})
-}
-
-func InternalServerError(w http.ResponseWriter, err error) {
-	slog.Info(fmt.Sprintf("Internal server error: %v", err))
-	Err(w, http.StatusInternalServerError, "internal server error")
-}
-
-func BadRequest(w http.ResponseWriter, msg string) {
-	slog.Info(fmt.Sprintf("Bad Request: %v", msg))
-	Err(w, http.StatusBadRequest, msg)
-}
-
-func NotFound(w http.ResponseWriter) {
-	Err(w, http.StatusNotFound, "resource not found")
-}
-
 func Text(w http.ResponseWriter, status int, body string) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(status)
@@ -2,52 +2,233 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/playground"
 
 	"github.com/axmz/go-port-service/internal/app"
+	"github.com/axmz/go-port-service/internal/config"
+	"github.com/axmz/go-port-service/internal/domain/audit"
+	domainSession "github.com/axmz/go-port-service/internal/domain/session"
 	"github.com/axmz/go-port-service/internal/transport/http/middleware"
+	"github.com/axmz/go-port-service/internal/transport/http/openapi"
+	"github.com/axmz/go-port-service/pkg/certs"
 )
 
 type Server struct {
 	Router *http.Server
+	// Redirect, when not nil, redirects plain HTTP requests to Router.
+	Redirect *http.Server
+	// certs serve Router's certificate when it serves https.
+	certs          *certs.Reloader
+	reloadInterval time.Duration
+	// started is called once the listener is bound.
+	started func()
+	// Routes are the patterns registered on the router.
+	Routes []string
+	// Spec documents the REST API.
+	Spec *openapi.Spec
+}
+
+// router records the patterns registered on the mux, so that they can be
+// checked against the API specification, and wraps their handlers.
+type router struct {
+	*http.ServeMux
+	patterns []string
+	wrap     func(http.Handler) http.Handler
+}
+
+func (m *router) Handle(pattern string, h http.Handler) {
+	m.patterns = append(m.patterns, pattern)
+	if m.wrap != nil {
+		h = m.wrap(h)
+	}
+	m.ServeMux.Handle(pattern, h)
+}
+
+func (m *router) HandleFunc(pattern string, h http.HandlerFunc) {
+	m.Handle(pattern, h)
+}
+
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// group registers routes, whose patterns are relative to prefix, with their
+// handlers wrapped by wrap.
+func (m *router) group(prefix string, wrap func(http.Handler) http.Handler, routes []route) {
+	for _, rt := range routes {
+		method, path, _ := strings.Cut(rt.pattern, " ")
+		m.Handle(method+" "+prefix+path, wrap(rt.handler))
+	}
+}
+
+// legacyAPI deprecates the unversioned /api paths in favour of /api/v1.
+var legacyAPI = middleware.Deprecation{
+	Since:  time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+	Sunset: time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC),
+	Successor: func(r *http.Request) string {
+		return "/api/v1" + strings.TrimPrefix(r.URL.Path, "/api")
+	},
 }
 
 func NewServer(app *app.App) *Server {
-	mux := http.NewServeMux()
+	spec, err := openapi.Load()
+	if err != nil {
+		log.Fatalf("failed to load the OpenAPI specification: %v", err)
+	}
 
-	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
+	mux := &router{ServeMux: http.NewServeMux()}
+	if app.Config.OpenAPI.ValidateRequests {
+		mux.wrap = func(h http.Handler) http.Handler { return middleware.ValidateRequest(spec, h) }
+	}
+
+	audited := func(action audit.Action, h http.HandlerFunc) http.HandlerFunc {
+		return middleware.Audit(app.Services.Audit, app.Services.SessionManager, action, h).ServeHTTP
+	}
+
+	userID := func(ctx context.Context) string {
+		return app.Services.SessionManager.GetString(ctx, domainSession.UserIDKey)
+	}
+	clientKey := middleware.ClientKey(userID)
+	limited := func(l middleware.RateLimiter, h http.Handler) http.Handler {
+		return middleware.RateLimit(l, clientKey, h)
+	}
+
+	feature := func(enabled func(f *config.Features) bool, h http.Handler) http.Handler {
+		return middleware.Feature(func() bool { return enabled(app.Features.Load()) }, h)
+	}
+	graphQL := func(f *config.Features) bool { return f.GraphQL }
+	upload := func(f *config.Features) bool { return f.Upload }
+	portWrites := func(f *config.Features) bool { return f.PortWrites }
+
+	mux.Handle("/static/", app.Assets.Handler())
 
 	mux.HandleFunc("/", app.Handlers.Page.Home)
 	mux.Handle("/private", middleware.LoggedInMiddleware(app.Services.SessionManager, http.HandlerFunc(app.Handlers.Page.Private)))
 	mux.Handle("/public", http.HandlerFunc(app.Handlers.Page.Private))
-	mux.HandleFunc("/metrics", app.Handlers.Page.Metrics)
+	mux.Handle("/metrics", app.Metrics.Handler())
+	mux.Handle("GET /healthz", app.Health.LivenessHandler())
+	mux.Handle("GET /readyz", app.Health.ReadinessHandler())
+	mux.Handle("GET /startupz", app.Health.StartupHandler())
+	mux.Handle("GET /openapi.json", spec.Handler())
+	mux.Handle("GET /docs", openapi.DocsHandler())
+	mux.Handle("GET /docs/redoc.standalone.js", openapi.RedocHandler())
+
+	mux.Handle("/playground", feature(graphQL, playground.Handler("GraphQL playground", "/query")))
+	mux.Handle("/query", feature(graphQL, app.Handlers.GraphQLQuery))
+
+	admin := func(h http.HandlerFunc) http.Handler {
+		return middleware.AdminMiddleware(app.Services.SessionManager, app.Services.User.IsAdmin, h)
+	}
+
+	// Version 1 is the API as it was before versioning. Its handlers are
+	// built once so that the legacy paths share limits with /api/v1.
+	v1 := []route{
+		{"POST /ports", feature(upload, limited(app.RateLimiters.Upload,
+			middleware.ConcurrencyLimit(app.Uploads,
+				audited(audit.ActionPortUpload, app.Handlers.Ports.Upload))))},
+		{"GET /ports", http.HandlerFunc(app.Handlers.Ports.GetAll)},
+		{"GET /ports/{id}", http.HandlerFunc(app.Handlers.Ports.Get)},
+		{"GET /ports/count", http.HandlerFunc(app.Handlers.Ports.Count)},
+		{"PUT /ports/{id}", feature(portWrites, audited(audit.ActionPortUpdate, app.Handlers.Ports.UpdatePort))},
+		{"DELETE /ports/{id}", feature(portWrites, audited(audit.ActionPortDelete, app.Handlers.Ports.Delete))},
+
+		{"POST /webauth/register/begin", limited(app.RateLimiters.Auth, audited(audit.ActionRegisterBegin, app.Handlers.WebAuthn.BeginRegistration))},
+		{"POST /webauth/register/finish", limited(app.RateLimiters.Auth, audited(audit.ActionRegisterFinish, app.Handlers.WebAuthn.FinishRegistration))},
+		{"POST /webauth/login/begin", limited(app.RateLimiters.Auth, audited(audit.ActionLoginBegin, app.Handlers.WebAuthn.BeginLogin))},
+		{"POST /webauth/login/finish", limited(app.RateLimiters.Auth, audited(audit.ActionLoginFinish, app.Handlers.WebAuthn.FinishLogin))},
+		{"POST /webauth/logout", audited(audit.ActionLogout, app.Handlers.WebAuthn.Logout)},
+
+		{"GET /sessions", http.HandlerFunc(app.Handlers.Sessions.List)},
+		{"DELETE /sessions/{id}", http.HandlerFunc(app.Handlers.Sessions.Revoke)},
+		{"POST /sessions/logout-all", http.HandlerFunc(app.Handlers.Sessions.RevokeAll)},
+
+		{"GET /me", http.HandlerFunc(app.Handlers.Users.Me)},
+		{"PUT /me", audited(audit.ActionUserUpdate, app.Handlers.Users.UpdateMe)},
 
-	mux.Handle("/playground", playground.Handler("GraphQL playground", "/query"))
-	mux.Handle("/query", app.Handlers.GraphQLQuery)
+		{"GET /admin/users", admin(app.Handlers.Users.List)},
+		{"GET /admin/users/{id}", admin(app.Handlers.Users.Get)},
+		{"DELETE /admin/users/{id}", admin(audited(audit.ActionUserDelete, app.Handlers.Users.Delete))},
+		{"POST /admin/users/{id}/disable", admin(audited(audit.ActionUserDisable, app.Handlers.Users.Disable))},
+		{"POST /admin/users/{id}/enable", admin(audited(audit.ActionUserEnable, app.Handlers.Users.Enable))},
+		{"PUT /admin/users/{id}/role", admin(audited(audit.ActionUserRole, app.Handlers.Users.SetRole))},
+		{"POST /admin/users/{id}/unlock", admin(audited(audit.ActionUserUnlock, app.Handlers.WebAuthn.Unlock))},
+		{"GET /admin/security/events", admin(app.Handlers.WebAuthn.SecurityEvents)},
+		{"GET /audit", admin(app.Handlers.Audit.List)},
+		{"GET /audit/export", admin(app.Handlers.Audit.Export)},
+	}
+
+	// Version 2 drops the envelope, renames fields and paginates lists.
+	v2 := []route{
+		{"GET /ports", http.HandlerFunc(app.Handlers.PortsV2.List)},
+		{"GET /ports/{id}", http.HandlerFunc(app.Handlers.PortsV2.Get)},
+		{"DELETE /ports/{id}", feature(portWrites, audited(audit.ActionPortDelete, app.Handlers.PortsV2.Delete))},
+		{"POST /ports/{id}/rename", feature(portWrites, audited(audit.ActionPortRename, app.Handlers.PortsV2.Rename))},
+	}
+
+	version := func(v string) func(http.Handler) http.Handler {
+		return func(h http.Handler) http.Handler { return middleware.APIVersion(app.Metrics, v, h) }
+	}
+	legacy := func(h http.Handler) http.Handler {
+		return middleware.APIVersion(app.Metrics, "legacy", middleware.Deprecated(legacyAPI, h))
+	}
+	mux.group("/api/v1", version("v1"), v1)
+	mux.group("/api/v2", version("v2"), v2)
+	mux.group("/api", legacy, v1)
+
+	accessLog := middleware.AccessLogOptions{
+		SampleRate:    app.Config.Logging.AccessSampleRate,
+		SlowThreshold: app.Config.Logging.SlowRequest,
+		RedactHeaders: app.Config.Logging.RedactHeaders,
+	}
 
-	mux.HandleFunc("POST /api/ports", app.Handlers.Ports.Upload)
-	mux.HandleFunc("GET /api/ports", app.Handlers.Ports.GetAll)
-	mux.HandleFunc("GET /api/ports/{id}", app.Handlers.Ports.Get)
-	mux.HandleFunc("GET /api/ports/count", app.Handlers.Ports.Count)
-	mux.HandleFunc("PUT /api/ports/{id}", app.Handlers.Ports.UpdatePort)
-	mux.HandleFunc("DELETE /api/ports/{id}", app.Handlers.Ports.Delete)
+	// The API-wide limit applies on top of the per-group ones above.
+	apiClientKey := func(r *http.Request) string {
+		if strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/query" {
+			return clientKey(r)
+		}
+		return ""
+	}
+
+	// Pages are the HTML pages; they get a CSRF token for their scripts.
+	pages := []string{"/", "/private", "/public"}
+	page := func(r *http.Request) bool {
+		return (r.Method == http.MethodGet || r.Method == http.MethodHead) && slices.Contains(pages, r.URL.Path)
+	}
 
-	mux.HandleFunc("POST /api/webauth/register/begin", app.Handlers.WebAuthn.BeginRegistration)
-	mux.HandleFunc("POST /api/webauth/register/finish", app.Handlers.WebAuthn.FinishRegistration)
-	mux.HandleFunc("POST /api/webauth/login/begin", app.Handlers.WebAuthn.BeginLogin)
-	mux.HandleFunc("POST /api/webauth/login/finish", app.Handlers.WebAuthn.FinishLogin)
-	mux.HandleFunc("POST /api/webauth/logout", app.Handlers.WebAuthn.Logout)
+	corsPolicy := func(r *http.Request) *middleware.CORSPolicy {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/"):
+			return app.CORS.Load().API
+		case r.URL.Path == "/query":
+			return app.CORS.Load().GraphQL
+		}
+		return nil
+	}
 
 	handler :=
 		middleware.Recoverer(
-			app.Services.SessionManager.LoadAndSave(
-				middleware.RequestID(
-					middleware.Logger(mux))))
+			middleware.Compress(app.Config.HTTPServer.CompressMinSize,
+				middleware.APIKeyAuth(app.Config.Auth.APIKeys, app.Services.SessionManager.Cookie.Name,
+					app.Services.SessionManager.LoadAndSave(
+						middleware.RequestID(
+							middleware.Tracing(mux,
+								middleware.Logger(app.Log, mux, app.Metrics, userID, accessLog,
+									middleware.CORS(mux, corsPolicy,
+										middleware.RateLimit(app.RateLimiters.API, apiClientKey,
+											middleware.RejectInvalidAPIKey(
+												middleware.CSRF(app.Services.SessionManager, app.Config.HTTPServer.Protocol == "https", page, mux)))))))))))
 
 	r := &http.Server{
 		Handler:      handler,
@@ -55,20 +236,104 @@ func NewServer(app *app.App) *Server {
 		IdleTimeout:  app.Config.HTTPServer.IdleTimeout,
 		ReadTimeout:  app.Config.HTTPServer.ReadTimeout,
 		WriteTimeout: app.Config.HTTPServer.WriteTimeout,
+		Protocols:    new(http.Protocols),
 	}
+	r.Protocols.SetHTTP1(true)
+	r.Protocols.SetHTTP2(true)
 
-	return &Server{
-		Router: r,
+	s := &Server{
+		Router:  r,
+		started: app.Health.MarkStarted,
+		Routes:  mux.patterns,
+		Spec:    spec,
 	}
+	if app.Config.HTTPServer.Protocol == "https" {
+		s.setupTLS(app.Config.HTTPServer)
+	}
+	return s
+}
+
+// setupTLS loads the certificate, generating a self-signed one first if
+// asked to, and adds the redirect listener.
+func (s *Server) setupTLS(cfg config.HTTPServer) {
+	if cfg.TLS.SelfSigned {
+		hosts := []string{cfg.Host, "localhost", "127.0.0.1", "::1"}
+		if err := certs.SelfSigned(cfg.TLS.CertFile, cfg.TLS.KeyFile, hosts); err != nil {
+			log.Fatalf("failed to generate a self-signed certificate: %v", err)
+		}
+		slog.Warn("serving a self-signed certificate", slog.String("cert", cfg.TLS.CertFile))
+	}
+
+	var err error
+	if s.certs, err = certs.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile); err != nil {
+		log.Fatalf("failed to load the TLS certificate: %v", err)
+	}
+	s.reloadInterval = cfg.TLS.ReloadInterval
+	s.Router.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: s.certs.GetCertificate,
+	}
+
+	if cfg.RedirectPort != "" {
+		s.Redirect = &http.Server{
+			Addr:              cfg.RedirectPort,
+			Handler:           redirectToHTTPS(cfg.Host, cfg.Port),
+			ReadHeaderTimeout: cfg.ReadTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		}
+	}
+}
+
+// redirectToHTTPS redirects to the same path on host. The request's Host
+// header isn't trusted, so the redirect can't point elsewhere.
+func redirectToHTTPS(host, port string) http.Handler {
+	if port == ":443" {
+		port = ""
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://"+host+port+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
 }
 
 func (s *Server) Run() {
+	if s.Redirect != nil {
+		slog.Info(fmt.Sprintf("Redirecting HTTP on %s to https", s.Redirect.Addr))
+		go func() {
+			if err := s.Redirect.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatalf("HTTP redirect server ListenAndServe: %v", err)
+			}
+		}()
+	}
+	if s.certs != nil && s.reloadInterval > 0 {
+		go s.certs.Watch(s.reloadInterval)
+	}
+
 	slog.Info(fmt.Sprintf("Starting server on %s", s.Router.Addr))
-	if err := s.Router.ListenAndServe(); err != http.ErrServerClosed {
-		log.Fatalf("HTTP server ListenAndServe: %v", err)
+	ln, err := net.Listen("tcp", s.Router.Addr)
+	if err != nil {
+		log.Fatalf("HTTP server Listen: %v", err)
+	}
+	s.started()
+	if s.Router.TLSConfig != nil {
+		err = s.Router.ServeTLS(ln, "", "")
+	} else {
+		err = s.Router.Serve(ln)
+	}
+	if err != http.ErrServerClosed {
+		log.Fatalf("HTTP server Serve: %v", err)
 	}
 }
 
+// Shutdown stops the redirect listener and the certificate reloader along
+// with the server.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.Router.Shutdown(ctx)
+	var errs []error
+	if s.Redirect != nil {
+		errs = append(errs, s.Redirect.Shutdown(ctx))
+	}
+	errs = append(errs, s.Router.Shutdown(ctx))
+	if s.certs != nil {
+		errs = append(errs, s.certs.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
 }
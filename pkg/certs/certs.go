@@ -0,0 +1,112 @@
+// Package certs serves TLS certificates from files that can be replaced
+// while the server runs, and generates self-signed ones for development.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reloader holds a certificate loaded from a cert and key file and loads it
+// again when either file changes.
+type Reloader struct {
+	certFile, keyFile string
+
+	cert atomic.Pointer[tls.Certificate]
+
+	mu      sync.Mutex
+	version fileVersion
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// fileVersion identifies the content of the files without reading them.
+type fileVersion struct {
+	certMod, keyMod   time.Time
+	certSize, keySize int64
+}
+
+// NewReloader loads the certificate and key pair.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, stop: make(chan struct{})}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate. It is meant for
+// tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Reload loads the pair again if either file changed since the last load
+// and reports whether it did. On error the current certificate stays.
+func (r *Reloader) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, err := stat(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+	if v == r.version && r.cert.Load() != nil {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	r.cert.Store(&cert)
+	r.version = v
+	return true, nil
+}
+
+// Watch checks the files every interval until Shutdown is called. A
+// rotation that writes the cert and key one after the other may briefly
+// fail to load; the old certificate is kept until both match.
+func (r *Reloader) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if reloaded, err := r.Reload(); err != nil {
+				slog.Warn("certificate reload failed, keeping the current one", slog.Any("error", err))
+			} else if reloaded {
+				slog.Info("certificate reloaded", slog.String("cert", r.certFile))
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Shutdown stops Watch.
+func (r *Reloader) Shutdown(ctx context.Context) error {
+	r.once.Do(func() { close(r.stop) })
+	return ctx.Err()
+}
+
+func stat(certFile, keyFile string) (fileVersion, error) {
+	c, err := os.Stat(certFile)
+	if err != nil {
+		return fileVersion{}, err
+	}
+	k, err := os.Stat(keyFile)
+	if err != nil {
+		return fileVersion{}, err
+	}
+	return fileVersion{
+		certMod: c.ModTime(), certSize: c.Size(),
+		keyMod: k.ModTime(), keySize: k.Size(),
+	}, nil
+}
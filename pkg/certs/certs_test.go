@@ -0,0 +1,98 @@
+package certs
+
+import (
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func files(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	return filepath.Join(dir, "certs", "cert.pem"), filepath.Join(dir, "certs", "key.pem")
+}
+
+func leaf(t *testing.T, r *Reloader) *x509.Certificate {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	l, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return l
+}
+
+func TestSelfSigned(t *testing.T) {
+	certFile, keyFile := files(t)
+
+	require.NoError(t, SelfSigned(certFile, keyFile, []string{"localhost", "127.0.0.1"}))
+
+	r, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+	l := leaf(t, r)
+	assert.NoError(t, l.VerifyHostname("localhost"))
+	assert.NoError(t, l.VerifyHostname("127.0.0.1"))
+	assert.Error(t, l.VerifyHostname("example.com"))
+
+	info, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "the key is private")
+
+	t.Run("keeps a usable certificate", func(t *testing.T) {
+		require.NoError(t, SelfSigned(certFile, keyFile, []string{"localhost"}))
+		r, err := NewReloader(certFile, keyFile)
+		require.NoError(t, err)
+		assert.Equal(t, l.SerialNumber, leaf(t, r).SerialNumber)
+	})
+
+	t.Run("replaces one missing a host", func(t *testing.T) {
+		require.NoError(t, SelfSigned(certFile, keyFile, []string{"localhost", "dev.example.com"}))
+		r, err := NewReloader(certFile, keyFile)
+		require.NoError(t, err)
+		assert.NoError(t, leaf(t, r).VerifyHostname("dev.example.com"))
+	})
+}
+
+func TestReloader_Reload(t *testing.T) {
+	certFile, keyFile := files(t)
+	require.NoError(t, SelfSigned(certFile, keyFile, []string{"localhost"}))
+
+	r, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+	first := leaf(t, r).SerialNumber
+
+	reloaded, err := r.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "unchanged files aren't loaded again")
+
+	t.Run("broken files keep the current certificate", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0o600))
+		_, err := r.Reload()
+		assert.Error(t, err)
+		assert.Equal(t, first, leaf(t, r).SerialNumber)
+	})
+
+	t.Run("a rotated certificate is picked up", func(t *testing.T) {
+		require.NoError(t, os.Remove(certFile))
+		require.NoError(t, SelfSigned(certFile, keyFile, []string{"localhost"}))
+		// Make sure the modification time moves on coarse clocks.
+		later := time.Now().Add(time.Second)
+		require.NoError(t, os.Chtimes(certFile, later, later))
+
+		go r.Watch(10 * time.Millisecond)
+		t.Cleanup(func() { _ = r.Shutdown(t.Context()) })
+
+		assert.Eventually(t, func() bool {
+			return leaf(t, r).SerialNumber.Cmp(first) != 0
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestNewReloader_MissingFiles(t *testing.T) {
+	certFile, keyFile := files(t)
+	_, err := NewReloader(certFile, keyFile)
+	assert.Error(t, err)
+}
@@ -2,10 +2,12 @@ package graceful
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"sort"
 	"sync"
 	"syscall"
 	"time"
@@ -13,39 +15,184 @@ import (
 
 type Operation = func(ctx context.Context) error
 
-func Shutdown(timeout time.Duration, ops map[string]Operation) <-chan struct{} {
-	wait := make(chan struct{})
-	go func() {
-		s := make(chan os.Signal, 1)
-		signal.Notify(s, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
-		<-s
+// Phase orders shutdown operations. Phases run one after another in
+// increasing order; the operations within a phase run concurrently.
+type Phase int
 
-		slog.Info("shutting down")
+const (
+	// PhaseStopAccepting makes the application stop advertising itself, e.g.
+	// by failing readiness, while it still serves.
+	PhaseStopAccepting Phase = iota
+	// PhaseDrain closes listeners and waits for in-flight requests.
+	PhaseDrain
+	// PhaseBackground stops background jobs once no request can start new ones.
+	PhaseBackground
+	// PhaseFlushStores closes the stores once nothing writes to them.
+	PhaseFlushStores
+	// PhaseCloseSessions closes the session store.
+	PhaseCloseSessions
+	// PhaseTelemetry flushes traces and metrics, which everything before may
+	// still have produced.
+	PhaseTelemetry
+)
 
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
+var phaseNames = map[Phase]string{
+	PhaseStopAccepting: "stop accepting",
+	PhaseDrain:         "drain",
+	PhaseBackground:    "background jobs",
+	PhaseFlushStores:   "flush stores",
+	PhaseCloseSessions: "close sessions",
+	PhaseTelemetry:     "telemetry",
+}
 
-		var wg sync.WaitGroup
+func (p Phase) String() string {
+	if name, ok := phaseNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("phase %d", int(p))
+}
 
-		for key, op := range ops {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
+type operation struct {
+	phase   Phase
+	name    string
+	timeout time.Duration
+	op      Operation
+}
 
-				slog.Info(fmt.Sprintf("cleaning up: %s", key))
-				if err := op(ctx); err != nil {
-					slog.Info(fmt.Sprintf("%s: clean up failed: %s", key, err.Error()))
-					return
-				}
+// Manager shuts the application down in phases when it receives a
+// termination signal. A second signal exits immediately.
+type Manager struct {
+	timeout time.Duration
+	exit    func(code int)
 
-				slog.Info(fmt.Sprintf("%s was shutdown gracefully", key))
-			}()
-		}
+	mu  sync.Mutex
+	ops []operation
+}
 
-		wg.Wait()
+// New creates a manager whose operations time out after timeout unless
+// registered with their own.
+func New(timeout time.Duration) *Manager {
+	return &Manager{timeout: timeout, exit: os.Exit}
+}
 
-		close(wait)
+// Register adds op to phase. A zero timeout uses the manager's default.
+func (m *Manager) Register(phase Phase, name string, timeout time.Duration, op Operation) {
+	if timeout <= 0 {
+		timeout = m.timeout
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ops = append(m.ops, operation{phase: phase, name: name, timeout: timeout, op: op})
+}
+
+// Wait blocks until SIGINT or SIGTERM and then shuts down, see Shutdown.
+// Another signal during shutdown exits the process with status 1.
+func (m *Manager) Wait() error {
+	s := make(chan os.Signal, 2)
+	signal.Notify(s, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(s)
+
+	sig := <-s
+	slog.Info("shutting down", slog.String("signal", sig.String()))
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case sig := <-s:
+			slog.Error("forcing exit", slog.String("signal", sig.String()))
+			m.exit(1)
+		case <-done:
+		}
 	}()
 
-	return wait
+	return m.Shutdown()
+}
+
+// Shutdown runs the registered operations phase by phase. A phase starts
+// once every operation of the previous one returned or timed out. Failures
+// don't stop the shutdown; they are joined into the returned error.
+func (m *Manager) Shutdown() error {
+	m.mu.Lock()
+	ops := append([]operation(nil), m.ops...)
+	m.mu.Unlock()
+
+	sort.SliceStable(ops, func(i, j int) bool { return ops[i].phase < ops[j].phase })
+
+	var errs []error
+	for start := 0; start < len(ops); {
+		end := start
+		for end < len(ops) && ops[end].phase == ops[start].phase {
+			end++
+		}
+		slog.Info(fmt.Sprintf("shutdown phase: %s", ops[start].phase))
+		errs = append(errs, runPhase(ops[start:end])...)
+		start = end
+	}
+	return errors.Join(errs...)
+}
+
+func runPhase(ops []operation) []error {
+	errs := make([]error, len(ops))
+	var wg sync.WaitGroup
+	for i, o := range ops {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := run(o); err != nil {
+				slog.Error(fmt.Sprintf("%s: clean up failed: %s", o.name, err.Error()))
+				errs[i] = fmt.Errorf("%s: %w", o.name, err)
+				return
+			}
+			slog.Info(fmt.Sprintf("%s was shutdown gracefully", o.name))
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// run calls the operation and gives up on it when its timeout expires, even
+// if it ignores its context.
+func run(o operation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	slog.Info(fmt.Sprintf("cleaning up: %s", o.name))
+	res := make(chan error, 1)
+	go func() { res <- o.op(ctx) }()
+
+	select {
+	case err := <-res:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s: %w", o.timeout, ctx.Err())
+	}
+}
+
+// Reload calls fn on every SIGHUP until stop is called. Calls never overlap;
+// a SIGHUP arriving during one is handled after it. After stop, SIGHUP is
+// ignored rather than left to kill the process, since stop is meant to be
+// called while shutting down.
+func Reload(fn func()) (stop func()) {
+	s := make(chan os.Signal, 1)
+	signal.Notify(s, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-s:
+				fn()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Ignore(syscall.SIGHUP)
+			signal.Stop(s)
+			close(done)
+		})
+	}
 }
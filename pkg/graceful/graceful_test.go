@@ -0,0 +1,122 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown_RunsPhasesInOrder(t *testing.T) {
+	m := New(time.Second)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) Operation {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	// Registered out of order on purpose.
+	m.Register(PhaseFlushStores, "store", 0, record("store"))
+	m.Register(PhaseTelemetry, "tracer", 0, record("tracer"))
+	m.Register(PhaseDrain, "http", 0, func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond) // stores must wait for this
+		return record("http")(ctx)
+	})
+	m.Register(PhaseStopAccepting, "readiness", 0, record("readiness"))
+
+	require.NoError(t, m.Shutdown())
+	assert.Equal(t, []string{"readiness", "http", "store", "tracer"}, order)
+}
+
+func TestShutdown_AggregatesErrorsAndTimeouts(t *testing.T) {
+	m := New(time.Second)
+
+	errStore := errors.New("disk full")
+	var ranLater bool
+	m.Register(PhaseDrain, "stuck", 10*time.Millisecond, func(context.Context) error {
+		select {} // ignores its context
+	})
+	m.Register(PhaseFlushStores, "store", 0, func(context.Context) error { return errStore })
+	m.Register(PhaseTelemetry, "tracer", 0, func(context.Context) error {
+		ranLater = true
+		return nil
+	})
+
+	err := m.Shutdown()
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errStore)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "stuck: timed out after 10ms")
+	assert.True(t, ranLater, "failures don't stop later phases")
+}
+
+func TestWait_SecondSignalForcesExit(t *testing.T) {
+	m := New(time.Second)
+
+	exited := make(chan int, 1)
+	m.exit = func(code int) { exited <- code }
+
+	release := make(chan struct{})
+	m.Register(PhaseDrain, "slow", 0, func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- m.Wait() }()
+
+	self, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+
+	// Wait has to be listening before the first signal arrives.
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, self.Signal(syscall.SIGTERM))
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, self.Signal(syscall.SIGTERM))
+
+	select {
+	case code := <-exited:
+		assert.Equal(t, 1, code)
+	case <-time.After(time.Second):
+		t.Fatal("second signal did not force an exit")
+	}
+
+	close(release)
+	assert.NoError(t, <-done)
+}
+
+func TestReload_IgnoresSIGHUPAfterStop(t *testing.T) {
+	t.Cleanup(func() { signal.Reset(syscall.SIGHUP) })
+
+	reloaded := make(chan struct{}, 1)
+	stop := Reload(func() { reloaded <- struct{}{} })
+
+	self, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+
+	require.NoError(t, self.Signal(syscall.SIGHUP))
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("SIGHUP did not reload")
+	}
+
+	// Without a handler, SIGHUP would end the test binary here.
+	stop()
+	require.NoError(t, self.Signal(syscall.SIGHUP))
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, reloaded)
+}
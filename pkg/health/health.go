@@ -0,0 +1,201 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+var (
+	ErrNotStarted   = errors.New("application is starting")
+	ErrShuttingDown = errors.New("application is shutting down")
+)
+
+// Check reports the health of a single dependency. A nil error means healthy.
+type Check = func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health aggregates liveness and readiness checks. Readiness also fails
+// until the application is marked as started and as soon as shutdown begins.
+type Health struct {
+	timeout time.Duration
+
+	mu        sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+
+	started      atomic.Bool
+	shuttingDown atomic.Bool
+}
+
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+func (h *Health) AddLivenessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness = append(h.liveness, namedCheck{name, check})
+}
+
+func (h *Health) AddReadinessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness = append(h.readiness, namedCheck{name, check})
+}
+
+// MarkStarted flips the startup probe and allows readiness to succeed.
+func (h *Health) MarkStarted() {
+	h.started.Store(true)
+}
+
+// MarkShuttingDown makes readiness fail so load balancers stop routing new
+// traffic while in-flight requests drain.
+func (h *Health) MarkShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+func (h *Health) Liveness(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := append([]namedCheck(nil), h.liveness...)
+	h.mu.RUnlock()
+
+	return h.run(ctx, checks)
+}
+
+func (h *Health) Readiness(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := append([]namedCheck{
+		{"startup", h.checkStarted},
+		{"shutdown", h.checkNotShuttingDown},
+	}, h.readiness...)
+	h.mu.RUnlock()
+
+	return h.run(ctx, checks)
+}
+
+func (h *Health) Startup(ctx context.Context) Report {
+	return h.run(ctx, []namedCheck{{"startup", h.checkStarted}})
+}
+
+func (h *Health) LivenessHandler() http.Handler {
+	return h.handler(h.Liveness)
+}
+
+func (h *Health) ReadinessHandler() http.Handler {
+	return h.handler(h.Readiness)
+}
+
+func (h *Health) StartupHandler() http.Handler {
+	return h.handler(h.Startup)
+}
+
+func (h *Health) checkStarted(context.Context) error {
+	if !h.started.Load() {
+		return ErrNotStarted
+	}
+	return nil
+}
+
+func (h *Health) checkNotShuttingDown(context.Context) error {
+	if h.shuttingDown.Load() {
+		return ErrShuttingDown
+	}
+	return nil
+}
+
+// run executes the checks concurrently, each bounded by the configured timeout.
+func (h *Health) run(ctx context.Context, checks []namedCheck) Report {
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := runCheck(ctx, c.check)
+			res := CheckResult{Status: StatusOK, Duration: time.Since(start).String()}
+			if err != nil {
+				res.Status = StatusFail
+				res.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = res
+			if err != nil {
+				report.Status = StatusFail
+			}
+		}()
+	}
+
+	wg.Wait()
+	return report
+}
+
+// runCheck returns when the check does or when ctx expires, whichever is first,
+// so that a stuck dependency can't hang the probe.
+func runCheck(ctx context.Context, check Check) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- check(ctx) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Health) handler(probe func(ctx context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := probe(r.Context())
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			slog.Info("error encoding health report", slog.Any("error", err))
+		}
+	})
+}
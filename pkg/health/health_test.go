@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func probe(t *testing.T, h http.Handler) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var report Report
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	return rec.Code, report
+}
+
+func TestReadiness_Lifecycle(t *testing.T) {
+	h := New(time.Second)
+	h.AddReadinessCheck("db", func(context.Context) error { return nil })
+
+	code, report := probe(t, h.ReadinessHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusFail, report.Checks["startup"].Status)
+	assert.Equal(t, StatusOK, report.Checks["db"].Status)
+
+	h.MarkStarted()
+	code, report = probe(t, h.ReadinessHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+
+	h.MarkShuttingDown()
+	code, report = probe(t, h.ReadinessHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, ErrShuttingDown.Error(), report.Checks["shutdown"].Error)
+
+	// Liveness is unaffected by shutdown.
+	code, _ = probe(t, h.LivenessHandler())
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestReadiness_FailingAndStuckChecks(t *testing.T) {
+	h := New(20 * time.Millisecond)
+	h.MarkStarted()
+	h.AddReadinessCheck("sessions", func(context.Context) error { return errors.New("closed") })
+	h.AddReadinessCheck("stuck", func(context.Context) error { select {} })
+
+	code, report := probe(t, h.ReadinessHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "closed", report.Checks["sessions"].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["stuck"].Error)
+}
@@ -0,0 +1,389 @@
+package inmem
+
+import (
+	"container/heap"
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Policy picks the entry to evict when a cache is full.
+type Policy int
+
+const (
+	// LRU evicts the least recently used entry.
+	LRU Policy = iota
+	// LFU evicts the least frequently used entry, the least recently used of
+	// those on a tie.
+	LFU
+)
+
+// EvictionReason tells OnEvict why an entry was removed.
+type EvictionReason string
+
+const (
+	// Expired entries outlived their TTL.
+	Expired EvictionReason = "expired"
+	// Capacity entries were evicted to make room under MaxEntries.
+	Capacity EvictionReason = "capacity"
+)
+
+// CacheOptions configure a store made with NewCache. With the zero value,
+// entries neither expire nor get evicted.
+type CacheOptions[T any] struct {
+	// TTL is how long entries stored with Put live. Zero means forever;
+	// PutWithTTL overrides it per entry.
+	TTL time.Duration
+	// SweepInterval is how often a background sweeper removes expired
+	// entries. It defaults to TTL. Without a sweeper, expired entries are
+	// removed when the store is read. Shutdown stops the sweeper.
+	SweepInterval time.Duration
+	// MaxEntries bounds the store; adding to a full store evicts an entry
+	// chosen by Policy. Zero means unbounded.
+	MaxEntries int
+	// Policy defaults to LRU.
+	Policy Policy
+	// OnEvict is called for every entry that expires or is evicted, after
+	// the store is unlocked, so it may use the store.
+	OnEvict func(key string, value T, reason EvictionReason)
+}
+
+// Stats counts cache lookups and removals since the cache was created.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+}
+
+type cache[T any] struct {
+	opts    CacheOptions[T]
+	now     func() time.Time
+	policy  evictionPolicy // nil when unbounded
+	expires map[string]time.Time
+	queue   expiryQueue
+
+	hits, misses, evictions, expirations atomic.Uint64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+type eviction[T any] struct {
+	key    string
+	value  T
+	reason EvictionReason
+}
+
+// NewCache creates a store whose entries can expire and which can be bounded
+// in size. Stores made with New do neither.
+func NewCache[T any](opts CacheOptions[T]) *InMemoryDB[T] {
+	db := New[T]()
+	c := &cache[T]{
+		opts:    opts,
+		now:     time.Now,
+		expires: make(map[string]time.Time),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if opts.MaxEntries > 0 {
+		switch opts.Policy {
+		case LFU:
+			c.policy = newLFU()
+		default:
+			c.policy = newLRU()
+		}
+	}
+	db.cache = c
+
+	interval := opts.SweepInterval
+	if interval <= 0 {
+		interval = opts.TTL
+	}
+	if interval > 0 {
+		go db.sweep(interval)
+	} else {
+		close(c.done)
+	}
+	return db
+}
+
+func (db *InMemoryDB[T]) sweep(interval time.Duration) {
+	defer close(db.cache.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			db.expire()
+		case <-db.cache.stop:
+			return
+		}
+	}
+}
+
+// PutWithTTL stores value for ttl, or forever if ttl is zero. On a store made
+// with New, where nothing expires, it is Put.
+func (db *InMemoryDB[T]) PutWithTTL(ctx context.Context, key string, value T, ttl time.Duration) {
+	if db.cache == nil {
+		db.Put(ctx, key, value)
+		return
+	}
+	db.mu.Lock()
+	evicted := db.expireLocked()
+	evicted = append(evicted, db.putLocked(key, value, ttl)...)
+	db.bump()
+	db.mu.Unlock()
+	db.notify(evicted)
+}
+
+// Stats returns the cache statistics. Stores made with New keep none.
+func (db *InMemoryDB[T]) Stats() Stats {
+	if db.cache == nil {
+		return Stats{}
+	}
+	c := db.cache
+	return Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+	}
+}
+
+func (db *InMemoryDB[T]) cacheGet(key string) (T, bool) {
+	c := db.cache
+	db.mu.Lock()
+	val, ok := db.data[key]
+	var evicted []eviction[T]
+	if ok && c.expiredLocked(key) {
+		evicted = append(evicted, db.removeLocked(key, Expired))
+		var zero T
+		val, ok = zero, false
+	}
+	if ok {
+		c.hits.Add(1)
+		if c.policy != nil {
+			c.policy.used(key)
+		}
+	} else {
+		c.misses.Add(1)
+	}
+	db.mu.Unlock()
+	db.notify(evicted)
+	return val, ok
+}
+
+// putLocked stores value with the cache bookkeeping, first evicting an entry
+// if a new key would overflow the store. Evicting before adding keeps LFU
+// from evicting the new key, which is always the least frequently used. The
+// caller holds the write lock and bumps the revision.
+func (db *InMemoryDB[T]) putLocked(key string, value T, ttl time.Duration) []eviction[T] {
+	c := db.cache
+	var evicted []eviction[T]
+	_, exists := db.data[key]
+	if c.policy != nil {
+		if exists {
+			c.policy.used(key)
+		} else {
+			for len(db.data) >= c.opts.MaxEntries {
+				evicted = append(evicted, db.removeLocked(c.policy.victim(), Capacity))
+			}
+			c.policy.added(key)
+		}
+	}
+	db.setLocked(key, value)
+	if ttl > 0 {
+		deadline := c.now().Add(ttl)
+		c.expires[key] = deadline
+		heap.Push(&c.queue, expiry{key: key, deadline: deadline})
+	} else {
+		delete(c.expires, key)
+	}
+	return evicted
+}
+
+// removeLocked removes key, which must exist, for reason.
+func (db *InMemoryDB[T]) removeLocked(key string, reason EvictionReason) eviction[T] {
+	c := db.cache
+	e := eviction[T]{key: key, value: db.data[key], reason: reason}
+	db.deleteLocked(key)
+	switch reason {
+	case Expired:
+		c.expirations.Add(1)
+	case Capacity:
+		c.evictions.Add(1)
+	}
+	db.bump()
+	return e
+}
+
+// forgetLocked drops the bookkeeping of a deleted key.
+func (c *cache[T]) forgetLocked(key string) {
+	delete(c.expires, key)
+	if c.policy != nil {
+		c.policy.removed(key)
+	}
+}
+
+func (db *InMemoryDB[T]) expireLocked() []eviction[T] {
+	c := db.cache
+	var evicted []eviction[T]
+	now := c.now()
+	for c.queue.Len() > 0 && !c.queue[0].deadline.After(now) {
+		e := heap.Pop(&c.queue).(expiry)
+		// Entries put again since were queued again; skip the stale ones.
+		if deadline, ok := c.expires[e.key]; ok && deadline.Equal(e.deadline) {
+			evicted = append(evicted, db.removeLocked(e.key, Expired))
+		}
+	}
+	return evicted
+}
+
+func (c *cache[T]) expiredLocked(key string) bool {
+	deadline, ok := c.expires[key]
+	return ok && !deadline.After(c.now())
+}
+
+// expire removes expired entries, if the cache has any.
+func (db *InMemoryDB[T]) expire() {
+	if db.cache == nil {
+		return
+	}
+	db.mu.Lock()
+	evicted := db.expireLocked()
+	db.mu.Unlock()
+	db.notify(evicted)
+}
+
+func (db *InMemoryDB[T]) notify(evicted []eviction[T]) {
+	if db.cache.opts.OnEvict == nil {
+		return
+	}
+	for _, e := range evicted {
+		db.cache.opts.OnEvict(e.key, e.value, e.reason)
+	}
+}
+
+// stopSweeper stops the sweeper and waits for it until ctx is done.
+func (db *InMemoryDB[T]) stopSweeper(ctx context.Context) error {
+	db.cache.stopOnce.Do(func() { close(db.cache.stop) })
+	select {
+	case <-db.cache.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type expiry struct {
+	key      string
+	deadline time.Time
+}
+
+// expiryQueue is a min-heap of deadlines.
+type expiryQueue []expiry
+
+func (q expiryQueue) Len() int           { return len(q) }
+func (q expiryQueue) Less(i, j int) bool { return q[i].deadline.Before(q[j].deadline) }
+func (q expiryQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *expiryQueue) Push(x any)        { *q = append(*q, x.(expiry)) }
+func (q *expiryQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// evictionPolicy tracks the use of keys to pick one to evict.
+type evictionPolicy interface {
+	added(key string)
+	used(key string)
+	removed(key string)
+	// victim is only called while some key is tracked.
+	victim() string
+}
+
+type lru struct {
+	order *list.List // front is most recently used
+	elems map[string]*list.Element
+}
+
+func newLRU() *lru {
+	return &lru{order: list.New(), elems: make(map[string]*list.Element)}
+}
+
+func (p *lru) added(key string) { p.elems[key] = p.order.PushFront(key) }
+func (p *lru) used(key string)  { p.order.MoveToFront(p.elems[key]) }
+func (p *lru) removed(key string) {
+	p.order.Remove(p.elems[key])
+	delete(p.elems, key)
+}
+func (p *lru) victim() string { return p.order.Back().Value.(string) }
+
+type lfuItem struct {
+	key   string
+	count uint64
+	// tick is when the key was last used, to break ties.
+	tick  uint64
+	index int
+}
+
+// lfu is a min-heap of keys by use count.
+type lfu struct {
+	items []*lfuItem
+	keys  map[string]*lfuItem
+	tick  uint64
+}
+
+func newLFU() *lfu {
+	return &lfu{keys: make(map[string]*lfuItem)}
+}
+
+func (p *lfu) added(key string) {
+	p.tick++
+	heap.Push(p, &lfuItem{key: key, count: 1, tick: p.tick})
+}
+
+func (p *lfu) used(key string) {
+	p.tick++
+	it := p.keys[key]
+	it.count++
+	it.tick = p.tick
+	heap.Fix(p, it.index)
+}
+
+func (p *lfu) removed(key string) {
+	heap.Remove(p, p.keys[key].index)
+}
+
+func (p *lfu) victim() string { return p.items[0].key }
+
+func (p *lfu) Len() int { return len(p.items) }
+func (p *lfu) Less(i, j int) bool {
+	a, b := p.items[i], p.items[j]
+	if a.count != b.count {
+		return a.count < b.count
+	}
+	return a.tick < b.tick
+}
+func (p *lfu) Swap(i, j int) {
+	p.items[i], p.items[j] = p.items[j], p.items[i]
+	p.items[i].index = i
+	p.items[j].index = j
+}
+func (p *lfu) Push(x any) {
+	it := x.(*lfuItem)
+	it.index = len(p.items)
+	p.items = append(p.items, it)
+	p.keys[it.key] = it
+}
+func (p *lfu) Pop() any {
+	it := p.items[len(p.items)-1]
+	p.items = p.items[:len(p.items)-1]
+	delete(p.keys, it.key)
+	return it
+}
@@ -0,0 +1,122 @@
+package inmem
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type evicted struct {
+	key    string
+	reason EvictionReason
+}
+
+// newTestCache returns a cache without a sweeper whose clock is advanced by
+// the returned function.
+func newTestCache(opts CacheOptions[int], got *[]evicted) (*InMemoryDB[int], func(time.Duration)) {
+	opts.OnEvict = func(key string, _ int, reason EvictionReason) {
+		*got = append(*got, evicted{key, reason})
+	}
+	db := NewCache(opts)
+	now := time.Unix(0, 0)
+	db.cache.now = func() time.Time { return now }
+	return db, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	var got []evicted
+	db, advance := newTestCache(CacheOptions[int]{TTL: time.Minute, SweepInterval: time.Hour}, &got)
+	defer db.Shutdown(ctx)
+
+	db.Put(ctx, "a", 1)
+	db.PutWithTTL(ctx, "b", 2, 2*time.Minute)
+	db.PutWithTTL(ctx, "c", 3, 0)
+
+	advance(time.Minute)
+	_, ok := db.Get(ctx, "a")
+	assert.False(t, ok)
+	v, ok := db.Get(ctx, "b")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+
+	// Putting again restarts the TTL.
+	db.PutWithTTL(ctx, "b", 4, 2*time.Minute)
+	advance(90 * time.Second)
+	assert.Equal(t, 2, db.Len(ctx))
+
+	advance(time.Minute)
+	assert.Equal(t, []int{3}, db.GetAll(ctx))
+	assert.Equal(t, []evicted{{"a", Expired}, {"b", Expired}}, got)
+	assert.Equal(t, Stats{Hits: 1, Misses: 1, Expirations: 2}, db.Stats())
+}
+
+func TestCacheEviction(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		policy Policy
+		want   string
+	}{
+		{LRU, "b"},
+		{LFU, "a"},
+	} {
+		var got []evicted
+		db, _ := newTestCache(CacheOptions[int]{MaxEntries: 3, Policy: tt.policy}, &got)
+
+		db.Put(ctx, "a", 1)
+		db.Put(ctx, "b", 2)
+		db.Put(ctx, "c", 3)
+		// b is used most often but least recently; a and c tie on use.
+		db.Get(ctx, "b")
+		db.Get(ctx, "b")
+		db.Get(ctx, "a")
+		db.Get(ctx, "c")
+		db.Put(ctx, "d", 4)
+
+		_, ok := db.Get(ctx, tt.want)
+		assert.False(t, ok, tt.policy)
+		assert.Equal(t, 3, db.Len(ctx))
+		assert.Equal(t, []evicted{{tt.want, Capacity}}, got)
+		assert.Equal(t, uint64(1), db.Stats().Evictions)
+
+		// Deleted keys are no longer candidates.
+		db.Delete(ctx, "d")
+		db.Put(ctx, "e", 5)
+		assert.Len(t, got, 1)
+	}
+}
+
+func TestCacheSweeper(t *testing.T) {
+	ctx := context.Background()
+	expired := make(chan string, 1)
+	db := NewCache(CacheOptions[int]{
+		TTL:           time.Millisecond,
+		SweepInterval: time.Millisecond,
+		OnEvict: func(key string, _ int, _ EvictionReason) {
+			expired <- key
+		},
+	})
+	db.Put(ctx, "a", 1)
+
+	select {
+	case key := <-expired:
+		assert.Equal(t, "a", key)
+	case <-time.After(time.Second):
+		t.Fatal("entry was not swept")
+	}
+	require.NoError(t, db.Shutdown(ctx))
+	require.NoError(t, db.Shutdown(ctx))
+}
+
+func TestPlainStoreHasNoCache(t *testing.T) {
+	ctx := context.Background()
+	db := New[int]()
+	db.PutWithTTL(ctx, "a", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	_, ok := db.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, Stats{}, db.Stats())
+}
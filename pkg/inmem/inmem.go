@@ -3,21 +3,43 @@ package inmem
 import (
 	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Note: this is a naive implementation.
 type InMemoryDB[T any] struct {
 	data map[string]T
+	// revs holds the revision that stored each key, for transactions.
+	revs map[string]uint64
 	mu   sync.RWMutex
+	// id orders the writer locks of stores committed together.
+	id uint64
+
+	// revision is bumped on every change so callers can cheaply tell whether
+	// the store changed, e.g. to compute ETags.
+	revision atomic.Uint64
+	modified atomic.Int64
+
+	// cache is set by NewCache; stores made with New neither expire nor
+	// evict entries.
+	cache *cache[T]
 }
 
 func New[T any]() *InMemoryDB[T] {
-	return &InMemoryDB[T]{
+	db := &InMemoryDB[T]{
 		data: make(map[string]T),
+		revs: make(map[string]uint64),
+		id:   storeIDs.Add(1),
 	}
+	db.modified.Store(time.Now().UnixNano())
+	return db
 }
 
 func (db *InMemoryDB[T]) Get(_ context.Context, key string) (T, bool) {
+	if db.cache != nil {
+		return db.cacheGet(key)
+	}
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 	val, ok := db.data[key]
@@ -25,11 +47,11 @@ func (db *InMemoryDB[T]) Get(_ context.Context, key string) (T, bool) {
 }
 
 func (db *InMemoryDB[T]) GetAll(_ context.Context) []T {
-	res := make([]T, 0, len(db.data))
-
+	db.expire()
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 
+	res := make([]T, 0, len(db.data))
 	for _, v := range db.data {
 		res = append(res, v)
 	}
@@ -37,27 +59,114 @@ func (db *InMemoryDB[T]) GetAll(_ context.Context) []T {
 	return res
 }
 
-func (db *InMemoryDB[T]) Put(_ context.Context, key string, value T) {
+// Snapshot copies the store. ShardedDB takes snapshots without copying.
+func (db *InMemoryDB[T]) Snapshot(_ context.Context) *Snapshot[T] {
+	db.expire()
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	t := newTable[T]()
+	for k, v := range db.data {
+		t.set(k, v, db.revs[k])
+	}
+	return &Snapshot[T]{v: &version[T]{
+		shards:   []*table[T]{t},
+		count:    len(db.data),
+		revision: db.revision.Load(),
+		modified: db.modified.Load(),
+	}}
+}
+
+func (db *InMemoryDB[T]) Put(ctx context.Context, key string, value T) {
+	if db.cache != nil {
+		db.PutWithTTL(ctx, key, value, db.cache.opts.TTL)
+		return
+	}
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	db.data[key] = value
+	db.setLocked(key, value)
+	db.bump()
+}
+
+// PutMany stores every entry under a single lock, as one change.
+func (db *InMemoryDB[T]) PutMany(_ context.Context, entries map[string]T) {
+	if len(entries) == 0 {
+		return
+	}
+	if db.cache != nil {
+		db.mu.Lock()
+		evicted := db.expireLocked()
+		for k, v := range entries {
+			evicted = append(evicted, db.putLocked(k, v, db.cache.opts.TTL)...)
+		}
+		db.bump()
+		db.mu.Unlock()
+		db.notify(evicted)
+		return
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for k, v := range entries {
+		db.setLocked(k, v)
+	}
+	db.bump()
 }
 
 func (db *InMemoryDB[T]) Delete(_ context.Context, key string) (T, bool) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 	temp, ok := db.data[key]
-	delete(db.data, key)
+	if ok {
+		db.deleteLocked(key)
+		db.bump()
+	}
 	return temp, ok
 }
 
+// setLocked stores value under key as part of the change the caller is
+// about to bump the revision for.
+func (db *InMemoryDB[T]) setLocked(key string, value T) {
+	db.data[key] = value
+	db.revs[key] = db.revision.Load() + 1
+}
+
+// deleteLocked removes key along with its bookkeeping, if it exists.
+func (db *InMemoryDB[T]) deleteLocked(key string) {
+	if _, ok := db.data[key]; !ok {
+		return
+	}
+	delete(db.data, key)
+	delete(db.revs, key)
+	if db.cache != nil {
+		db.cache.forgetLocked(key)
+	}
+}
+
+// bump must be called with the write lock held.
+func (db *InMemoryDB[T]) bump() {
+	db.modified.Store(time.Now().UnixNano())
+	db.revision.Add(1)
+}
+
+// Revision returns a counter that increases with every change to the store
+// and the time of the last change (or of creation if there was none).
+func (db *InMemoryDB[T]) Revision(_ context.Context) (uint64, time.Time) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.revision.Load(), time.Unix(0, db.modified.Load())
+}
+
 func (db *InMemoryDB[T]) Len(_ context.Context) int {
+	db.expire()
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 	return len(db.data)
 }
 
+// Shutdown stops the sweeper of a store made with NewCache.
 func (db *InMemoryDB[T]) Shutdown(ctx context.Context) error {
+	if db.cache != nil {
+		return db.stopSweeper(ctx)
+	}
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -65,3 +174,21 @@ func (db *InMemoryDB[T]) Shutdown(ctx context.Context) error {
 		return nil
 	}
 }
+
+// Ping reports whether the store lock can be acquired before ctx expires,
+// which catches a deadlocked writer.
+func (db *InMemoryDB[T]) Ping(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		db.mu.RLock()
+		db.mu.RUnlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,387 @@
+package inmem
+
+import (
+	"context"
+	"hash/maphash"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultShards is the shard count used when ShardedOptions.Shards is zero.
+const DefaultShards = 32
+
+type ShardedOptions struct {
+	// Shards is how many tables, each with its own lock, the keys are spread
+	// over. More shards let more writers run at once and make the copy that
+	// the first write after a Snapshot does smaller.
+	Shards int
+	// Hash maps a key to its shard. It defaults to maphash with a random seed.
+	Hash func(key string) uint64
+}
+
+// entry is a value along with the revision that stored it, which tells
+// transactions whether the key changed since they read it.
+type entry[T any] struct {
+	value T
+	rev   uint64
+}
+
+// version is an immutable state of the store, as held by a Snapshot.
+type version[T any] struct {
+	shards   []*table[T]
+	count    int
+	revision uint64
+	modified int64
+}
+
+func (v *version[T]) get(hash func(string) uint64, key string) (entry[T], bool) {
+	return v.shards[shardIndex(hash, key, len(v.shards))].get(key)
+}
+
+var storeIDs atomic.Uint64
+
+// table holds entries in slices, indexed by key, so that scans copy values
+// instead of walking a map. Deleting moves the last entry into the gap.
+type table[T any] struct {
+	index  map[string]int
+	keys   []string
+	values []T
+	revs   []uint64
+}
+
+func newTable[T any]() *table[T] {
+	return &table[T]{index: make(map[string]int)}
+}
+
+func (t *table[T]) get(key string) (entry[T], bool) {
+	i, ok := t.index[key]
+	if !ok {
+		return entry[T]{}, false
+	}
+	return entry[T]{value: t.values[i], rev: t.revs[i]}, true
+}
+
+func (t *table[T]) set(key string, value T, rev uint64) (added bool) {
+	if i, ok := t.index[key]; ok {
+		t.values[i] = value
+		t.revs[i] = rev
+		return false
+	}
+	t.index[key] = len(t.keys)
+	t.keys = append(t.keys, key)
+	t.values = append(t.values, value)
+	t.revs = append(t.revs, rev)
+	return true
+}
+
+func (t *table[T]) delete(key string) {
+	i := t.index[key]
+	last := len(t.keys) - 1
+	if i != last {
+		t.keys[i], t.values[i], t.revs[i] = t.keys[last], t.values[last], t.revs[last]
+		t.index[t.keys[i]] = i
+	}
+	var zero T
+	t.keys[last], t.values[last] = "", zero // let go of what they point to
+	t.keys, t.values, t.revs = t.keys[:last], t.values[:last], t.revs[:last]
+	delete(t.index, key)
+}
+
+func (t *table[T]) clone() *table[T] {
+	return &table[T]{
+		index:  maps.Clone(t.index),
+		keys:   slices.Clone(t.keys),
+		values: slices.Clone(t.values),
+		revs:   slices.Clone(t.revs),
+	}
+}
+
+// shard is a table with its own lock, so writes to different shards don't
+// wait for each other.
+type shard[T any] struct {
+	mu sync.RWMutex
+	t  *table[T]
+	// shared is set while a snapshot holds t; the next write copies t first.
+	shared bool
+	_      [64]byte // keeps the locks of neighbouring shards off one cache line
+}
+
+// setLocked must be called with the shard's write lock held.
+func (s *shard[T]) setLocked(key string, value T, rev uint64) (added bool) {
+	s.own()
+	return s.t.set(key, value, rev)
+}
+
+// deleteLocked must be called with the shard's write lock held.
+func (s *shard[T]) deleteLocked(key string) (removed bool) {
+	if _, ok := s.t.index[key]; !ok {
+		return false
+	}
+	s.own()
+	s.t.delete(key)
+	return true
+}
+
+// own copies t if a snapshot holds it, so writes don't show in the snapshot.
+func (s *shard[T]) own() {
+	if s.shared {
+		s.t = s.t.clone()
+		s.shared = false
+	}
+}
+
+// ShardedDB has the API of InMemoryDB but spreads the keys over shards with
+// a lock each, so writers to different shards run in parallel and readers
+// only wait for writers to the shard they read. A reader never sees part of
+// a write, including a PutMany, which locks every shard it touches.
+//
+// Snapshots share the shards with the store: taking one marks the shards,
+// and only the first write to each marked shard copies it. A snapshot taken
+// while nothing changed is the previous one, so frequent snapshots of a
+// rarely written store are free.
+type ShardedDB[T any] struct {
+	hash func(string) uint64
+	// id orders the writer locks of stores committed together.
+	id     uint64
+	shards []shard[T]
+
+	// revision and modified change with the shard locks of the write held.
+	revision atomic.Uint64
+	modified atomic.Int64
+	count    atomic.Int64
+	// snapshot is the last one taken, reused while revision is unchanged.
+	snapshot atomic.Pointer[version[T]]
+}
+
+func NewSharded[T any](opts ShardedOptions) *ShardedDB[T] {
+	if opts.Shards <= 0 {
+		opts.Shards = DefaultShards
+	}
+	if opts.Hash == nil {
+		seed := maphash.MakeSeed()
+		opts.Hash = func(key string) uint64 { return maphash.String(seed, key) }
+	}
+
+	db := &ShardedDB[T]{
+		hash:   opts.Hash,
+		id:     storeIDs.Add(1),
+		shards: make([]shard[T], opts.Shards),
+	}
+	for i := range db.shards {
+		db.shards[i].t = newTable[T]()
+	}
+	db.modified.Store(time.Now().UnixNano())
+	return db
+}
+
+func (db *ShardedDB[T]) shardIndex(key string, shards int) int {
+	return shardIndex(db.hash, key, shards)
+}
+
+func shardIndex(hash func(string) uint64, key string, shards int) int {
+	if shards == 1 {
+		return 0
+	}
+	return int(hash(key) % uint64(shards))
+}
+
+func (db *ShardedDB[T]) shard(key string) *shard[T] {
+	return &db.shards[db.shardIndex(key, len(db.shards))]
+}
+
+// lockAll takes the write lock of every shard, in order so that writers
+// locking several shards can't deadlock.
+func (db *ShardedDB[T]) lockAll() {
+	for i := range db.shards {
+		db.shards[i].mu.Lock()
+	}
+}
+
+func (db *ShardedDB[T]) unlockAll() {
+	for i := range db.shards {
+		db.shards[i].mu.Unlock()
+	}
+}
+
+// bump starts a change and returns its revision. It must be called with the
+// write locks of the shards the change touches held.
+func (db *ShardedDB[T]) bump() uint64 {
+	db.modified.Store(time.Now().UnixNano())
+	return db.revision.Add(1)
+}
+
+// Snapshot returns the current state of the store. It stays the same however
+// the store changes afterwards.
+func (db *ShardedDB[T]) Snapshot(_ context.Context) *Snapshot[T] {
+	if v := db.snapshot.Load(); v != nil && v.revision == db.revision.Load() {
+		return &Snapshot[T]{hash: db.hash, v: v}
+	}
+
+	db.lockAll()
+	v := &version[T]{
+		shards:   make([]*table[T], len(db.shards)),
+		revision: db.revision.Load(),
+		modified: db.modified.Load(),
+	}
+	for i := range db.shards {
+		s := &db.shards[i]
+		s.shared = true
+		v.shards[i] = s.t
+		v.count += len(s.t.keys)
+	}
+	db.unlockAll()
+
+	db.snapshot.Store(v)
+	return &Snapshot[T]{hash: db.hash, v: v}
+}
+
+func (db *ShardedDB[T]) Get(_ context.Context, key string) (T, bool) {
+	s := db.shard(key)
+	s.mu.RLock()
+	e, ok := s.t.get(key)
+	s.mu.RUnlock()
+	return e.value, ok
+}
+
+// GetAll holds every shard's read lock while copying, so it sees each write
+// entirely or not at all, without the cost of a snapshot.
+func (db *ShardedDB[T]) GetAll(_ context.Context) []T {
+	for i := range db.shards {
+		db.shards[i].mu.RLock()
+	}
+	res := make([]T, 0, db.count.Load())
+	for i := range db.shards {
+		res = append(res, db.shards[i].t.values...)
+		db.shards[i].mu.RUnlock()
+	}
+	return res
+}
+
+func (db *ShardedDB[T]) Put(_ context.Context, key string, value T) {
+	s := db.shard(key)
+	s.mu.Lock()
+	if s.setLocked(key, value, db.bump()) {
+		db.count.Add(1)
+	}
+	s.mu.Unlock()
+}
+
+// PutMany stores every entry as one change: readers see all of them or none.
+func (db *ShardedDB[T]) PutMany(_ context.Context, entries map[string]T) {
+	if len(entries) == 0 {
+		return
+	}
+	touched := make([]bool, len(db.shards))
+	for k := range entries {
+		touched[db.shardIndex(k, len(db.shards))] = true
+	}
+	db.lockShards(touched)
+	defer db.unlockShards(touched)
+
+	rev := db.bump()
+	for k, v := range entries {
+		if db.shard(k).setLocked(k, v, rev) {
+			db.count.Add(1)
+		}
+	}
+}
+
+func (db *ShardedDB[T]) lockShards(touched []bool) {
+	for i, ok := range touched {
+		if ok {
+			db.shards[i].mu.Lock()
+		}
+	}
+}
+
+func (db *ShardedDB[T]) unlockShards(touched []bool) {
+	for i, ok := range touched {
+		if ok {
+			db.shards[i].mu.Unlock()
+		}
+	}
+}
+
+func (db *ShardedDB[T]) Delete(_ context.Context, key string) (T, bool) {
+	s := db.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.t.get(key)
+	if ok {
+		s.deleteLocked(key)
+		db.count.Add(-1)
+		db.bump()
+	}
+	return e.value, ok
+}
+
+// Revision returns a counter that increases with every change to the store
+// and the time of the last change (or of creation if there was none).
+func (db *ShardedDB[T]) Revision(_ context.Context) (uint64, time.Time) {
+	return db.revision.Load(), time.Unix(0, db.modified.Load())
+}
+
+func (db *ShardedDB[T]) Len(_ context.Context) int {
+	return int(db.count.Load())
+}
+
+func (db *ShardedDB[T]) Shutdown(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// Ping reports whether every shard lock can be acquired before ctx expires,
+// which catches a deadlocked writer.
+func (db *ShardedDB[T]) Ping(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		db.lockAll()
+		db.unlockAll()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Snapshot is a read-only version of a store. It is safe for concurrent use
+// and holds on to its data until it is garbage collected.
+type Snapshot[T any] struct {
+	hash func(string) uint64
+	v    *version[T]
+}
+
+func (s *Snapshot[T]) Get(key string) (T, bool) {
+	e, ok := s.v.get(s.hash, key)
+	return e.value, ok
+}
+
+func (s *Snapshot[T]) GetAll() []T {
+	res := make([]T, 0, s.v.count)
+	for _, t := range s.v.shards {
+		res = append(res, t.values...)
+	}
+	return res
+}
+
+func (s *Snapshot[T]) Len() int {
+	return s.v.count
+}
+
+// Revision identifies the version: snapshots with the same revision hold the
+// same data.
+func (s *Snapshot[T]) Revision() (uint64, time.Time) {
+	return s.v.revision, time.Unix(0, s.v.modified)
+}
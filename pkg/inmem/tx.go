@@ -0,0 +1,298 @@
+package inmem
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+)
+
+var (
+	// ErrConflict is returned by Commit when a key the transaction read or
+	// wrote was changed by someone else since the transaction began. The
+	// transaction is rolled back and may be retried from the start.
+	ErrConflict = errors.New("transaction conflicts with a concurrent change")
+	// ErrTxDone is returned when committing a transaction that was already
+	// committed or rolled back.
+	ErrTxDone = errors.New("transaction already committed or rolled back")
+)
+
+type txWrite[T any] struct {
+	value   T
+	deleted bool
+}
+
+// stamp is what a transaction saw of a key: the revision that stored it and
+// whether it was there.
+type stamp struct {
+	rev uint64
+	ok  bool
+}
+
+// txStore is the side of a store that transactions commit to. The Locked
+// methods are called with the writer lock held.
+type txStore[T any] interface {
+	storeID() uint64
+	lock()
+	unlock()
+	revisionLocked() uint64
+	latestLocked(key string) (entry[T], bool)
+	// applyLocked stores the writes as one change. The returned func, if
+	// any, is called after the store is unlocked.
+	applyLocked(writes map[string]txWrite[T]) func()
+}
+
+// Tx is an optimistic transaction on an InMemoryDB or a ShardedDB. It keeps
+// its writes to itself until Commit, which fails if a key the transaction
+// read or wrote was changed by someone else since it first touched the key.
+// A Tx is not safe for concurrent use.
+type Tx[T any] struct {
+	db txStore[T]
+	// read returns what the transaction sees of keys it didn't write.
+	read   func(key string) (entry[T], bool)
+	begin  uint64
+	seen   map[string]stamp
+	writes map[string]txWrite[T]
+	done   bool
+}
+
+func newTx[T any](db txStore[T], begin uint64, read func(key string) (entry[T], bool)) *Tx[T] {
+	return &Tx[T]{
+		db:     db,
+		read:   read,
+		begin:  begin,
+		seen:   make(map[string]stamp),
+		writes: make(map[string]txWrite[T]),
+	}
+}
+
+// Begin starts a transaction on a snapshot of the store. Its reads see that
+// snapshot no matter what is committed meanwhile.
+func (db *ShardedDB[T]) Begin(ctx context.Context) *Tx[T] {
+	base := db.Snapshot(ctx).v
+	return newTx[T](db, base.revision, func(key string) (entry[T], bool) {
+		return base.get(db.hash, key)
+	})
+}
+
+// Begin starts a transaction on the store. Unlike on a ShardedDB, its reads
+// see the latest committed value of keys it hasn't touched yet; Commit still
+// fails if a touched key changed afterwards.
+func (db *InMemoryDB[T]) Begin(_ context.Context) *Tx[T] {
+	return newTx[T](db, db.revision.Load(), func(key string) (entry[T], bool) {
+		db.mu.RLock()
+		defer db.mu.RUnlock()
+		return db.latestLocked(key)
+	})
+}
+
+// touch remembers what the transaction saw of key the first time.
+func (tx *Tx[T]) touch(key string) (entry[T], bool) {
+	e, ok := tx.read(key)
+	if _, seen := tx.seen[key]; !seen {
+		tx.seen[key] = stamp{rev: e.rev, ok: ok}
+	}
+	return e, ok
+}
+
+func (tx *Tx[T]) Get(key string) (T, bool) {
+	if w, ok := tx.writes[key]; ok {
+		return w.value, !w.deleted
+	}
+	e, ok := tx.touch(key)
+	return e.value, ok
+}
+
+func (tx *Tx[T]) Put(key string, value T) {
+	if _, ok := tx.writes[key]; !ok {
+		tx.touch(key)
+	}
+	tx.writes[key] = txWrite[T]{value: value}
+}
+
+// Delete removes key within the transaction and returns the value it had.
+func (tx *Tx[T]) Delete(key string) (T, bool) {
+	val, ok := tx.Get(key)
+	if ok {
+		var zero T
+		tx.writes[key] = txWrite[T]{value: zero, deleted: true}
+	}
+	return val, ok
+}
+
+// Commit makes the writes visible to readers at once, or returns ErrConflict
+// and changes nothing.
+func (tx *Tx[T]) Commit(ctx context.Context) error {
+	return CommitAll(ctx, tx)
+}
+
+// Rollback discards the writes. It does nothing after Commit.
+func (tx *Tx[T]) Rollback() {
+	tx.done = true
+}
+
+// Committer is a transaction that CommitAll can commit. *Tx implements it.
+type Committer interface {
+	Rollback()
+
+	isDone() bool
+	store() uint64
+	lock()
+	unlock()
+	validate() error
+	apply() func()
+}
+
+// CommitAll commits transactions on different stores together: either all of
+// them are applied or, if any conflicts, none is. Each store switches to its
+// new version atomically, but a reader of several stores may see one switch
+// before another.
+func CommitAll(ctx context.Context, txs ...Committer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if tx.isDone() {
+			return ErrTxDone
+		}
+	}
+	defer func() {
+		for _, tx := range txs {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock in a fixed order so that concurrent CommitAll calls can't deadlock.
+	sorted := slices.SortedFunc(slices.Values(txs), func(a, b Committer) int {
+		return cmp.Compare(a.store(), b.store())
+	})
+	for i, tx := range sorted {
+		if i > 0 && sorted[i-1].store() == tx.store() {
+			return errors.New("inmem: transactions committed together must be on different stores")
+		}
+	}
+	for _, tx := range sorted {
+		tx.lock()
+	}
+	unlock := func() {
+		for _, tx := range sorted {
+			tx.unlock()
+		}
+	}
+
+	for _, tx := range sorted {
+		if err := tx.validate(); err != nil {
+			unlock()
+			return err
+		}
+	}
+	var after []func()
+	for _, tx := range sorted {
+		if f := tx.apply(); f != nil {
+			after = append(after, f)
+		}
+	}
+	unlock()
+	for _, f := range after {
+		f()
+	}
+	return nil
+}
+
+func (tx *Tx[T]) isDone() bool  { return tx.done }
+func (tx *Tx[T]) store() uint64 { return tx.db.storeID() }
+func (tx *Tx[T]) lock()         { tx.db.lock() }
+func (tx *Tx[T]) unlock()       { tx.db.unlock() }
+
+// validate must be called with the writer lock held. A key conflicts when
+// the revision that stored it, or its presence, differs from what the
+// transaction first saw.
+func (tx *Tx[T]) validate() error {
+	if tx.db.revisionLocked() == tx.begin {
+		return nil
+	}
+	for key, s := range tx.seen {
+		e, ok := tx.db.latestLocked(key)
+		if ok != s.ok || e.rev != s.rev {
+			return ErrConflict
+		}
+	}
+	return nil
+}
+
+// apply must be called with the writer lock held, after validate.
+func (tx *Tx[T]) apply() func() {
+	if len(tx.writes) == 0 {
+		return nil
+	}
+	return tx.db.applyLocked(tx.writes)
+}
+
+func (db *ShardedDB[T]) storeID() uint64 { return db.id }
+func (db *ShardedDB[T]) lock()           { db.lockAll() }
+func (db *ShardedDB[T]) unlock()         { db.unlockAll() }
+
+func (db *ShardedDB[T]) revisionLocked() uint64 {
+	return db.revision.Load()
+}
+
+func (db *ShardedDB[T]) latestLocked(key string) (entry[T], bool) {
+	return db.shard(key).t.get(key)
+}
+
+func (db *ShardedDB[T]) applyLocked(writes map[string]txWrite[T]) func() {
+	rev := db.bump()
+	for key, w := range writes {
+		s := db.shard(key)
+		switch {
+		case w.deleted:
+			if s.deleteLocked(key) {
+				db.count.Add(-1)
+			}
+		case s.setLocked(key, w.value, rev):
+			db.count.Add(1)
+		}
+	}
+	return nil
+}
+
+func (db *InMemoryDB[T]) storeID() uint64 { return db.id }
+func (db *InMemoryDB[T]) lock()           { db.mu.Lock() }
+func (db *InMemoryDB[T]) unlock()         { db.mu.Unlock() }
+
+func (db *InMemoryDB[T]) revisionLocked() uint64 {
+	return db.revision.Load()
+}
+
+// latestLocked treats entries that expired but weren't removed yet as gone.
+func (db *InMemoryDB[T]) latestLocked(key string) (entry[T], bool) {
+	val, ok := db.data[key]
+	if !ok || db.cache != nil && db.cache.expiredLocked(key) {
+		return entry[T]{}, false
+	}
+	return entry[T]{value: val, rev: db.revs[key]}, true
+}
+
+// applyLocked stores the writes like PutMany, with the cache's TTL, eviction
+// and OnEvict applying as usual.
+func (db *InMemoryDB[T]) applyLocked(writes map[string]txWrite[T]) func() {
+	var evicted []eviction[T]
+	if db.cache != nil {
+		evicted = db.expireLocked()
+	}
+	for key, w := range writes {
+		switch {
+		case w.deleted:
+			db.deleteLocked(key)
+		case db.cache != nil:
+			evicted = append(evicted, db.putLocked(key, w.value, db.cache.opts.TTL)...)
+		default:
+			db.setLocked(key, w.value)
+		}
+	}
+	db.bump()
+	if len(evicted) == 0 {
+		return nil
+	}
+	return func() { db.notify(evicted) }
+}
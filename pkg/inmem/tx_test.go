@@ -0,0 +1,282 @@
+package inmem
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTx_ReadYourWrites(t *testing.T) {
+	ctx := context.Background()
+	db := NewSharded[int](ShardedOptions{Shards: 4})
+	db.PutMany(ctx, map[string]int{"a": 1, "b": 2})
+
+	tx := db.Begin(ctx)
+	tx.Put("a", 10)
+	tx.Put("c", 3)
+	old, ok := tx.Delete("b")
+	assert.True(t, ok)
+	assert.Equal(t, 2, old)
+
+	v, _ := tx.Get("a")
+	assert.Equal(t, 10, v)
+	_, ok = tx.Get("b")
+	assert.False(t, ok)
+
+	// Nothing is visible before Commit.
+	v, _ = db.Get(ctx, "a")
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 2, db.Len(ctx))
+
+	rev, _ := db.Revision(ctx)
+	require.NoError(t, tx.Commit(ctx))
+	after, _ := db.Revision(ctx)
+	assert.Equal(t, rev+1, after, "a commit is one change")
+
+	v, _ = db.Get(ctx, "a")
+	assert.Equal(t, 10, v)
+	_, ok = db.Get(ctx, "b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, db.Len(ctx))
+
+	assert.ErrorIs(t, tx.Commit(ctx), ErrTxDone)
+}
+
+func TestTx_Rollback(t *testing.T) {
+	ctx := context.Background()
+	db := NewSharded[int](ShardedOptions{})
+
+	tx := db.Begin(ctx)
+	tx.Put("a", 1)
+	tx.Rollback()
+
+	assert.ErrorIs(t, tx.Commit(ctx), ErrTxDone)
+	assert.Zero(t, db.Len(ctx))
+}
+
+// txDB is what the transaction tests need from either store.
+type txDB interface {
+	Get(ctx context.Context, key string) (int, bool)
+	Put(ctx context.Context, key string, value int)
+	Delete(ctx context.Context, key string) (int, bool)
+	Revision(ctx context.Context) (uint64, time.Time)
+	Begin(ctx context.Context) *Tx[int]
+}
+
+var txStores = []struct {
+	name string
+	new  func() txDB
+}{
+	{"sharded", func() txDB { return NewSharded[int](ShardedOptions{}) }},
+	{"mutex", func() txDB { return New[int]() }},
+	{"cache", func() txDB { return NewCache(CacheOptions[int]{MaxEntries: 100}) }},
+}
+
+func TestTx_Conflicts(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		tx    func(tx *Tx[int])
+		other func(db txDB)
+		want  error
+	}{
+		{
+			name:  "read key changed",
+			tx:    func(tx *Tx[int]) { v, _ := tx.Get("a"); tx.Put("b", v) },
+			other: func(db txDB) { db.Put(ctx, "a", 5) },
+			want:  ErrConflict,
+		},
+		{
+			name:  "read key deleted",
+			tx:    func(tx *Tx[int]) { tx.Get("a") },
+			other: func(db txDB) { db.Delete(ctx, "a") },
+			want:  ErrConflict,
+		},
+		{
+			name:  "missing key created",
+			tx:    func(tx *Tx[int]) { tx.Get("new") },
+			other: func(db txDB) { db.Put(ctx, "new", 1) },
+			want:  ErrConflict,
+		},
+		{
+			name:  "written key changed",
+			tx:    func(tx *Tx[int]) { tx.Put("a", 2) },
+			other: func(db txDB) { db.Put(ctx, "a", 3) },
+			want:  ErrConflict,
+		},
+		{
+			name:  "unrelated key changed",
+			tx:    func(tx *Tx[int]) { tx.Get("a"); tx.Put("a", 2) },
+			other: func(db txDB) { db.Put(ctx, "z", 3) },
+		},
+		{
+			name:  "same value written again",
+			tx:    func(tx *Tx[int]) { tx.Get("a") },
+			other: func(db txDB) { db.Put(ctx, "a", 1) },
+			want:  ErrConflict,
+		},
+	}
+	for _, store := range txStores {
+		for _, tt := range tests {
+			t.Run(store.name+"/"+tt.name, func(t *testing.T) {
+				db := store.new()
+				db.Put(ctx, "a", 1)
+
+				tx := db.Begin(ctx)
+				tt.tx(tx)
+				tt.other(db)
+				rev, _ := db.Revision(ctx)
+
+				err := tx.Commit(ctx)
+				assert.ErrorIs(t, err, tt.want)
+				if tt.want != nil {
+					after, _ := db.Revision(ctx)
+					assert.Equal(t, rev, after, "a conflicting commit changes nothing")
+				}
+			})
+		}
+	}
+}
+
+// TestTx_ConcurrentIncrements has transactions race to increment the same
+// counter. Conflicts make the losers retry, so no increment is lost.
+func TestTx_ConcurrentIncrements(t *testing.T) {
+	for _, store := range txStores {
+		t.Run(store.name, func(t *testing.T) { testConcurrentIncrements(t, store.new()) })
+	}
+}
+
+func testConcurrentIncrements(t *testing.T, db txDB) {
+	ctx := context.Background()
+	const workers, increments = 8, 50
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		conflicts int
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range increments {
+				for {
+					tx := db.Begin(ctx)
+					n, _ := tx.Get("counter")
+					runtime.Gosched() // let others commit in between
+					tx.Put("counter", n+1)
+					err := tx.Commit(ctx)
+					if err == nil {
+						break
+					}
+					if !assert.ErrorIs(t, err, ErrConflict) {
+						return
+					}
+					mu.Lock()
+					conflicts++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, _ := db.Get(ctx, "counter")
+	assert.Equal(t, workers*increments, n)
+	assert.NotZero(t, conflicts, "transactions should have interleaved")
+}
+
+func TestCommitAll(t *testing.T) {
+	ctx := context.Background()
+	// The stores differ so that transactions on both kinds commit together.
+	left := NewSharded[int](ShardedOptions{})
+	right := New[int]()
+	left.Put(ctx, "balance", 100)
+	right.Put(ctx, "balance", 100)
+
+	transfer := func(from, to txDB, amount int) error {
+		txFrom, txTo := from.Begin(ctx), to.Begin(ctx)
+		a, _ := txFrom.Get("balance")
+		b, _ := txTo.Get("balance")
+		runtime.Gosched()
+		txFrom.Put("balance", a-amount)
+		txTo.Put("balance", b+amount)
+		return CommitAll(ctx, txFrom, txTo)
+	}
+
+	t.Run("conflict in one store applies neither", func(t *testing.T) {
+		txLeft, txRight := left.Begin(ctx), right.Begin(ctx)
+		txLeft.Put("balance", 0)
+		v, _ := txRight.Get("balance")
+		txRight.Put("balance", v+100)
+		right.Put(ctx, "balance", 100)
+
+		assert.ErrorIs(t, CommitAll(ctx, txLeft, txRight), ErrConflict)
+		v, _ = left.Get(ctx, "balance")
+		assert.Equal(t, 100, v)
+	})
+
+	t.Run("same store twice", func(t *testing.T) {
+		assert.Error(t, CommitAll(ctx, left.Begin(ctx), left.Begin(ctx)))
+	})
+
+	t.Run("concurrent transfers in both directions", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var from, to txDB = left, right
+				if i%2 == 1 {
+					from, to = right, left
+				}
+				for range 25 {
+					for {
+						err := transfer(from, to, 1)
+						if !errors.Is(err, ErrConflict) {
+							assert.NoError(t, err)
+							break
+						}
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		a, _ := left.Get(ctx, "balance")
+		b, _ := right.Get(ctx, "balance")
+		assert.Equal(t, 200, a+b, "money is neither created nor lost")
+		assert.Equal(t, 100, a, "as many transfers each way")
+	})
+}
+
+func TestTx_CacheEvicts(t *testing.T) {
+	ctx := context.Background()
+	var db *InMemoryDB[int]
+	var evicted []string
+	db = NewCache(CacheOptions[int]{
+		MaxEntries: 2,
+		OnEvict: func(key string, _ int, _ EvictionReason) {
+			_, _ = db.Get(ctx, key) // the store is unlocked by now
+			evicted = append(evicted, key)
+		},
+	})
+	db.Put(ctx, "a", 1)
+	db.Put(ctx, "b", 2)
+
+	tx := db.Begin(ctx)
+	tx.Put("c", 3)
+	require.NoError(t, tx.Commit(ctx))
+
+	assert.Equal(t, []string{"a"}, evicted)
+	assert.Equal(t, 2, db.Len(ctx))
+	v, _ := db.Get(ctx, "c")
+	assert.Equal(t, 3, v)
+}
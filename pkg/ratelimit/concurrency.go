@@ -0,0 +1,41 @@
+package ratelimit
+
+// Concurrency allows a limited number of operations to run at once. Callers
+// that don't get a slot are expected to give up rather than queue.
+type Concurrency struct {
+	slots chan struct{}
+}
+
+// NewConcurrency allows n operations at once. n <= 0 means no limit.
+func NewConcurrency(n int) *Concurrency {
+	c := &Concurrency{}
+	if n > 0 {
+		c.slots = make(chan struct{}, n)
+	}
+	return c
+}
+
+// Limit returns the number of slots, or 0 if there is no limit.
+func (c *Concurrency) Limit() int {
+	return cap(c.slots)
+}
+
+// TryAcquire takes a slot if one is free. Every successful call must be
+// followed by Release.
+func (c *Concurrency) TryAcquire() bool {
+	if c.slots == nil {
+		return true
+	}
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Concurrency) Release() {
+	if c.slots != nil {
+		<-c.slots
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLimiter(p Policy) (*Limiter, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(p)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l, now := newTestLimiter(Policy{Limit: 2, Period: time.Second})
+
+	first := l.Allow("a")
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 2, first.Limit)
+	assert.Equal(t, 1, first.Remaining)
+
+	assert.True(t, l.Allow("a").Allowed)
+
+	denied := l.Allow("a")
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, 0, denied.Remaining)
+	assert.Equal(t, 500*time.Millisecond, denied.RetryAfter)
+	assert.Equal(t, time.Second, denied.Reset)
+
+	assert.True(t, l.Allow("b").Allowed, "keys have separate buckets")
+
+	*now = now.Add(500 * time.Millisecond)
+	assert.True(t, l.Allow("a").Allowed, "a token is refilled")
+	assert.False(t, l.Allow("a").Allowed)
+}
+
+func TestLimiter_Burst(t *testing.T) {
+	l, _ := newTestLimiter(Policy{Limit: 1, Period: time.Minute, Burst: 3})
+
+	for range 3 {
+		assert.True(t, l.Allow("a").Allowed)
+	}
+	assert.False(t, l.Allow("a").Allowed)
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	l, _ := newTestLimiter(Policy{})
+	for range 100 {
+		assert.True(t, l.Allow("a").Allowed)
+	}
+}
+
+func TestLimiter_SetPolicy(t *testing.T) {
+	l, _ := newTestLimiter(Policy{Limit: 10, Period: time.Minute})
+	assert.True(t, l.Allow("a").Allowed)
+
+	l.SetPolicy(Policy{Limit: 1, Period: time.Minute})
+	assert.True(t, l.Allow("a").Allowed)
+	assert.False(t, l.Allow("a").Allowed)
+}
+
+func TestLimiter_SweepsIdleBuckets(t *testing.T) {
+	l, now := newTestLimiter(Policy{Limit: 1, Period: time.Second})
+	l.Allow("a")
+	l.Allow("b")
+
+	*now = now.Add(2 * time.Second)
+	l.Allow("c")
+	assert.Len(t, l.buckets, 1)
+}
@@ -3,9 +3,9 @@ document.getElementById('registerBtn').onclick = async function () {
     if (!email) return output('Please enter a valid email.');
 
     // Begin registration (fetch challenge/options from backend)
-    let res = await fetch('/api/webauth/register/begin', {
+    let res = await fetch('/api/v1/webauth/register/begin', {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken() },
         body: JSON.stringify({ email })
     });
     if (!res.ok) return output('Failed to start registration');
@@ -35,9 +35,9 @@ document.getElementById('registerBtn').onclick = async function () {
         }
     };
 
-    res = await fetch('/api/webauth/register/finish', {
+    res = await fetch('/api/v1/webauth/register/finish', {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken() },
         body: JSON.stringify(attestation)
     });
     if (!res.ok) return output('Registration finish failed');
@@ -61,9 +61,9 @@ document.getElementById('loginBtn').onclick = async function () {
     if (!email) return output('Please enter a valid email.');
 
     // Begin login (fetch challenge/options from backend)
-    let res = await fetch('/api/webauth/login/begin', {
+    let res = await fetch('/api/v1/webauth/login/begin', {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken() },
         body: JSON.stringify({ email })
     });
     if (!res.ok) return output('Failed to start login');
@@ -100,9 +100,9 @@ document.getElementById('loginBtn').onclick = async function () {
         }
     };
 
-    res = await fetch('/api/webauth/login/finish', {
+    res = await fetch('/api/v1/webauth/login/finish', {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken() },
         body: JSON.stringify(authData)
     });
     if (!res.ok) return output('Login finish failed');
@@ -122,6 +122,10 @@ document.getElementById('loginBtn').onclick = async function () {
 };
 
 // Helper functions
+function csrfToken() {
+    const meta = document.querySelector('meta[name="csrf-token"]');
+    return meta ? meta.content : '';
+}
 function output(msg) {
     document.getElementById('output').textContent = msg;
 }